@@ -1,22 +1,37 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/authorizating"
 	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/handling"
 	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/session"
 )
 
 func main() {
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("migrate give error:%s\n", err)
+		}
+		return
+	}
+
 	err := session.InitSessionStorage()
 	if err != nil {
 		log.Println(err.Error())
 		return
 	}
 
-	r, err := handling.NewHandler()
+	auth, err := authorizating.NewAutenticator(context.Background())
+	if err != nil {
+		log.Fatalf("startup authenticator give error:%s\n", err)
+		return
+	}
+
+	r, err := handling.NewHandler(auth)
 	if err != nil {
 		log.Fatalf("startup router give error:%s\n", err)
 		return
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/migrating"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/storaging"
+)
+
+const migrateUsage = "usage: locale-mgmt migrate status|up|down|to <version>"
+
+//runMigrate handles the migrate subcommand
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := storaging.OpenPostgres()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := migrating.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d %-40s %s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "to":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %s: %v", args[1], err)
+		}
+		return migrator.To(ctx, version)
+	default:
+		return errors.New(migrateUsage)
+	}
+}
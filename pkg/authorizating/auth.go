@@ -4,108 +4,180 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"sync"
+	"time"
 
 	"golang.org/x/oauth2"
 
 	oidc "github.com/coreos/go-oidc"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
 	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/session"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
 )
 
-//Autenticator is the class for authentication
+//tokenRefreshLeeway is how long before expiry the access token is refreshed
+const tokenRefreshLeeway = time.Minute
+
+//defaultRefreshInterval is used when AUTH0_REFRESH_INTERVAL is not set
+const defaultRefreshInterval = time.Hour
+
+//Autenticator is the class for authentication, it is built once at startup
+//and keeps provider metadata and signing keys cached between refreshes
 type Autenticator struct {
-	Provider *oidc.Provider
-	Config   oauth2.Config
-	Context  context.Context
+	issuer          string
+	refreshInterval time.Duration
+	keySet          *cachedKeySet
+	verifier        *oidc.IDTokenVerifier
+
+	mu     sync.RWMutex
+	config oauth2.Config
 }
 
-//NewAutenticator return an object with provider and config to get auth token
-func NewAutenticator() (*Autenticator, error) {
+//NewAutenticator discovers provider metadata and keys, then keeps them refreshed in background until ctx is done
+func NewAutenticator(ctx context.Context) (*Autenticator, error) {
+	refreshInterval := defaultRefreshInterval
+	if candidate := os.Getenv("AUTH0_REFRESH_INTERVAL"); candidate != "" {
+		interval, err := time.ParseDuration(candidate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUTH0_REFRESH_INTERVAL: %v", err)
+		}
+		refreshInterval = interval
+	}
 
-	ctx := context.Background()
+	issuer := os.Getenv("AUTH0_DOMAIN")
+	keySet := &cachedKeySet{}
+	a := &Autenticator{
+		issuer:          issuer,
+		refreshInterval: refreshInterval,
+		keySet:          keySet,
+		verifier:        oidc.NewVerifier(issuer, keySet, &oidc.Config{ClientID: os.Getenv("AUTH0_CLIENT_ID")}),
+		config: oauth2.Config{
+			ClientID:     os.Getenv("AUTH0_CLIENT_ID"),
+			ClientSecret: os.Getenv("AUTH0_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("AUTH0_CALLBACK_URL"),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", oidc.ScopeOfflineAccess},
+		},
+	}
 
-	provider, err := oidc.NewProvider(ctx, os.Getenv("AUTH0_DOMAIN"))
+	err := a.Refresh(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	conf := oauth2.Config{
-		ClientID:     os.Getenv("AUTH0_CLIENT_ID"),
-		ClientSecret: os.Getenv("AUTH0_CLIENT_SECRET"),
-		RedirectURL:  os.Getenv("AUTH0_CALLBACK_URL"),
-		Scopes:       []string{oidc.ScopeOpenID, "profile"},
-		Endpoint:     provider.Endpoint(),
+	go a.keepRefreshed(ctx)
+
+	return a, nil
+}
+
+//Refresh reloads discovery document and JWKS, on error the cached ones are left untouched;
+//it gives up after providerTimeout
+func (a *Autenticator) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(oidc.ClientContext(ctx, providerClient), providerTimeout)
+	defer cancel()
+	provider, err := oidc.NewProvider(ctx, a.issuer)
+	if err != nil {
+		return err
+	}
+
+	var metadata struct {
+		JWKSURL string `json:"jwks_uri"`
+	}
+	err = provider.Claims(&metadata)
+	if err != nil {
+		return err
 	}
 
-	return &Autenticator{
-		Provider: provider,
-		Config:   conf,
-		Context:  ctx,
-	}, nil
+	keys, err := fetchKeys(ctx, metadata.JWKSURL)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.config.Endpoint = provider.Endpoint()
+	a.mu.Unlock()
+	a.keySet.update(metadata.JWKSURL, keys)
+
+	return nil
+}
+
+func (a *Autenticator) keepRefreshed(ctx context.Context) {
+	ticker := time.NewTicker(a.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.Refresh(ctx); err != nil {
+				log.Printf("Refresh of auth provider metadata failed, keep using cached one: %v\n", err)
+			}
+		}
+	}
+}
+
+//OAuthConfig return the oauth2 config with the last discovered endpoint
+func (a *Autenticator) OAuthConfig() oauth2.Config {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.config
 }
 
 //CallbackHandler manage callback call by Auth0 provider
-func CallbackHandler(c *gin.Context) {
+func (a *Autenticator) CallbackHandler(c *gin.Context) {
 
 	//retrive session to get state for compare
 	ss, err := session.Store.Get(c.Request, "auth-session")
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		problem.AbortInternal(c, err)
 		return
 	}
 
 	//compare state returned by provider with session stored one
 	if c.Request.URL.Query().Get("state") != ss.Values["state"] {
-		c.AbortWithError(http.StatusInternalServerError, errors.New("Invalid state parameter"))
-		return
-	}
-
-	authenticator, err := NewAutenticator()
-	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		problem.Abort(c, http.StatusBadRequest, problem.CodeBadRequest, "Invalid state parameter")
 		return
 	}
 
 	//request access token with code returned by provider
-	token, err := authenticator.Config.Exchange(context.TODO(), c.Request.URL.Query().Get("code"))
+	config := a.OAuthConfig()
+	token, err := config.Exchange(c.Request.Context(), c.Request.URL.Query().Get("code"))
 	if err != nil {
-		c.AbortWithError(http.StatusUnauthorized, err)
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Code exchange with auth provider failed")
 		return
 	}
 
 	rawIdToken, ok := token.Extra("id_token").(string)
 	if !ok {
-		c.AbortWithError(http.StatusInternalServerError, errors.New("No id_token field in oauth2 token"))
+		problem.Abort(c, http.StatusBadGateway, problem.CodeUpstreamFailed, "No id_token field in oauth2 token")
 		return
 	}
 
-	oidcConfig := &oidc.Config{
-		ClientID: os.Getenv("AUTH0_CLIENT_ID"),
-	}
-
 	//parse and verify jwt token
-	idToken, err := authenticator.Provider.Verifier(oidcConfig).Verify(context.TODO(), rawIdToken)
+	idToken, err := a.verifier.Verify(c.Request.Context(), rawIdToken)
 
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, errors.New("Failed to verify id token: "+err.Error()))
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Failed to verify id token")
 		return
 	}
 
 	//retrive claims from jwt token
 	var profile map[string]interface{}
 	if err := idToken.Claims(&profile); err != nil {
-		c.AbortWithError(http.StatusInternalServerError, errors.New("Failed to marshall profile: "+err.Error()))
+		problem.AbortInternal(c, errors.New("Failed to marshall profile: "+err.Error()))
 		return
 	}
 
 	ss.Values["id_token"] = rawIdToken
-	ss.Values["access_token"] = token.AccessToken
 	ss.Values["profile"] = profile
+	storeToken(ss, token)
 	err = ss.Save(c.Request, c.Writer)
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, errors.New("Failed to save session: "+err.Error()))
+		problem.AbortInternal(c, errors.New("Failed to save session: "+err.Error()))
 		return
 	}
 
@@ -113,7 +185,8 @@ func CallbackHandler(c *gin.Context) {
 }
 
 //AuthRequired is the middleware to test if user is authenticated
-func AuthRequired() gin.HandlerFunc {
+//and to refresh the access token when it is close to expiry
+func (a *Autenticator) AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		if testing := os.Getenv("test"); testing == "on" {
@@ -123,12 +196,41 @@ func AuthRequired() gin.HandlerFunc {
 
 		ss, err := session.Store.Get(c.Request, "auth-session")
 		if err != nil {
-			c.AbortWithError(http.StatusInternalServerError, err)
+			problem.AbortInternal(c, err)
+			return
+		}
+
+		token, ok := tokenFromSession(ss)
+		if !ok {
+			abortUnauthorized(c, "No active session, login required")
+			return
+		}
+
+		if token.Expiry.IsZero() || time.Until(token.Expiry) > tokenRefreshLeeway {
+			c.Next()
+			return
+		}
+
+		if token.RefreshToken == "" {
+			abortUnauthorized(c, "Session expired, login required")
+			return
+		}
+
+		//an empty access token forces the token source to use the refresh token
+		config := a.OAuthConfig()
+		refreshed, err := config.TokenSource(c.Request.Context(), &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+		if err != nil {
+			abortUnauthorized(c, "Session expired, login required")
 			return
 		}
 
-		if _, ok := ss.Values["access_token"]; !ok {
-			c.Redirect(http.StatusTemporaryRedirect, os.Getenv("WEB_APP_DOMAIN")+"/welcome")
+		if rawIdToken, ok := refreshed.Extra("id_token").(string); ok {
+			ss.Values["id_token"] = rawIdToken
+		}
+		storeToken(ss, refreshed)
+		err = ss.Save(c.Request, c.Writer)
+		if err != nil {
+			problem.AbortInternal(c, errors.New("Failed to save session: "+err.Error()))
 			return
 		}
 
@@ -136,6 +238,44 @@ func AuthRequired() gin.HandlerFunc {
 	}
 }
 
+//storeToken keeps access token, refresh token and expiry in session,
+//no expiry is kept when the provider does not set expires_in
+func storeToken(ss *sessions.Session, token *oauth2.Token) {
+	ss.Values["access_token"] = token.AccessToken
+	if token.Expiry.IsZero() {
+		delete(ss.Values, "token_expiry")
+	} else {
+		ss.Values["token_expiry"] = token.Expiry.Unix()
+	}
+	if token.RefreshToken != "" {
+		ss.Values["refresh_token"] = token.RefreshToken
+	}
+}
+
+//tokenFromSession rebuild the oauth2 token stored in session,
+//a missing expiry is left zero, as oauth2 does, for a token that does not expire
+func tokenFromSession(ss *sessions.Session) (*oauth2.Token, bool) {
+	accessToken, ok := ss.Values["access_token"].(string)
+	if !ok {
+		return nil, false
+	}
+
+	token := &oauth2.Token{AccessToken: accessToken}
+	if refreshToken, ok := ss.Values["refresh_token"].(string); ok {
+		token.RefreshToken = refreshToken
+	}
+	if expiry, ok := ss.Values["token_expiry"].(int64); ok {
+		token.Expiry = time.Unix(expiry, 0)
+	}
+
+	return token, true
+}
+
+//abortUnauthorized stops the chain with a problem body instead of a redirect, so xhr clients can handle it
+func abortUnauthorized(c *gin.Context, message string) {
+	problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, message)
+}
+
 type GenericMessage struct {
 	Message string
 }
@@ -144,7 +284,7 @@ type GenericMessage struct {
 func RestrictedHandler(c *gin.Context) {
 	ss, err := session.Store.Get(c.Request, "auth-session")
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		problem.AbortInternal(c, err)
 		return
 	}
 
@@ -0,0 +1,133 @@
+package authorizating
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+//providerTimeout is the max duration of a call to the auth provider
+const providerTimeout = 10 * time.Second
+
+//keysRefetchInterval is the min interval between two fetches of keys for an unknown key id,
+//so forged tokens cannot make every request call the provider
+const keysRefetchInterval = 30 * time.Second
+
+//providerClient is the http client of every call to the auth provider
+var providerClient = &http.Client{Timeout: providerTimeout}
+
+//cachedKeySet implements oidc.KeySet keeping the provider signing keys in memory,
+//so id tokens can be verified while the provider is unreachable
+type cachedKeySet struct {
+	mu        sync.RWMutex
+	jwksURL   string
+	keys      []jose.JSONWebKey
+	fetchedAt time.Time
+}
+
+func (ks *cachedKeySet) update(jwksURL string, keys []jose.JSONWebKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.jwksURL = jwksURL
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+}
+
+//claimFetch return true, and marks keys as fetched now, when last fetch is older than keysRefetchInterval
+func (ks *cachedKeySet) claimFetch() bool {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if time.Since(ks.fetchedAt) < keysRefetchInterval {
+		return false
+	}
+	ks.fetchedAt = time.Now()
+	return true
+}
+
+func (ks *cachedKeySet) cached() (string, []jose.JSONWebKey) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.jwksURL, ks.keys
+}
+
+//VerifySignature implements oidc.KeySet, keys are fetched again only for an unknown key id
+//and at most once every keysRefetchInterval, in between an unknown key id is rejected
+func (ks *cachedKeySet) VerifySignature(ctx context.Context, jwt string) ([]byte, error) {
+	jws, err := jose.ParseSigned(jwt)
+	if err != nil {
+		return nil, fmt.Errorf("malformed jwt: %v", err)
+	}
+
+	keyID := ""
+	if len(jws.Signatures) > 0 {
+		keyID = jws.Signatures[0].Header.KeyID
+	}
+
+	jwksURL, keys := ks.cached()
+	if payload, ok := verifyWithKeys(jws, keyID, keys); ok {
+		return payload, nil
+	}
+
+	//provider could have rotated keys
+	if !ks.claimFetch() {
+		return nil, errors.New("failed to verify id token signature: unknown signing key")
+	}
+	keys, err = fetchKeys(ctx, jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetching keys: %v", err)
+	}
+	ks.update(jwksURL, keys)
+
+	if payload, ok := verifyWithKeys(jws, keyID, keys); ok {
+		return payload, nil
+	}
+
+	return nil, errors.New("failed to verify id token signature")
+}
+
+func verifyWithKeys(jws *jose.JSONWebSignature, keyID string, keys []jose.JSONWebKey) ([]byte, bool) {
+	for _, key := range keys {
+		if keyID != "" && key.KeyID != keyID {
+			continue
+		}
+		if payload, err := jws.Verify(&key); err == nil {
+			return payload, true
+		}
+	}
+	return nil, false
+}
+
+func fetchKeys(ctx context.Context, jwksURL string) ([]jose.JSONWebKey, error) {
+	if jwksURL == "" {
+		return nil, errors.New("no jwks_uri in provider metadata")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := providerClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, jwksURL)
+	}
+
+	var keySet jose.JSONWebKeySet
+	err = json.NewDecoder(resp.Body).Decode(&keySet)
+	if err != nil {
+		return nil, err
+	}
+
+	return keySet.Keys, nil
+}
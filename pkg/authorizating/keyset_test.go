@@ -0,0 +1,60 @@
+package authorizating
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+func signedToken(t *testing.T, keyID string) (string, jose.JSONWebKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: keyID}}, nil)
+	assert.NoError(t, err)
+	jws, err := signer.Sign([]byte(`{"sub":"anna"}`))
+	assert.NoError(t, err)
+	token, err := jws.CompactSerialize()
+	assert.NoError(t, err)
+	return token, jose.JSONWebKey{Key: &key.PublicKey, KeyID: keyID, Algorithm: string(jose.RS256), Use: "sig"}
+}
+
+func TestVerifySignatureRateLimitsFetches(t *testing.T) {
+	token, publicKey := signedToken(t, "rotated")
+	forged, _ := signedToken(t, "forged")
+
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{publicKey}})
+	}))
+	defer server.Close()
+
+	ks := &cachedKeySet{jwksURL: server.URL}
+	payload, err := ks.VerifySignature(context.Background(), token)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"sub":"anna"}`, string(payload))
+	assert.Equal(t, 1, fetches)
+
+	for i := 0; i < 3; i++ {
+		_, err = ks.VerifySignature(context.Background(), forged)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 1, fetches, "unknown key ids are rejected without fetching until keysRefetchInterval")
+
+	ks.fetchedAt = time.Now().Add(-keysRefetchInterval)
+	_, err = ks.VerifySignature(context.Background(), forged)
+	assert.Error(t, err)
+	assert.Equal(t, 2, fetches)
+
+	_, err = ks.VerifySignature(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fetches)
+}
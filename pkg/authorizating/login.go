@@ -8,19 +8,20 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
 	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/session"
 	"github.com/gin-gonic/gin"
 )
 
 //LoginHandler manage login call to auth provider
-func LoginHandler(c *gin.Context) {
+func (a *Autenticator) LoginHandler(c *gin.Context) {
 
 	//random to generate state for request and then compare the code for getting auth-token
 	b := make([]byte, 32)
 	_, err := rand.Read(b)
 
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		problem.AbortInternal(c, err)
 		return
 	}
 
@@ -30,7 +31,7 @@ func LoginHandler(c *gin.Context) {
 	//int session to store sate
 	ss, err := session.Store.Get(c.Request, "auth-session")
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		problem.AbortInternal(c, err)
 		return
 	}
 
@@ -38,19 +39,13 @@ func LoginHandler(c *gin.Context) {
 	ss.Values["state"] = state
 	err = ss.Save(c.Request, c.Writer)
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
-		return
-	}
-
-	//init connectoin with authenticator
-	authenticator, err := NewAutenticator()
-	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		problem.AbortInternal(c, err)
 		return
 	}
 
 	//call auth provider with random state
-	redirectLocation := authenticator.Config.AuthCodeURL(state)
+	config := a.OAuthConfig()
+	redirectLocation := config.AuthCodeURL(state)
 	c.Redirect(http.StatusTemporaryRedirect, redirectLocation)
 }
 
@@ -60,7 +55,7 @@ func LogoutHandler(c *gin.Context) {
 	logoutUrl, err := url.Parse(domain)
 
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		problem.AbortInternal(c, err)
 		return
 	}
 
@@ -88,7 +83,7 @@ func LogoutHandler(c *gin.Context) {
 
 	returnTo, err := url.Parse(urlToReturn + "/welcome")
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		problem.AbortInternal(c, err)
 		return
 	}
 
@@ -106,7 +101,7 @@ func InfoHandler(c *gin.Context) {
 
 	ss, err := session.Store.Get(c.Request, "auth-session")
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		problem.AbortInternal(c, err)
 		return
 	}
 
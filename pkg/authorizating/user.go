@@ -0,0 +1,69 @@
+package authorizating
+
+import (
+	"os"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/session"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	//anonymousUser is returned when no profile is stored in session
+	anonymousUser = "anonymous"
+	//defaultRolesClaim is the claim of the id token that lists user roles when AUTH0_ROLES_CLAIM is not set
+	defaultRolesClaim = "https://locale-mgmt/roles"
+	//RoleAdmin is the role needed for destructive operations
+	RoleAdmin = "admin"
+)
+
+//CurrentUser return the name of logged user taken from session profile
+func CurrentUser(c *gin.Context) string {
+	profile := currentProfile(c)
+
+	for _, claim := range []string{"name", "sub"} {
+		if user, ok := profile[claim].(string); ok && user != "" {
+			return user
+		}
+	}
+
+	return anonymousUser
+}
+
+//HasRole return true if logged user profile lists the role, every role is granted in test mode
+func HasRole(c *gin.Context, role string) bool {
+	if testing := os.Getenv("test"); testing == "on" {
+		return true
+	}
+
+	rolesClaim := os.Getenv("AUTH0_ROLES_CLAIM")
+	if rolesClaim == "" {
+		rolesClaim = defaultRolesClaim
+	}
+
+	roles, ok := currentProfile(c)[rolesClaim].([]interface{})
+	if !ok {
+		return false
+	}
+
+	for _, candidate := range roles {
+		if candidate == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+func currentProfile(c *gin.Context) map[string]interface{} {
+	ss, err := session.Store.Get(c.Request, "auth-session")
+	if err != nil {
+		return map[string]interface{}{}
+	}
+
+	profile, ok := ss.Values["profile"].(map[string]interface{})
+	if !ok {
+		return map[string]interface{}{}
+	}
+
+	return profile
+}
@@ -0,0 +1,59 @@
+package exchanging
+
+//Message rappresents a string of a catalog with the context a translator needs about its key;
+//plural variants are by CLDR category
+type Message struct {
+	ID            string
+	Description   string
+	Note          string
+	Tags          []string
+	Screenshots   []string
+	MaxLength     int
+	Source        string
+	SourcePlurals map[string]string
+	Target        string
+	TargetPlurals map[string]string
+	NeedsReview   bool
+}
+
+//Catalog rappresents the messages of a bundle to translate from SourceLang to TargetLang,
+//TargetCategories are the CLDR plural categories of TargetLang
+type Catalog struct {
+	Name             string
+	SourceLang       string
+	TargetLang       string
+	TargetCategories []string
+	Messages         []Message
+}
+
+//isPlural return true when the message has plural variants in source or target
+func (m Message) isPlural() bool {
+	return len(m.SourcePlurals) > 0 || len(m.TargetPlurals) > 0
+}
+
+//hasTarget return true when the message is translated
+func (m Message) hasTarget() bool {
+	return m.Target != "" || len(m.TargetPlurals) > 0
+}
+
+//sourceOf return the source text of a plural category, the other variant or the source when missing
+func (m Message) sourceOf(category string) string {
+	if text, ok := m.SourcePlurals[category]; ok {
+		return text
+	}
+	if text, ok := m.SourcePlurals["other"]; ok {
+		return text
+	}
+	return m.Source
+}
+
+//targetOf return the target text of a plural category, the other variant or the target when missing
+func (m Message) targetOf(category string) string {
+	if text, ok := m.TargetPlurals[category]; ok {
+		return text
+	}
+	if text, ok := m.TargetPlurals["other"]; ok {
+		return text
+	}
+	return m.Target
+}
@@ -0,0 +1,131 @@
+package exchanging
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testCatalog = Catalog{
+	Name:             "checkout",
+	SourceLang:       "en",
+	TargetLang:       "ru",
+	TargetCategories: []string{"one", "few", "many", "other"},
+	Messages: []Message{
+		{
+			ID:          "PAY_BUTTON",
+			Description: "Button that confirms the order",
+			Note:        "keep it short,\nit is on mobile",
+			Tags:        []string{"checkout", "button"},
+			Screenshots: []string{"/api/v1/bundles/checkout/keys/PAY_BUTTON/screenshots/3"},
+			MaxLength:   12,
+			Source:      `Pay "now"`,
+			Target:      "Оплатить",
+		},
+		{
+			ID:            "ITEMS",
+			Source:        "{n} items",
+			SourcePlurals: map[string]string{"one": "{n} item", "other": "{n} items"},
+			TargetPlurals: map[string]string{"one": "{n} товар", "few": "{n} товара", "many": "{n} товаров", "other": "{n} товара"},
+			NeedsReview:   true,
+		},
+		{ID: "EMPTY", Source: "Your cart is empty"},
+	},
+}
+
+func TestWriteXLIFF(t *testing.T) {
+	var sb strings.Builder
+	assert.NoError(t, WriteXLIFF(&sb, testCatalog))
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<xliff xmlns="urn:oasis:names:tc:xliff:document:1.2" version="1.2">
+  <file original="checkout" source-language="en" target-language="ru" datatype="plaintext" tool-id="locale-mgmt">
+    <body>
+      <trans-unit id="PAY_BUTTON" resname="PAY_BUTTON" maxwidth="12" size-unit="char">
+        <source>Pay &#34;now&#34;</source>
+        <target state="translated">Оплатить</target>
+        <note from="description">Button that confirms the order</note>
+        <note from="developer">keep it short,&#xA;it is on mobile</note>
+        <note from="tags">checkout, button</note>
+        <note from="screenshot">/api/v1/bundles/checkout/keys/PAY_BUTTON/screenshots/3</note>
+      </trans-unit>
+      <group id="ITEMS" resname="ITEMS" restype="x-gettext-plurals">
+        <trans-unit id="ITEMS[one]">
+          <source>{n} item</source>
+          <target state="needs-review-translation">{n} товар</target>
+        </trans-unit>
+        <trans-unit id="ITEMS[few]">
+          <source>{n} items</source>
+          <target state="needs-review-translation">{n} товара</target>
+        </trans-unit>
+        <trans-unit id="ITEMS[many]">
+          <source>{n} items</source>
+          <target state="needs-review-translation">{n} товаров</target>
+        </trans-unit>
+        <trans-unit id="ITEMS[other]">
+          <source>{n} items</source>
+          <target state="needs-review-translation">{n} товара</target>
+        </trans-unit>
+      </group>
+      <trans-unit id="EMPTY" resname="EMPTY">
+        <source>Your cart is empty</source>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>
+`
+	assert.Equal(t, expected, sb.String())
+}
+
+func TestWritePO(t *testing.T) {
+	var sb strings.Builder
+	assert.NoError(t, WritePO(&sb, testCatalog))
+
+	expected := `msgid ""
+msgstr ""
+"Project-Id-Version: checkout\n"
+"Language: ru\n"
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Content-Transfer-Encoding: 8bit\n"
+"Plural-Forms: nplurals=3; plural=(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2);\n"
+"X-Generator: locale-mgmt\n"
+
+#. Button that confirms the order
+#. keep it short,
+#. it is on mobile
+#. tags: checkout, button
+#. max length: 12
+#. screenshot: /api/v1/bundles/checkout/keys/PAY_BUTTON/screenshots/3
+msgctxt "PAY_BUTTON"
+msgid "Pay \"now\""
+msgstr "Оплатить"
+
+#, fuzzy
+msgctxt "ITEMS"
+msgid "{n} item"
+msgid_plural "{n} items"
+msgstr[0] "{n} товар"
+msgstr[1] "{n} товара"
+msgstr[2] "{n} товаров"
+
+msgctxt "EMPTY"
+msgid "Your cart is empty"
+msgstr ""
+`
+	assert.Equal(t, expected, sb.String())
+}
+
+func TestPluralsOf(t *testing.T) {
+	assert.Equal(t, gettextPluralsByLang["fr"], pluralsOf("fr-CA", []string{"one", "many", "other"}))
+	assert.Equal(t, oneOther, pluralsOf("pt-PT", []string{"one", "many", "other"}))
+	assert.Equal(t, onlyOther, pluralsOf("ja", []string{"other"}))
+	assert.Equal(t, oneOther, pluralsOf("it-IT", []string{"one", "many", "other"}))
+}
+
+func TestWritePOString(t *testing.T) {
+	var sb strings.Builder
+	writePOString(&sb, "msgstr", "first\nsecond\t\\")
+	assert.Equal(t, "msgstr \"\"\n\"first\\n\"\n\"second\\t\\\\\"\n", sb.String())
+}
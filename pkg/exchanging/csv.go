@@ -0,0 +1,69 @@
+package exchanging
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//columns of a glossary CSV that are not langs
+const (
+	csvDoNotTranslate = "do_not_translate"
+	csvNote           = "note"
+)
+
+//ReadConceptsCSV decodes a glossary CSV: the header has a lang per column, plus optional do_not_translate and note columns;
+//every other row is a concept with a term for every not empty lang column, ID is its line
+func ReadConceptsCSV(r io.Reader) ([]Concept, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("invalid CSV: missing header")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	langs := 0
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+		if header[i] != csvDoNotTranslate && header[i] != csvNote && header[i] != "" {
+			langs++
+		}
+	}
+	if langs == 0 {
+		return nil, fmt.Errorf("invalid CSV: header has no lang column")
+	}
+
+	concepts := []Concept{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return concepts, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		concept := Concept{ID: fmt.Sprint(line)}
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			switch header[i] {
+			case "":
+			case csvDoNotTranslate:
+				value = strings.ToLower(value)
+				concept.DoNotTranslate = value == "yes" || value == "true" || value == "1" || value == "x"
+			case csvNote:
+				concept.Note = value
+			default:
+				if value != "" {
+					concept.Terms = append(concept.Terms, Variant{header[i], value})
+				}
+			}
+		}
+		concepts = append(concepts, concept)
+	}
+}
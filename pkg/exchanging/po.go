@@ -0,0 +1,154 @@
+package exchanging
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+//gettextPlurals rappresents the Plural-Forms of a lang: the expression that picks the index of msgstr
+//and the CLDR category of every index
+type gettextPlurals struct {
+	expression string
+	categories []string
+}
+
+//slavicEast is the expression of east and south slavic langs
+const slavicEast = "(n%10==1 && n%100!=11 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)"
+
+var (
+	oneOther  = gettextPlurals{"(n != 1)", []string{"one", "other"}}
+	onlyOther = gettextPlurals{"0", []string{"other"}}
+)
+
+//gettextPluralsByLang are the Plural-Forms of langs whose ones are not (n != 1), by tag or base lang
+var gettextPluralsByLang = map[string]gettextPlurals{
+	"fr":    {"(n > 1)", []string{"one", "other"}},
+	"pt":    {"(n > 1)", []string{"one", "other"}},
+	"pt-PT": oneOther,
+	"ru":    {slavicEast, []string{"one", "few", "many"}},
+	"uk":    {slavicEast, []string{"one", "few", "many"}},
+	"be":    {slavicEast, []string{"one", "few", "many"}},
+	"sr":    {slavicEast, []string{"one", "few", "other"}},
+	"hr":    {slavicEast, []string{"one", "few", "other"}},
+	"bs":    {slavicEast, []string{"one", "few", "other"}},
+	"pl":    {"(n==1 ? 0 : n%10>=2 && n%10<=4 && (n%100<10 || n%100>=20) ? 1 : 2)", []string{"one", "few", "many"}},
+	"cs":    {"(n==1 ? 0 : n>=2 && n<=4 ? 1 : 2)", []string{"one", "few", "other"}},
+	"sk":    {"(n==1 ? 0 : n>=2 && n<=4 ? 1 : 2)", []string{"one", "few", "other"}},
+	"lt":    {"(n%10==1 && n%100!=11 ? 0 : n%10>=2 && (n%100<10 || n%100>=20) ? 1 : 2)", []string{"one", "few", "other"}},
+	"ro":    {"(n==1 ? 0 : (n==0 || (n%100>0 && n%100<20)) ? 1 : 2)", []string{"one", "few", "other"}},
+	"sl":    {"(n%100==1 ? 0 : n%100==2 ? 1 : n%100==3 || n%100==4 ? 2 : 3)", []string{"one", "two", "few", "other"}},
+	"he":    {"(n==1 ? 0 : n==2 ? 1 : 2)", []string{"one", "two", "other"}},
+	"ar":    {"(n==0 ? 0 : n==1 ? 1 : n==2 ? 2 : n%100>=3 && n%100<=10 ? 3 : n%100>=11 ? 4 : 5)", []string{"zero", "one", "two", "few", "many", "other"}},
+}
+
+//pluralsOf return the Plural-Forms of lang; a lang not in the table is (n != 1),
+//or a single form when its only CLDR category is other
+func pluralsOf(lang string, categories []string) gettextPlurals {
+	if plurals, ok := gettextPluralsByLang[lang]; ok {
+		return plurals
+	}
+	if plurals, ok := gettextPluralsByLang[strings.SplitN(lang, "-", 2)[0]]; ok {
+		return plurals
+	}
+	if len(categories) == 1 && categories[0] == "other" {
+		return onlyOther
+	}
+	return oneOther
+}
+
+//quotePO return value as a PO string, escaped and in double quotes
+func quotePO(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+//writePOString writes keyword with value, a value with more lines is split after every newline
+func writePOString(sb *strings.Builder, keyword, value string) {
+	lines := strings.SplitAfter(value, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= 1 {
+		fmt.Fprintf(sb, "%s %s\n", keyword, quotePO(value))
+		return
+	}
+
+	fmt.Fprintf(sb, "%s \"\"\n", keyword)
+	for _, line := range lines {
+		sb.WriteString(quotePO(line) + "\n")
+	}
+}
+
+//writePOComments writes the context of message as extracted comments, one for every line
+func writePOComments(sb *strings.Builder, message Message) {
+	comments := []string{}
+	if message.Description != "" {
+		comments = append(comments, strings.Split(message.Description, "\n")...)
+	}
+	if message.Note != "" {
+		comments = append(comments, strings.Split(message.Note, "\n")...)
+	}
+	if len(message.Tags) > 0 {
+		comments = append(comments, "tags: "+strings.Join(message.Tags, ", "))
+	}
+	if message.MaxLength > 0 {
+		comments = append(comments, fmt.Sprintf("max length: %d", message.MaxLength))
+	}
+	for _, screenshot := range message.Screenshots {
+		comments = append(comments, "screenshot: "+screenshot)
+	}
+
+	for _, comment := range comments {
+		sb.WriteString(strings.TrimRight("#. "+comment, " ") + "\n")
+	}
+}
+
+//WritePO encodes catalog as a gettext PO file with msgctxt as key; description, note, tags and screenshots
+//are extracted comments and messages that need review are fuzzy. A message with plural variants has
+//msgid the one source variant, msgid_plural the other one and a msgstr for every form of Plural-Forms
+func WritePO(w io.Writer, catalog Catalog) error {
+	plurals := pluralsOf(catalog.TargetLang, catalog.TargetCategories)
+
+	var sb strings.Builder
+	sb.WriteString("msgid \"\"\nmsgstr \"\"\n")
+	for _, header := range []string{
+		"Project-Id-Version: " + catalog.Name,
+		"Language: " + strings.ReplaceAll(catalog.TargetLang, "-", "_"),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: 8bit",
+		fmt.Sprintf("Plural-Forms: nplurals=%d; plural=%s;", len(plurals.categories), plurals.expression),
+		"X-Generator: " + creationTool,
+	} {
+		sb.WriteString(quotePO(header+"\n") + "\n")
+	}
+
+	for _, message := range catalog.Messages {
+		sb.WriteString("\n")
+		writePOComments(&sb, message)
+		if message.NeedsReview && message.hasTarget() {
+			sb.WriteString("#, fuzzy\n")
+		}
+		writePOString(&sb, "msgctxt", message.ID)
+
+		if !message.isPlural() {
+			writePOString(&sb, "msgid", message.Source)
+			writePOString(&sb, "msgstr", message.Target)
+			continue
+		}
+
+		writePOString(&sb, "msgid", message.sourceOf("one"))
+		writePOString(&sb, "msgid_plural", message.sourceOf("other"))
+		for i, category := range plurals.categories {
+			target := ""
+			if message.hasTarget() {
+				target = message.targetOf(category)
+			}
+			writePOString(&sb, fmt.Sprintf("msgstr[%d]", i), target)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
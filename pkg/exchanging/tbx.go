@@ -0,0 +1,130 @@
+package exchanging
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	//tbxDoNotTranslate is the type of descrip, admin or termNote that flags a concept as not to be translated
+	tbxDoNotTranslate = "x-doNotTranslate"
+	//xmlNamespace is the namespace of xml:lang
+	xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+)
+
+//Concept rappresents a glossary entry, the same term in more langs; DoNotTranslate terms are the same in every lang
+type Concept struct {
+	ID             string
+	DoNotTranslate bool
+	Note           string
+	Terms          []Variant
+}
+
+//ReadTBX decodes the concepts of a TBX document, both TBX 2 (martif, termEntry, langSet) and TBX 3 (tbx, conceptEntry, langSec);
+//a concept is not to be translated when it has a descrip, admin or termNote of type x-doNotTranslate with value yes or true
+func ReadTBX(r io.Reader) ([]Concept, error) {
+	decoder := xml.NewDecoder(r)
+	concepts := []Concept{}
+	var concept *Concept
+	lang := ""
+	root := true
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			if root {
+				return nil, fmt.Errorf("invalid TBX: empty document")
+			}
+			return concepts, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid TBX: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if root {
+				if t.Name.Local != "martif" && t.Name.Local != "tbx" {
+					return nil, fmt.Errorf("invalid TBX: root element is %s, not martif or tbx", t.Name.Local)
+				}
+				root = false
+				continue
+			}
+
+			switch t.Name.Local {
+			case "termEntry", "conceptEntry":
+				concepts = append(concepts, Concept{ID: attr(t, "", "id")})
+				concept = &concepts[len(concepts)-1]
+			case "langSet", "langSec":
+				lang = attr(t, xmlNamespace, "lang")
+				if lang == "" {
+					lang = attr(t, "", "lang")
+				}
+			case "term":
+				text, err := elementText(decoder)
+				if err != nil {
+					return nil, fmt.Errorf("invalid TBX: %w", err)
+				}
+				if concept != nil && strings.TrimSpace(text) != "" {
+					concept.Terms = append(concept.Terms, Variant{lang, strings.TrimSpace(text)})
+				}
+			case "descrip", "admin", "termNote", "note":
+				text, err := elementText(decoder)
+				if err != nil {
+					return nil, fmt.Errorf("invalid TBX: %w", err)
+				}
+				if concept == nil {
+					continue
+				}
+				switch attr(t, "", "type") {
+				case tbxDoNotTranslate:
+					value := strings.ToLower(strings.TrimSpace(text))
+					concept.DoNotTranslate = concept.DoNotTranslate || value == "yes" || value == "true"
+				case "definition", "":
+					if concept.Note == "" {
+						concept.Note = strings.TrimSpace(text)
+					}
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "termEntry", "conceptEntry":
+				concept = nil
+			case "langSet", "langSec":
+				lang = ""
+			}
+		}
+	}
+}
+
+//attr return the value of the attribute of start with name in space, empty when it's not there
+func attr(start xml.StartElement, space, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Space == space && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+//elementText return the text of the element just started, inline elements included, and consumes its end
+func elementText(decoder *xml.Decoder) (string, error) {
+	var sb strings.Builder
+	for depth := 1; depth > 0; {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			sb.Write(t)
+		}
+	}
+	return sb.String(), nil
+}
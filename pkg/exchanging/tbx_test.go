@@ -0,0 +1,72 @@
+package exchanging
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadTBX(t *testing.T) {
+	tbx2 := `<?xml version="1.0" encoding="UTF-8"?>
+<martif type="TBX" xml:lang="en">
+  <text><body>
+    <termEntry id="c1">
+      <descrip type="definition">Area where a team works</descrip>
+      <langSet xml:lang="en"><tig><term>Workspace</term></tig></langSet>
+      <langSet xml:lang="it-IT"><tig><term>Area di lavoro</term></tig></langSet>
+    </termEntry>
+    <termEntry id="c2">
+      <descrip type="x-doNotTranslate">yes</descrip>
+      <langSet xml:lang="en"><ntig><termGrp><term>Dashboard</term></termGrp></ntig></langSet>
+    </termEntry>
+  </body></text>
+</martif>`
+
+	concepts, err := ReadTBX(strings.NewReader(tbx2))
+	assert.NoError(t, err)
+	assert.Equal(t, []Concept{
+		{ID: "c1", Note: "Area where a team works", Terms: []Variant{{"en", "Workspace"}, {"it-IT", "Area di lavoro"}}},
+		{ID: "c2", DoNotTranslate: true, Terms: []Variant{{"en", "Dashboard"}}},
+	}, concepts)
+
+	tbx3 := `<tbx type="TBX-Basic" style="dca" xml:lang="en" xmlns="urn:iso:std:iso:30042:ed-2">
+  <text><body>
+    <conceptEntry id="7">
+      <langSec xml:lang="en"><termSec><term>Save</term></termSec></langSec>
+      <langSec xml:lang="de"><termSec><term>Speichern</term></termSec></langSec>
+    </conceptEntry>
+  </body></text>
+</tbx>`
+
+	concepts, err = ReadTBX(strings.NewReader(tbx3))
+	assert.NoError(t, err)
+	assert.Equal(t, []Concept{{ID: "7", Terms: []Variant{{"en", "Save"}, {"de", "Speichern"}}}}, concepts)
+
+	_, err = ReadTBX(strings.NewReader(`<tmx version="1.4"/>`))
+	assert.EqualError(t, err, "invalid TBX: root element is tmx, not martif or tbx")
+
+	_, err = ReadTBX(strings.NewReader(`<martif><text>`))
+	assert.Error(t, err)
+}
+
+func TestReadConceptsCSV(t *testing.T) {
+	src := "\ufeffen,it-IT,de,do_not_translate,note\n" +
+		"Workspace,Area di lavoro,Arbeitsbereich,,team area\n" +
+		"Dashboard,,,yes,\n" +
+		"\"Save, now\",Salva ora,,,\n"
+
+	concepts, err := ReadConceptsCSV(strings.NewReader(src))
+	assert.NoError(t, err)
+	assert.Equal(t, []Concept{
+		{ID: "2", Note: "team area", Terms: []Variant{{"en", "Workspace"}, {"it-IT", "Area di lavoro"}, {"de", "Arbeitsbereich"}}},
+		{ID: "3", DoNotTranslate: true, Terms: []Variant{{"en", "Dashboard"}}},
+		{ID: "4", Terms: []Variant{{"en", "Save, now"}, {"it-IT", "Salva ora"}}},
+	}, concepts)
+
+	_, err = ReadConceptsCSV(strings.NewReader("note,do_not_translate\n"))
+	assert.EqualError(t, err, "invalid CSV: header has no lang column")
+
+	_, err = ReadConceptsCSV(strings.NewReader("en,it\nSave\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,199 @@
+package exchanging
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	//tmxVersion is the version of written documents
+	tmxVersion = "1.4"
+	//tmxAllLangs as srclang means any variant can be the source
+	tmxAllLangs = "*all*"
+	//creationTool is written in the header of every document
+	creationTool = "locale-mgmt"
+)
+
+//Variant rappresents the text of a unit in a lang
+type Variant struct {
+	Lang string
+	Text string
+}
+
+//Unit rappresents a translation unit, the same text in more langs; SourceLang is empty when any variant can be the source
+type Unit struct {
+	ID         string
+	SourceLang string
+	Props      map[string]string
+	Variants   []Variant
+}
+
+//Memory rappresents a TMX document
+type Memory struct {
+	SourceLang   string
+	CreationTool string
+	Units        []Unit
+}
+
+type tmxDocument struct {
+	XMLName xml.Name  `xml:"tmx"`
+	Version string    `xml:"version,attr"`
+	Header  tmxHeader `xml:"header"`
+	Units   []tmxUnit `xml:"body>tu"`
+}
+
+type tmxHeader struct {
+	CreationTool        string `xml:"creationtool,attr"`
+	CreationToolVersion string `xml:"creationtoolversion,attr"`
+	SegType             string `xml:"segtype,attr"`
+	OTMF                string `xml:"o-tmf,attr"`
+	AdminLang           string `xml:"adminlang,attr"`
+	SrcLang             string `xml:"srclang,attr"`
+	DataType            string `xml:"datatype,attr"`
+}
+
+type tmxUnit struct {
+	ID       string       `xml:"tuid,attr,omitempty"`
+	SrcLang  string       `xml:"srclang,attr,omitempty"`
+	Props    []tmxProp    `xml:"prop"`
+	Variants []tmxVariant `xml:"tuv"`
+}
+
+type tmxProp struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type tmxVariant struct {
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	//OldLang is lang attribute of TMX 1.1, when decoding it matches xml:lang too
+	OldLang string `xml:"lang,attr,omitempty"`
+	Seg     tmxSeg `xml:"seg"`
+}
+
+//tmxSeg keeps the raw content of seg, that can have inline elements as <ph> and <bpt>
+type tmxSeg struct {
+	Inner string `xml:",innerxml"`
+}
+
+//text return the text of seg, inline elements contain native codes as escaped text so they are kept
+func (s tmxSeg) text() (string, error) {
+	var sb strings.Builder
+	decoder := xml.NewDecoder(strings.NewReader(s.Inner))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if data, ok := token.(xml.CharData); ok {
+			sb.Write(data)
+		}
+	}
+}
+
+//ReadTMX decodes a TMX document, versions 1.1 to 1.4 are accepted
+func ReadTMX(r io.Reader) (*Memory, error) {
+	var doc tmxDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid TMX: %w", err)
+	}
+	if doc.Version == "" {
+		return nil, fmt.Errorf("invalid TMX: missing version")
+	}
+
+	memory := &Memory{SourceLang: doc.Header.SrcLang, CreationTool: doc.Header.CreationTool}
+	if memory.SourceLang == tmxAllLangs {
+		memory.SourceLang = ""
+	}
+
+	for i, tu := range doc.Units {
+		unit := Unit{ID: tu.ID, SourceLang: memory.SourceLang, Props: map[string]string{}}
+		if tu.SrcLang != "" {
+			unit.SourceLang = tu.SrcLang
+			if unit.SourceLang == tmxAllLangs {
+				unit.SourceLang = ""
+			}
+		}
+		for _, prop := range tu.Props {
+			unit.Props[prop.Type] = prop.Value
+		}
+		for _, tuv := range tu.Variants {
+			text, err := tuv.Seg.text()
+			if err != nil {
+				return nil, fmt.Errorf("invalid TMX: seg of unit %d: %w", i, err)
+			}
+			lang := tuv.Lang
+			if lang == "" {
+				lang = tuv.OldLang
+			}
+			unit.Variants = append(unit.Variants, Variant{lang, text})
+		}
+		memory.Units = append(memory.Units, unit)
+	}
+
+	return memory, nil
+}
+
+//WriteTMX encodes memory as a TMX 1.4 document, props of a unit are written by type
+func WriteTMX(w io.Writer, memory Memory) error {
+	sourceLang := memory.SourceLang
+	if sourceLang == "" {
+		sourceLang = tmxAllLangs
+	}
+
+	doc := tmxDocument{
+		Version: tmxVersion,
+		Header: tmxHeader{
+			CreationTool:        creationTool,
+			CreationToolVersion: "1",
+			SegType:             "sentence",
+			OTMF:                creationTool,
+			AdminLang:           "en",
+			SrcLang:             sourceLang,
+			DataType:            "plaintext",
+		},
+		Units: make([]tmxUnit, 0, len(memory.Units)),
+	}
+
+	for _, unit := range memory.Units {
+		tu := tmxUnit{ID: unit.ID}
+		if unit.SourceLang != memory.SourceLang && unit.SourceLang != "" {
+			tu.SrcLang = unit.SourceLang
+		}
+
+		types := make([]string, 0, len(unit.Props))
+		for propType := range unit.Props {
+			types = append(types, propType)
+		}
+		sort.Strings(types)
+		for _, propType := range types {
+			tu.Props = append(tu.Props, tmxProp{propType, unit.Props[propType]})
+		}
+
+		for _, variant := range unit.Variants {
+			var escaped strings.Builder
+			if err := xml.EscapeText(&escaped, []byte(variant.Text)); err != nil {
+				return err
+			}
+			tu.Variants = append(tu.Variants, tmxVariant{Lang: variant.Lang, Seg: tmxSeg{escaped.String()}})
+		}
+		doc.Units = append(doc.Units, tu)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
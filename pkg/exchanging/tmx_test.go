@@ -0,0 +1,71 @@
+package exchanging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadTMX(t *testing.T) {
+	src := `<?xml version="1.0" encoding="UTF-8"?>
+<tmx version="1.4">
+  <header creationtool="Agency" creationtoolversion="2" segtype="sentence" o-tmf="x" adminlang="en" srclang="en-US" datatype="plaintext"/>
+  <body>
+    <tu tuid="42">
+      <prop type="x-key">HELLO</prop>
+      <tuv xml:lang="en-US"><seg>Hello <ph>&lt;b&gt;</ph>{name}<ph>&lt;/b&gt;</ph></seg></tuv>
+      <tuv xml:lang="it-IT"><seg>Ciao <ph>&lt;b&gt;</ph>{name}<ph>&lt;/b&gt;</ph></seg></tuv>
+    </tu>
+    <tu srclang="*all*">
+      <tuv lang="de"><seg>Tschüß &amp; bis bald</seg></tuv>
+    </tu>
+  </body>
+</tmx>`
+
+	memory, err := ReadTMX(strings.NewReader(src))
+	assert.NoError(t, err)
+	assert.Equal(t, "en-US", memory.SourceLang)
+	assert.Equal(t, "Agency", memory.CreationTool)
+	assert.Len(t, memory.Units, 2)
+
+	assert.Equal(t, "42", memory.Units[0].ID)
+	assert.Equal(t, "en-US", memory.Units[0].SourceLang)
+	assert.Equal(t, map[string]string{"x-key": "HELLO"}, memory.Units[0].Props)
+	assert.Equal(t, []Variant{{"en-US", "Hello <b>{name}</b>"}, {"it-IT", "Ciao <b>{name}</b>"}}, memory.Units[0].Variants)
+
+	assert.Equal(t, "", memory.Units[1].SourceLang)
+	assert.Equal(t, []Variant{{"de", "Tschüß & bis bald"}}, memory.Units[1].Variants)
+}
+
+func TestReadTMXErrors(t *testing.T) {
+	_, err := ReadTMX(strings.NewReader("<tmx><body>"))
+	assert.Error(t, err)
+
+	_, err = ReadTMX(strings.NewReader("<tmx><header/><body/></tmx>"))
+	assert.EqualError(t, err, "invalid TMX: missing version")
+}
+
+func TestWriteTMXRoundTrip(t *testing.T) {
+	memory := Memory{
+		SourceLang: "en-US",
+		Units: []Unit{
+			{ID: "label/HELLO", SourceLang: "en-US", Props: map[string]string{"x-key": "HELLO", "x-bundle": "label"},
+				Variants: []Variant{{"en-US", "Hello <b>{name}</b>\nbye"}, {"it-IT", "Ciao <b>{name}</b>\nciao"}}},
+			{ID: "2", SourceLang: "it-IT", Props: map[string]string{}, Variants: []Variant{{"it-IT", "Salve"}, {"de-DE", "Hallo"}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteTMX(&buf, memory))
+	assert.Contains(t, buf.String(), `<prop type="x-bundle">label</prop>`)
+	assert.Contains(t, buf.String(), `<tuv xml:lang="it-IT">`)
+	assert.Contains(t, buf.String(), `<tu tuid="2" srclang="it-IT">`)
+
+	read, err := ReadTMX(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, creationTool, read.CreationTool)
+	memory.CreationTool = creationTool
+	assert.Equal(t, memory, *read)
+}
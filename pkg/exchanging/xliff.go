@@ -0,0 +1,138 @@
+package exchanging
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+const (
+	//xliffVersion is the version of written documents
+	xliffVersion = "1.2"
+	//xliffPluralsType is the restype of the group of the plural variants of a message, as written by gettext tools
+	xliffPluralsType = "x-gettext-plurals"
+)
+
+type xliffDocument struct {
+	XMLName xml.Name  `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+	Version string    `xml:"version,attr"`
+	File    xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	Original       string         `xml:"original,attr"`
+	SourceLanguage string         `xml:"source-language,attr"`
+	TargetLanguage string         `xml:"target-language,attr"`
+	DataType       string         `xml:"datatype,attr"`
+	Tool           string         `xml:"tool-id,attr"`
+	Body           []xliffElement `xml:"body>unit"`
+}
+
+//xliffElement is a trans-unit or a group of the trans-units of plural variants, named by XMLName
+type xliffElement struct {
+	XMLName  xml.Name
+	ID       string       `xml:"id,attr"`
+	ResName  string       `xml:"resname,attr,omitempty"`
+	ResType  string       `xml:"restype,attr,omitempty"`
+	MaxWidth int          `xml:"maxwidth,attr,omitempty"`
+	SizeUnit string       `xml:"size-unit,attr,omitempty"`
+	Source   *xliffText   `xml:"source"`
+	Target   *xliffTarget `xml:"target"`
+	Notes    []xliffNote  `xml:"note"`
+	Variants []xliffElement
+}
+
+type xliffText struct {
+	Text string `xml:",chardata"`
+}
+
+type xliffTarget struct {
+	State string `xml:"state,attr"`
+	Text  string `xml:",chardata"`
+}
+
+type xliffNote struct {
+	From string `xml:"from,attr"`
+	Text string `xml:",chardata"`
+}
+
+//notes return the context of message as notes: description, developer note, tags and screenshots
+func (m Message) notes() []xliffNote {
+	notes := []xliffNote{}
+	if m.Description != "" {
+		notes = append(notes, xliffNote{"description", m.Description})
+	}
+	if m.Note != "" {
+		notes = append(notes, xliffNote{"developer", m.Note})
+	}
+	if len(m.Tags) > 0 {
+		notes = append(notes, xliffNote{"tags", strings.Join(m.Tags, ", ")})
+	}
+	for _, screenshot := range m.Screenshots {
+		notes = append(notes, xliffNote{"screenshot", screenshot})
+	}
+	return notes
+}
+
+//newXliffUnit return the trans-unit of a source and target text, target is missing when not translated
+func newXliffUnit(id, source, target string, translated, needsReview bool) xliffElement {
+	unit := xliffElement{XMLName: xml.Name{Local: "trans-unit"}, ID: id, Source: &xliffText{source}}
+	if translated {
+		unit.Target = &xliffTarget{State: "translated", Text: target}
+		if needsReview {
+			unit.Target.State = "needs-review-translation"
+		}
+	}
+	return unit
+}
+
+//WriteXLIFF encodes catalog as a XLIFF 1.2 document with a trans-unit by message, id and resname are the key;
+//a message with plural variants is a group of a trans-unit for every plural category of the target lang
+func WriteXLIFF(w io.Writer, catalog Catalog) error {
+	doc := xliffDocument{
+		Version: xliffVersion,
+		File: xliffFile{
+			Original:       catalog.Name,
+			SourceLanguage: catalog.SourceLang,
+			TargetLanguage: catalog.TargetLang,
+			DataType:       "plaintext",
+			Tool:           creationTool,
+			Body:           make([]xliffElement, 0, len(catalog.Messages)),
+		},
+	}
+
+	for _, message := range catalog.Messages {
+		if !message.isPlural() {
+			unit := newXliffUnit(message.ID, message.Source, message.Target, message.hasTarget(), message.NeedsReview)
+			unit.ResName = message.ID
+			unit.Notes = message.notes()
+			if message.MaxLength > 0 {
+				unit.MaxWidth, unit.SizeUnit = message.MaxLength, "char"
+			}
+			doc.File.Body = append(doc.File.Body, unit)
+			continue
+		}
+
+		group := xliffElement{XMLName: xml.Name{Local: "group"}, ID: message.ID, ResName: message.ID, ResType: xliffPluralsType, Notes: message.notes()}
+		for _, category := range catalog.TargetCategories {
+			unit := newXliffUnit(message.ID+"["+category+"]", message.sourceOf(category), message.targetOf(category),
+				message.hasTarget(), message.NeedsReview)
+			if message.MaxLength > 0 {
+				unit.MaxWidth, unit.SizeUnit = message.MaxLength, "char"
+			}
+			group.Variants = append(group.Variants, unit)
+		}
+		doc.File.Body = append(doc.File.Body, group)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,456 @@
+package formatting
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//argument types of ICU MessageFormat
+const (
+	TypePlural        = "plural"
+	TypeSelectOrdinal = "selectordinal"
+	TypeSelect        = "select"
+)
+
+//simple argument types, they can have a style after a comma
+var simpleTypes = map[string]bool{
+	"number": true, "date": true, "time": true, "spellout": true, "ordinal": true, "duration": true,
+}
+
+//Message is a parsed ICU MessageFormat pattern
+type Message struct {
+	Nodes []Node
+}
+
+//Node is a part of a message: Text, Pound or Argument
+type Node interface {
+	Position() int
+}
+
+//Text is literal text, quotes already resolved
+type Text struct {
+	Pos   int
+	Value string
+}
+
+//Pound is # in a plural option, it's replaced by the number
+type Pound struct {
+	Pos int
+}
+
+//Argument is a {name}, {name, type[, style]} or {name, plural|selectordinal|select, options} placeholder
+type Argument struct {
+	Pos          int
+	End          int
+	Name         string
+	Type         string
+	Style        string
+	PluralOffset int
+	Options      []Option
+}
+
+//Option is a selector of a plural, selectordinal or select argument with its message,
+//End is the byte offset of the '}' closing the message
+type Option struct {
+	Pos      int
+	End      int
+	Selector string
+	Message  *Message
+}
+
+//Position return the byte offset of text in the source
+func (t *Text) Position() int { return t.Pos }
+
+//Position return the byte offset of # in the source
+func (p *Pound) Position() int { return p.Pos }
+
+//Position return the byte offset of the opening brace in the source
+func (a *Argument) Position() int { return a.Pos }
+
+//SyntaxError rappresents a malformed message, line and column are 1-based and column counts characters
+type SyntaxError struct {
+	Offset int
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+//newSyntaxError return the error at byte offset of src
+func newSyntaxError(src string, offset int, format string, args ...interface{}) *SyntaxError {
+	if offset > len(src) {
+		offset = len(src)
+	}
+	before := src[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+	return &SyntaxError{offset, line, column, fmt.Sprintf(format, args...)}
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+//Parse parses src as ICU MessageFormat, the error is a *SyntaxError
+func Parse(src string) (*Message, error) {
+	p := &parser{src: src}
+	msg, err := p.message(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf(p.pos, "unmatched '}'")
+	}
+	return msg, nil
+}
+
+func (p *parser) errorf(offset int, format string, args ...interface{}) error {
+	return newSyntaxError(p.src, offset, format, args...)
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	return p.src[p.pos]
+}
+
+//message parses text and arguments up to an unmatched '}' or the end; # is a Pound in plural options
+func (p *parser) message(inPlural bool) (*Message, error) {
+	msg := &Message{}
+	var text strings.Builder
+	textStart := p.pos
+
+	appendText := func(start int, s string) {
+		if text.Len() == 0 {
+			textStart = start
+		}
+		text.WriteString(s)
+	}
+	flush := func() {
+		if text.Len() > 0 {
+			msg.Nodes = append(msg.Nodes, &Text{textStart, text.String()})
+			text.Reset()
+		}
+	}
+
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == '\'':
+			start := p.pos
+			appendText(start, p.quoted(inPlural))
+		case c == '{':
+			flush()
+			arg, err := p.argument()
+			if err != nil {
+				return nil, err
+			}
+			msg.Nodes = append(msg.Nodes, arg)
+		case c == '}':
+			flush()
+			return msg, nil
+		case c == '#' && inPlural:
+			flush()
+			msg.Nodes = append(msg.Nodes, &Pound{p.pos})
+			p.pos++
+		default:
+			_, size := utf8.DecodeRuneInString(p.src[p.pos:])
+			appendText(p.pos, p.src[p.pos:p.pos+size])
+			p.pos += size
+		}
+	}
+
+	flush()
+	return msg, nil
+}
+
+//quoted resolves an apostrophe: '' is a quote, '{ '} '# start quoted text up to the next single quote,
+//any other apostrophe is literal
+func (p *parser) quoted(inPlural bool) string {
+	start := p.pos
+	p.pos++
+	if p.eof() {
+		return "'"
+	}
+
+	next := p.peek()
+	if next == '\'' {
+		p.pos++
+		return "'"
+	}
+	if next != '{' && next != '}' && !(next == '#' && inPlural) {
+		return "'"
+	}
+
+	var sb strings.Builder
+	for !p.eof() {
+		c := p.peek()
+		if c == '\'' {
+			if p.pos+1 < len(p.src) && p.src[p.pos+1] == '\'' {
+				sb.WriteByte('\'')
+				p.pos += 2
+				continue
+			}
+			p.pos++
+			return sb.String()
+		}
+		sb.WriteByte(c)
+		p.pos++
+	}
+
+	//an unterminated quote runs to the end, as in ICU
+	return p.src[start+1:]
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		p.pos += size
+	}
+}
+
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
+
+func (p *parser) identifier() string {
+	start := p.pos
+	for !p.eof() {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !isIdentRune(r) {
+			break
+		}
+		p.pos += size
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) digits() string {
+	start := p.pos
+	for !p.eof() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+//argument parses from '{' to the matching '}'
+func (p *parser) argument() (*Argument, error) {
+	arg := &Argument{Pos: p.pos}
+	p.pos++
+	p.skipSpace()
+
+	arg.Name = p.identifier()
+	if arg.Name == "" {
+		if p.eof() {
+			return nil, p.errorf(arg.Pos, "unclosed '{'")
+		}
+		return nil, p.errorf(p.pos, "expected argument name")
+	}
+
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf(arg.Pos, "unclosed '{' of argument %s", arg.Name)
+	}
+	if p.peek() == '}' {
+		p.pos++
+		arg.End = p.pos
+		return arg, nil
+	}
+	if p.peek() != ',' {
+		return nil, p.errorf(p.pos, "expected ',' or '}' after argument name %s", arg.Name)
+	}
+	p.pos++
+	p.skipSpace()
+
+	typeStart := p.pos
+	arg.Type = p.identifier()
+	if arg.Type == "" {
+		return nil, p.errorf(p.pos, "expected argument type")
+	}
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf(arg.Pos, "unclosed '{' of argument %s", arg.Name)
+	}
+
+	switch {
+	case arg.Type == TypePlural || arg.Type == TypeSelectOrdinal || arg.Type == TypeSelect:
+		if p.peek() != ',' {
+			return nil, p.errorf(p.pos, "expected ',' after %s", arg.Type)
+		}
+		p.pos++
+		if err := p.options(arg); err != nil {
+			return nil, err
+		}
+	case simpleTypes[arg.Type]:
+		if p.peek() == ',' {
+			p.pos++
+			style, err := p.style(arg)
+			if err != nil {
+				return nil, err
+			}
+			arg.Style = strings.TrimSpace(style)
+		}
+		if p.eof() || p.peek() != '}' {
+			return nil, p.errorf(p.pos, "expected '}' to close argument %s", arg.Name)
+		}
+		p.pos++
+	default:
+		return nil, p.errorf(typeStart, "unknown argument type %q", arg.Type)
+	}
+
+	arg.End = p.pos
+	return arg, nil
+}
+
+//style reads the style of a simple argument up to the '}' closing the argument, nested braces are balanced
+func (p *parser) style(arg *Argument) (string, error) {
+	start := p.pos
+	depth := 0
+	for !p.eof() {
+		switch p.peek() {
+		case '\'':
+			p.quoted(false)
+			continue
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return p.src[start:p.pos], nil
+			}
+			depth--
+		}
+		p.pos++
+	}
+	return "", p.errorf(arg.Pos, "unclosed '{' of argument %s", arg.Name)
+}
+
+//options parses selectors and their messages up to the '}' closing the argument
+func (p *parser) options(arg *Argument) error {
+	isPlural := arg.Type != TypeSelect
+
+	p.skipSpace()
+	if isPlural && strings.HasPrefix(p.src[p.pos:], "offset:") {
+		p.pos += len("offset:")
+		p.skipSpace()
+		offsetStart := p.pos
+		value := p.digits()
+		if value == "" {
+			return p.errorf(offsetStart, "expected number after offset:")
+		}
+		arg.PluralOffset, _ = strconv.Atoi(value)
+	}
+
+	seen := map[string]bool{}
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return p.errorf(arg.Pos, "unclosed '{' of %s argument %s", arg.Type, arg.Name)
+		}
+		if p.peek() == '}' {
+			p.pos++
+			break
+		}
+
+		option := Option{Pos: p.pos}
+		if isPlural && p.peek() == '=' {
+			p.pos++
+			value := p.digits()
+			if value == "" {
+				return p.errorf(p.pos, "expected number after '='")
+			}
+			option.Selector = "=" + value
+		} else {
+			option.Selector = p.identifier()
+			if option.Selector == "" {
+				return p.errorf(p.pos, "expected selector or '}' in %s argument %s", arg.Type, arg.Name)
+			}
+		}
+		if seen[option.Selector] {
+			return p.errorf(option.Pos, "duplicate selector %s", option.Selector)
+		}
+		seen[option.Selector] = true
+
+		p.skipSpace()
+		if p.eof() || p.peek() != '{' {
+			return p.errorf(p.pos, "expected '{' after selector %s", option.Selector)
+		}
+		open := p.pos
+		p.pos++
+
+		msg, err := p.message(isPlural)
+		if err != nil {
+			return err
+		}
+		if p.eof() {
+			return p.errorf(open, "unclosed '{' of selector %s", option.Selector)
+		}
+		option.End = p.pos
+		p.pos++
+
+		option.Message = msg
+		arg.Options = append(arg.Options, option)
+	}
+
+	if !seen["other"] {
+		return p.errorf(arg.Pos, "%s argument %s needs an other selector", arg.Type, arg.Name)
+	}
+	return nil
+}
+
+//Walk calls fn for every node of msg, nested options included, depth first
+func Walk(msg *Message, fn func(Node)) {
+	for _, node := range msg.Nodes {
+		fn(node)
+		if arg, ok := node.(*Argument); ok {
+			for _, option := range arg.Options {
+				Walk(option.Message, fn)
+			}
+		}
+	}
+}
+
+//CheckCategories return an error for every plural selector not among cardinal
+//and every selectordinal selector not among ordinal categories of the language; =N selectors are always valid
+func CheckCategories(src string, msg *Message, cardinal, ordinal []string) []*SyntaxError {
+	allowed := map[string]map[string]bool{TypePlural: {}, TypeSelectOrdinal: {}}
+	for _, category := range cardinal {
+		allowed[TypePlural][category] = true
+	}
+	for _, category := range ordinal {
+		allowed[TypeSelectOrdinal][category] = true
+	}
+
+	errs := []*SyntaxError{}
+	Walk(msg, func(node Node) {
+		arg, ok := node.(*Argument)
+		if !ok || allowed[arg.Type] == nil {
+			return
+		}
+		for _, option := range arg.Options {
+			if strings.HasPrefix(option.Selector, "=") || allowed[arg.Type][option.Selector] {
+				continue
+			}
+			errs = append(errs, newSyntaxError(src, option.Pos, "%s category %s is not used by the language, valid ones are %s",
+				arg.Type, option.Selector, strings.Join(categoriesOf(arg.Type, cardinal, ordinal), ", ")))
+		}
+	})
+	return errs
+}
+
+func categoriesOf(argType string, cardinal, ordinal []string) []string {
+	if argType == TypeSelectOrdinal {
+		return ordinal
+	}
+	return cardinal
+}
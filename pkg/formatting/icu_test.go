@@ -0,0 +1,120 @@
+package formatting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	msg, err := Parse("Hi {name}, you have {count, plural, offset:1 =0 {no files} one {# file} other {# files}}")
+	assert.NoError(t, err)
+	assert.Len(t, msg.Nodes, 4)
+
+	name := msg.Nodes[1].(*Argument)
+	assert.Equal(t, "name", name.Name)
+	assert.Equal(t, 3, name.Pos)
+
+	count := msg.Nodes[3].(*Argument)
+	assert.Equal(t, TypePlural, count.Type)
+	assert.Equal(t, 1, count.PluralOffset)
+	assert.Equal(t, []string{"=0", "one", "other"}, []string{count.Options[0].Selector, count.Options[1].Selector, count.Options[2].Selector})
+	assert.IsType(t, &Pound{}, count.Options[1].Message.Nodes[0])
+}
+
+func TestParseQuotes(t *testing.T) {
+	msg, err := Parse("It''s '{literal}' and don't")
+	assert.NoError(t, err)
+	assert.Equal(t, []Node{&Text{0, "It's {literal} and don't"}}, msg.Nodes)
+
+	msg, err = Parse("'{x}' and it''s")
+	assert.NoError(t, err)
+	assert.Equal(t, []Node{&Text{0, "{x} and it's"}}, msg.Nodes)
+
+	msg, err = Parse("{n, plural, other {'#' is #}}")
+	assert.NoError(t, err)
+	other := msg.Nodes[0].(*Argument).Options[0].Message
+	assert.Equal(t, "# is ", other.Nodes[0].(*Text).Value)
+	assert.IsType(t, &Pound{}, other.Nodes[1])
+}
+
+func TestParseSimpleAndNested(t *testing.T) {
+	msg, err := Parse("{d, date, short} {n, number, ::currency/EUR} {g, select, female {{n, plural, one {her #} other {her # items}}} other {their}}")
+	assert.NoError(t, err)
+	assert.Equal(t, "short", msg.Nodes[0].(*Argument).Style)
+	assert.Equal(t, "::currency/EUR", msg.Nodes[2].(*Argument).Style)
+
+	arguments := []string{}
+	Walk(msg, func(node Node) {
+		if arg, ok := node.(*Argument); ok {
+			arguments = append(arguments, arg.Name)
+		}
+	})
+	assert.Equal(t, []string{"d", "n", "g", "n"}, arguments)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src    string
+		line   int
+		column int
+		msg    string
+	}{
+		{"Hello {name", 1, 7, "unclosed '{' of argument name"},
+		{"Hello }", 1, 7, "unmatched '}'"},
+		{"Hello {}", 1, 8, "expected argument name"},
+		{"{n, plural, one {# file}}", 1, 1, "plural argument n needs an other selector"},
+		{"{n, plural, one {# file} other {# files}", 1, 1, "unclosed '{' of plural argument n"},
+		{"{n, plural, one {# file} one {#} other {}}", 1, 26, "duplicate selector one"},
+		{"{n, plural, one # file other {}}", 1, 17, "expected '{' after selector one"},
+		{"{n, plurl, one {#} other {}}", 1, 5, `unknown argument type "plurl"`},
+		{"Line one\nè {n, select, a {x} other {y}", 2, 3, "unclosed '{' of select argument n"},
+		{"{n, number, integer", 1, 1, "unclosed '{' of argument n"},
+		{"{n name}", 1, 4, "expected ',' or '}' after argument name n"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		if !assert.Error(t, err, tt.src) {
+			continue
+		}
+		syntaxErr := err.(*SyntaxError)
+		assert.Equal(t, tt.line, syntaxErr.Line, tt.src)
+		assert.Equal(t, tt.column, syntaxErr.Column, tt.src)
+		assert.Equal(t, tt.msg, syntaxErr.Msg, tt.src)
+	}
+}
+
+func TestCheckCategories(t *testing.T) {
+	src := "{n, plural, =0 {none} one {#} few {#} other {#}} {p, selectordinal, one {#st} other {#th}}"
+	msg, err := Parse(src)
+	assert.NoError(t, err)
+
+	errs := CheckCategories(src, msg, []string{"one", "other"}, []string{"many", "other"})
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "line 1, column 31: plural category few is not used by the language, valid ones are one, other", errs[0].Error())
+	assert.Equal(t, 69, errs[1].Column)
+
+	assert.Empty(t, CheckCategories(src, msg, []string{"one", "few", "many", "other"}, []string{"one", "two", "few", "other"}))
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add("Hi {name}, {count, plural, one {# file} other {# files}}")
+	f.Add("It''s '{quoted}'")
+	f.Add("{g, select, a {{n, number, ::percent}} other {x}}")
+	f.Fuzz(func(t *testing.T, src string) {
+		msg, err := Parse(src)
+		if err != nil {
+			syntaxErr, ok := err.(*SyntaxError)
+			if !ok || syntaxErr.Offset < 0 || syntaxErr.Offset > len(src) {
+				t.Fatalf("bad error %v for %q", err, src)
+			}
+			return
+		}
+		Walk(msg, func(node Node) {
+			if node.Position() < 0 || node.Position() > len(src) {
+				t.Fatalf("node out of source %d for %q", node.Position(), src)
+			}
+		})
+	})
+}
@@ -0,0 +1,237 @@
+package formatting
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//placeholder kinds
+const (
+	PlaceholderICU      = "icu"
+	PlaceholderPrintf   = "printf"
+	PlaceholderMustache = "mustache"
+	PlaceholderHTML     = "html"
+)
+
+//issue codes of a translation compared to its source
+const (
+	IssueMissing   = "missing"
+	IssueExtra     = "extra"
+	IssueReordered = "reordered"
+)
+
+var (
+	mustachePattern = regexp.MustCompile(`\{\{\{?[^{}]+\}\}\}?`)
+	htmlPattern     = regexp.MustCompile(`</?([A-Za-z][A-Za-z0-9-]*)(?:\s[^<>]*)?/?>`)
+	bracePattern    = regexp.MustCompile(`\{\s*([A-Za-z0-9_][\w.-]*)\s*(?:,[^{}]*)?\}`)
+	printfPattern   = regexp.MustCompile(`%(?:\d+\$|\([A-Za-z_]\w*\))?[-+0#']*(?:\d+|\*)?(?:\.(?:\d+|\*))?(?:hh|h|ll|l|L|q|j|z|t)?[diuoxXeEfFgGaAcsp@%]`)
+)
+
+//Placeholder is a part of content that must be kept by every translation,
+//Text is its normalized form so {{ name }} and {{name}} or <a href="x"> and <a> are the same
+type Placeholder struct {
+	Kind       string `json:"kind"`
+	Text       string `json:"text"`
+	Pos        int    `json:"pos"`
+	positional bool
+}
+
+//Issue is a difference of placeholders between a translation and its source
+type Issue struct {
+	Code        string      `json:"code"`
+	Placeholder Placeholder `json:"placeholder"`
+}
+
+//ExtractPlaceholders return mustache, HTML tags, ICU arguments and printf verbs of content by position;
+//ICU arguments used in more options are returned once, when content is not valid ICU {name} ones are taken
+func ExtractPlaceholders(content string) []Placeholder {
+	result := []Placeholder{}
+	masked := []byte(content)
+	mask := func(start, end int) {
+		for i := start; i < end; i++ {
+			masked[i] = ' '
+		}
+	}
+
+	for _, loc := range mustachePattern.FindAllStringIndex(content, -1) {
+		inner := strings.Trim(content[loc[0]:loc[1]], "{} \t")
+		result = append(result, Placeholder{Kind: PlaceholderMustache, Text: "{{" + inner + "}}", Pos: loc[0]})
+		mask(loc[0], loc[1])
+	}
+
+	for _, loc := range htmlPattern.FindAllSubmatchIndex(masked, -1) {
+		tag := string(masked[loc[0]:loc[1]])
+		name := strings.ToLower(string(masked[loc[2]:loc[3]]))
+		text := "<" + name + ">"
+		if strings.HasPrefix(tag, "</") {
+			text = "</" + name + ">"
+		} else if strings.HasSuffix(tag, "/>") {
+			text = "<" + name + "/>"
+		}
+		result = append(result, Placeholder{Kind: PlaceholderHTML, Text: text, Pos: loc[0]})
+		mask(loc[0], loc[1])
+	}
+
+	if msg, err := Parse(string(masked)); err == nil {
+		seen := map[string]bool{}
+		Walk(msg, func(node Node) {
+			arg, ok := node.(*Argument)
+			if !ok || seen[arg.Name] {
+				return
+			}
+			seen[arg.Name] = true
+			result = append(result, Placeholder{Kind: PlaceholderICU, Text: "{" + arg.Name + "}", Pos: arg.Pos})
+		})
+	} else {
+		for _, loc := range bracePattern.FindAllSubmatchIndex(masked, -1) {
+			result = append(result, Placeholder{Kind: PlaceholderICU, Text: "{" + string(masked[loc[2]:loc[3]]) + "}", Pos: loc[0]})
+			mask(loc[0], loc[1])
+		}
+	}
+
+	for _, loc := range printfPattern.FindAllIndex(masked, -1) {
+		verb := string(masked[loc[0]:loc[1]])
+		if verb == "%%" {
+			continue
+		}
+		positional := strings.Contains(verb, "$") || strings.Contains(verb, "(")
+		result = append(result, Placeholder{Kind: PlaceholderPrintf, Text: verb, Pos: loc[0], positional: positional})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Pos < result[j].Pos })
+	return result
+}
+
+//ComparePlaceholders return placeholders of source missing in translation, the ones of translation
+//not in source and printf verbs without position in a different order, that would swap arguments
+func ComparePlaceholders(source, translation []Placeholder) []Issue {
+	issues := []Issue{}
+
+	counts := map[string]int{}
+	for _, p := range translation {
+		counts[p.Kind+p.Text]++
+	}
+	for _, p := range source {
+		if counts[p.Kind+p.Text] > 0 {
+			counts[p.Kind+p.Text]--
+			continue
+		}
+		issues = append(issues, Issue{IssueMissing, p})
+	}
+
+	counts = map[string]int{}
+	for _, p := range source {
+		counts[p.Kind+p.Text]++
+	}
+	for _, p := range translation {
+		if counts[p.Kind+p.Text] > 0 {
+			counts[p.Kind+p.Text]--
+			continue
+		}
+		issues = append(issues, Issue{IssueExtra, p})
+	}
+
+	if len(issues) > 0 {
+		return issues
+	}
+
+	sourceVerbs := sequentialVerbs(source)
+	translationVerbs := sequentialVerbs(translation)
+	for i := range sourceVerbs {
+		if sourceVerbs[i].Text != translationVerbs[i].Text {
+			issues = append(issues, Issue{IssueReordered, translationVerbs[i]})
+			break
+		}
+	}
+
+	return issues
+}
+
+//sequentialVerbs return printf verbs that take arguments by order
+func sequentialVerbs(placeholders []Placeholder) []Placeholder {
+	verbs := []Placeholder{}
+	for _, p := range placeholders {
+		if p.Kind == PlaceholderPrintf && !p.positional {
+			verbs = append(verbs, p)
+		}
+	}
+	return verbs
+}
+
+//Segment is a part of content, Translatable ones are text and the other ones placeholders or ICU syntax
+type Segment struct {
+	Text         string
+	Translatable bool
+}
+
+//Segments splits content in translatable text and placeholders, that joined are content;
+//for valid ICU only text of messages is translatable, # and plural or select syntax are not
+func Segments(content string) []Segment {
+	protected := make([]bool, len(content))
+	masked := []byte(content)
+	protect := func(start, end int) {
+		for i := start; i < end; i++ {
+			protected[i] = true
+			masked[i] = ' '
+		}
+	}
+
+	for _, loc := range mustachePattern.FindAllStringIndex(content, -1) {
+		protect(loc[0], loc[1])
+	}
+	for _, loc := range htmlPattern.FindAllIndex(masked, -1) {
+		protect(loc[0], loc[1])
+	}
+
+	if msg, err := Parse(string(masked)); err == nil {
+		text := make([]bool, len(content))
+		textSpans(msg, len(content), func(start, end int) {
+			for i := start; i < end; i++ {
+				text[i] = true
+			}
+		})
+		for i := range text {
+			if !text[i] {
+				protect(i, i+1)
+			}
+		}
+	} else {
+		for _, loc := range bracePattern.FindAllIndex(masked, -1) {
+			protect(loc[0], loc[1])
+		}
+	}
+
+	for _, loc := range printfPattern.FindAllIndex(masked, -1) {
+		protect(loc[0], loc[1])
+	}
+
+	segments := []Segment{}
+	for start := 0; start < len(content); {
+		end := start
+		for end < len(content) && protected[end] == protected[start] {
+			end++
+		}
+		segments = append(segments, Segment{content[start:end], !protected[start]})
+		start = end
+	}
+	return segments
+}
+
+//textSpans calls fn with the source span of every Text of msg, nested options included; end is where msg ends
+func textSpans(msg *Message, end int, fn func(start, end int)) {
+	for i, node := range msg.Nodes {
+		nodeEnd := end
+		if i+1 < len(msg.Nodes) {
+			nodeEnd = msg.Nodes[i+1].Position()
+		}
+		switch n := node.(type) {
+		case *Text:
+			fn(n.Pos, nodeEnd)
+		case *Argument:
+			for _, option := range n.Options {
+				textSpans(option.Message, option.End, fn)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package formatting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func texts(placeholders []Placeholder) []string {
+	result := []string{}
+	for _, p := range placeholders {
+		result = append(result, p.Text)
+	}
+	return result
+}
+
+func TestExtractPlaceholders(t *testing.T) {
+	tests := []struct {
+		content string
+		texts   []string
+	}{
+		{"Hello {username}, you have %d new <b>messages</b>", []string{"{username}", "%d", "<b>", "</b>"}},
+		{"{{ name }} has {{count}} items<br/>", []string{"{{name}}", "{{count}}", "<br/>"}},
+		{"{n, plural, one {# file of {owner}} other {# files of {owner}}}", []string{"{n}", "{owner}"}},
+		{"Not ICU {name} and {other", []string{"{name}"}},
+		{"%1$s wrote to %2$s, 100%% sure", []string{"%1$s", "%2$s"}},
+		{`<a href="https://example.com">link</a>`, []string{"<a>", "</a>"}},
+		{"Plain text, it's fine", []string{}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.texts, texts(ExtractPlaceholders(tt.content)), tt.content)
+	}
+}
+
+func TestComparePlaceholders(t *testing.T) {
+	tests := []struct {
+		name        string
+		source      string
+		translation string
+		codes       []string
+		texts       []string
+	}{
+		{"same", "Hello {username}, %d messages", "%d messaggi, ciao {username}", nil, nil},
+		{"missing", "Hello {username}", "Ciao", []string{IssueMissing}, []string{"{username}"}},
+		{"extra", "Hello", "Ciao <b>{username}</b>", []string{IssueExtra, IssueExtra, IssueExtra}, []string{"<b>", "{username}", "</b>"}},
+		{"reordered", "%s has %d files", "%d file di %s", []string{IssueReordered}, []string{"%d"}},
+		{"positional reorder", "%1$s has %2$d files", "%2$d file di %1$s", nil, nil},
+		{"attributes changed", `<a href="/en">go</a>`, `<a href="/it">vai</a>`, nil, nil},
+		{"twice in source", "%s and %s", "%s e basta", []string{IssueMissing}, []string{"%s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := ComparePlaceholders(ExtractPlaceholders(tt.source), ExtractPlaceholders(tt.translation))
+			codes := []string{}
+			issueTexts := []string{}
+			for _, issue := range issues {
+				codes = append(codes, issue.Code)
+				issueTexts = append(issueTexts, issue.Placeholder.Text)
+			}
+			if tt.codes == nil {
+				tt.codes, tt.texts = []string{}, []string{}
+			}
+			assert.Equal(t, tt.codes, codes)
+			assert.Equal(t, tt.texts, issueTexts)
+		})
+	}
+}
+
+func TestSegments(t *testing.T) {
+	tests := []struct {
+		content  string
+		segments []Segment
+	}{
+		{"Hello {username}, you have %d <b>messages</b>", []Segment{
+			{"Hello ", true}, {"{username}", false}, {", you have ", true}, {"%d", false}, {" ", true},
+			{"<b>", false}, {"messages", true}, {"</b>", false}}},
+		{"{n, plural, one {# file} other {# files}}", []Segment{
+			{"{n, plural, one {#", false}, {" file", true}, {"} other {#", false}, {" files", true}, {"}}", false}}},
+		{"'{quoted}' {{ name }}", []Segment{{"'{quoted}' ", true}, {"{{ name }}", false}}},
+		{"Not ICU {name} and {other", []Segment{{"Not ICU ", true}, {"{name}", false}, {" and {other", true}}},
+		{"", []Segment{}},
+	}
+
+	for _, tt := range tests {
+		segments := Segments(tt.content)
+		assert.Equal(t, tt.segments, segments, tt.content)
+
+		joined := ""
+		for _, segment := range segments {
+			joined += segment.Text
+		}
+		assert.Equal(t, tt.content, joined)
+	}
+}
+
+//FuzzSegments checks that segments always join back to content
+func FuzzSegments(f *testing.F) {
+	f.Add("Hi {name}, {count, plural, one {# file} other {# files}}")
+	f.Add("<a href='x'>{{ link }}</a> %1$s")
+	f.Fuzz(func(t *testing.T, content string) {
+		joined := ""
+		for _, segment := range Segments(content) {
+			if segment.Text == "" {
+				t.Fatalf("empty segment for %q", content)
+			}
+			joined += segment.Text
+		}
+		if joined != content {
+			t.Fatalf("segments of %q join to %q", content, joined)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package formatting
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+//bidi controls wrapping text of mirrored pseudo-localization, so it's shown right to left
+const (
+	rightToLeftOverride = "\u202e"
+	popDirectional      = "\u202c"
+)
+
+//pseudoPadding is repeated to expand the length of content
+const pseudoPadding = "~"
+
+//entityPattern matches HTML character references, kept as they are like placeholders
+var entityPattern = regexp.MustCompile(`&(?:[A-Za-z][A-Za-z0-9]*|#[0-9]+|#[xX][0-9A-Fa-f]+);`)
+
+//accented holds the accented look-alike of every ASCII letter
+var accented = map[rune]rune{
+	'a': 'á', 'b': 'ƀ', 'c': 'ç', 'd': 'ð', 'e': 'é', 'f': 'ƒ', 'g': 'ĝ', 'h': 'ĥ', 'i': 'î', 'j': 'ĵ', 'k': 'ķ', 'l': 'ļ', 'm': 'ɱ',
+	'n': 'ñ', 'o': 'ö', 'p': 'þ', 'q': 'ǫ', 'r': 'ŕ', 's': 'š', 't': 'ţ', 'u': 'û', 'v': 'ṽ', 'w': 'ŵ', 'x': 'ẋ', 'y': 'ý', 'z': 'ž',
+	'A': 'Å', 'B': 'Ɓ', 'C': 'Ç', 'D': 'Ð', 'E': 'É', 'F': 'Ƒ', 'G': 'Ĝ', 'H': 'Ĥ', 'I': 'Î', 'J': 'Ĵ', 'K': 'Ķ', 'L': 'Ļ', 'M': 'Ṁ',
+	'N': 'Ñ', 'O': 'Ö', 'P': 'Þ', 'Q': 'Ǫ', 'R': 'Ŕ', 'S': 'Š', 'T': 'Ţ', 'U': 'Û', 'V': 'Ṽ', 'W': 'Ŵ', 'X': 'Ẋ', 'Y': 'Ý', 'Z': 'Ž',
+}
+
+//PseudoOptions rappresents how content is pseudo-localized: Expansion is the percentage of length added,
+//Mirrored wraps text in right to left override for RTL testing
+type PseudoOptions struct {
+	Expansion int
+	Mirrored  bool
+}
+
+//Pseudolocalize return content with accented letters, padded by Expansion percent of its text and between brackets;
+//placeholders, ICU syntax, markup and character references are kept as they are
+func Pseudolocalize(content string, options PseudoOptions) string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	length := 0
+	for _, segment := range Segments(content) {
+		if !segment.Translatable {
+			sb.WriteString(segment.Text)
+			continue
+		}
+
+		text := accentText(segment.Text)
+		trimmed := strings.TrimSpace(text)
+		length += utf8.RuneCountInString(trimmed)
+		if options.Mirrored && trimmed != "" {
+			//spaces around text stay outside the override, so they still separate it from placeholders
+			start := strings.Index(text, trimmed)
+			text = text[:start] + rightToLeftOverride + trimmed + popDirectional + text[start+len(trimmed):]
+		}
+		sb.WriteString(text)
+	}
+
+	if padding := (length*options.Expansion + 99) / 100; padding > 0 {
+		sb.WriteString(" " + strings.Repeat(pseudoPadding, padding))
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+//accentText replaces letters of text with their accented look-alike, but in character references
+func accentText(text string) string {
+	var sb strings.Builder
+	last := 0
+	for _, loc := range append(entityPattern.FindAllStringIndex(text, -1), []int{len(text), len(text)}) {
+		sb.WriteString(strings.Map(func(r rune) rune {
+			if a, ok := accented[r]; ok {
+				return a
+			}
+			return r
+		}, text[last:loc[0]]))
+		sb.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	return sb.String()
+}
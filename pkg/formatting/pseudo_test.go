@@ -0,0 +1,35 @@
+package formatting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPseudolocalize(t *testing.T) {
+	tests := []struct {
+		content  string
+		options  PseudoOptions
+		expected string
+	}{
+		{"Save", PseudoOptions{}, "[Šáṽé]"},
+		{"Save", PseudoOptions{Expansion: 30}, "[Šáṽé ~~]"},
+		{"Hello {name}, you have %d new <b>messages</b>", PseudoOptions{Expansion: 10}, "[Ĥéļļö {name}, ýöû ĥáṽé %d ñéŵ <b>ɱéššáĝéš</b> ~~~]"},
+		{"{n, plural, one {# file} other {# files}}", PseudoOptions{}, "[{n, plural, one {# ƒîļé} other {# ƒîļéš}}]"},
+		{"Tom &amp; Jerry", PseudoOptions{}, "[Ţöɱ &amp; Ĵéŕŕý]"},
+		{"{{ count }} items", PseudoOptions{}, "[{{ count }} îţéɱš]"},
+		{"Hi {name}", PseudoOptions{Mirrored: true}, "[\u202eĤî\u202c {name}]"},
+		{"", PseudoOptions{Expansion: 50}, "[]"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Pseudolocalize(tt.content, tt.options), tt.content)
+	}
+
+	//placeholders and ICU syntax are kept
+	source := "{count, plural, one {{name} has # file} other {{name} has # files}}"
+	result := Pseudolocalize(source, PseudoOptions{Expansion: 40, Mirrored: true})
+	_, err := Parse(result)
+	assert.NoError(t, err)
+	assert.Empty(t, ComparePlaceholders(ExtractPlaceholders(source), ExtractPlaceholders(result)))
+}
@@ -1,7 +1,10 @@
 package handling
 
 import (
+	"net/http"
+
 	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/authorizating"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
 	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/storaging"
 	"github.com/gin-gonic/gin"
 )
@@ -10,13 +13,20 @@ type genericMessage struct {
 	Message string
 }
 
-//NewHandler return a new router handler
-func NewHandler() (*gin.Engine, error) {
+//NewHandler return a new router handler, auth is the long-lived authenticator shared by every request
+func NewHandler(auth *authorizating.Autenticator) (*gin.Engine, error) {
 
 	rh := gin.Default()
+	rh.HandleMethodNotAllowed = true
+	rh.NoRoute(func(c *gin.Context) {
+		problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "No route for "+c.Request.URL.Path)
+	})
+	rh.NoMethod(func(c *gin.Context) {
+		problem.Abort(c, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, c.Request.Method+" not allowed on "+c.Request.URL.Path)
+	})
 
-	rh.GET("/callback", authorizating.CallbackHandler)
-	rh.GET("/login", authorizating.LoginHandler)
+	rh.GET("/callback", auth.CallbackHandler)
+	rh.GET("/login", auth.LoginHandler)
 	rh.GET("/logout", authorizating.LogoutHandler)
 
 	rh.GET("/info", authorizating.InfoHandler)
@@ -26,24 +36,71 @@ func NewHandler() (*gin.Engine, error) {
 		return nil, err
 	}
 
-	apiGroup := rh.Group("/api/v1")
+	//audit trail runs after authentication, so calls rejected as unauthenticated are not recorded
+	apiGroup := rh.Group("/api/v1", auth.AuthRequired(), lph.AuditTrail())
 	{
-		apiGroup.GET("/restricted", authorizating.AuthRequired(), authorizating.RestrictedHandler)
+		apiGroup.GET("/restricted", authorizating.RestrictedHandler)
+
+		apiGroup.GET("/langs", lph.GetAllLangs)
+		apiGroup.GET("/bundles", lph.GetAllBundles)
+		apiGroup.GET("/bundles/:id", lph.GetBundle)
+		apiGroup.POST("/bundles/:id", lph.PostBundle)
+		apiGroup.PATCH("/bundles/:id", lph.PatchBundle)
+		apiGroup.DELETE("/bundles/:id", lph.DeleteBundle)
+		apiGroup.GET("/bundles/:id/lint", lph.LintBundle)
+		apiGroup.GET("/bundles/:id/duplicates", lph.GetDuplicates)
+		apiGroup.POST("/bundles/:id/translate", lph.TranslateBundle)
+		apiGroup.GET("/bundles/:id/violations", lph.GetConstraintViolations)
+		apiGroup.GET("/bundles/:id/keys", lph.GetKeys)
+		apiGroup.PUT("/bundles/:id/keys/:key", lph.PutKey)
+		apiGroup.DELETE("/bundles/:id/keys/:key", lph.DeleteKey)
+		apiGroup.POST("/bundles/:id/keys/:key/rename", lph.RenameKey)
+		apiGroup.POST("/bundles/:id/keys/:key/screenshots", lph.PostScreenshot)
+		apiGroup.GET("/bundles/:id/keys/:key/screenshots/:screenshotId", lph.GetScreenshot)
+		apiGroup.DELETE("/bundles/:id/keys/:key/screenshots/:screenshotId", lph.DeleteScreenshot)
+		apiGroup.GET("/bundles/:id/export", lph.ExportBundle)
+		apiGroup.GET("/bundles/:id/questions", lph.GetOpenQuestions)
+		apiGroup.GET("/bundle/:bundleId/langs", lph.GetAllLangs)
+
+		apiGroup.GET("/locale-item/:id", lph.GetLocaleItemById)
+		apiGroup.GET("/locale-item/:id/suggestions", lph.GetLocaleItemSuggestions)
+		apiGroup.GET("/locale-item/:id/comments", lph.GetComments)
+		apiGroup.POST("/locale-item/:id/comments", lph.PostComment)
+		apiGroup.PATCH("/locale-item/:id/comments/:commentId", lph.PatchComment)
+		apiGroup.GET("/locale-item/:id/history", lph.GetLocaleItemHistory)
+		apiGroup.POST("/locale-item", lph.PostLocaleItem)
+		apiGroup.POST("/locale-items", lph.PostLocaleItems)
+
+		apiGroup.POST("/locale-items/:bundle", lph.GetLocaleItemByBundleKeyLang)
+
+		apiGroup.GET("/search", lph.SearchLocaleItems)
+		apiGroup.GET("/similar", lph.GetSimilarLocaleItems)
+
+		apiGroup.DELETE("/locale-items/:bundle", lph.DeleteLocaleItemByBundleKeyLang)
+		apiGroup.DELETE("/locale-items/:bundle/lang/:langId", lph.DeleteLocaleItemByBundleKeyLang)
+		apiGroup.DELETE("/locale-items/:bundle/lang/:langId/key/:keyId", lph.DeleteLocaleItemByBundleKeyLang)
+		apiGroup.DELETE("/locale-items/:bundle/key/:keyId", lph.DeleteLocaleItemByBundleKeyLang)
+
+		apiGroup.GET("/tm/suggestions", lph.GetSuggestions)
+		apiGroup.POST("/tm/import", lph.ImportMemory)
+		apiGroup.GET("/tm/export", lph.ExportMemory)
 
-		apiGroup.GET("/langs", authorizating.AuthRequired(), lph.GetAllLangs)
-		apiGroup.GET("/bundles", authorizating.AuthRequired(), lph.GetAllBundles)
-		apiGroup.GET("/bundle/:bundleId/langs", authorizating.AuthRequired(), lph.GetAllLangs)
+		apiGroup.GET("/glossary", lph.GetGlossary)
+		apiGroup.POST("/glossary", lph.PostGlossaryEntry)
+		apiGroup.POST("/glossary/import", lph.ImportGlossary)
+		apiGroup.PATCH("/glossary/:id", lph.PatchGlossaryEntry)
+		apiGroup.DELETE("/glossary/:id", lph.DeleteGlossaryEntry)
 
-		apiGroup.GET("/locale-item/:id", authorizating.AuthRequired(), lph.GetLocaleItemById)
-		apiGroup.POST("/locale-item", authorizating.AuthRequired(), lph.PostLocaleItem)
-		apiGroup.POST("/locale-items", authorizating.AuthRequired(), lph.PostLocaleItems)
+		apiGroup.GET("/trash", lph.GetTrash)
+		apiGroup.POST("/trash/restore", lph.RestoreTrash)
 
-		apiGroup.POST("/locale-items/:bundle", authorizating.AuthRequired(), lph.GetLocaleItemByBundleKeyLang)
+		apiGroup.GET("/audit", lph.GetAuditEntries)
 
-		apiGroup.DELETE("/locale-items/:bundle", authorizating.AuthRequired(), lph.DeleteLocaleItemByBundleKeyLang)
-		apiGroup.DELETE("/locale-items/:bundle/lang/:langId", authorizating.AuthRequired(), lph.DeleteLocaleItemByBundleKeyLang)
-		apiGroup.DELETE("/locale-items/:bundle/lang/:langId/key/:keyId", authorizating.AuthRequired(), lph.DeleteLocaleItemByBundleKeyLang)
-		apiGroup.DELETE("/locale-items/:bundle/key/:keyId", authorizating.AuthRequired(), lph.DeleteLocaleItemByBundleKeyLang)
+		apiGroup.GET("/languages", lph.GetLanguages)
+		apiGroup.GET("/languages/:tag", lph.GetLanguage)
+		apiGroup.POST("/languages", lph.PostLanguage)
+		apiGroup.PATCH("/languages/:tag", lph.PatchLanguage)
+		apiGroup.DELETE("/languages/:tag", lph.DeleteLanguage)
 
 	}
 
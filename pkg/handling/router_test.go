@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"regexp"
 	"strings"
@@ -44,7 +45,8 @@ func TestMain(m *testing.M) {
 		log.Panicln(err)
 	}
 
-	r, err = NewHandler()
+	//no authenticator needed, auth is bypassed in test mode
+	r, err = NewHandler(nil)
 	if err != nil {
 		log.Panicln(err)
 	}
@@ -67,11 +69,13 @@ func TestRoutes(t *testing.T) {
 		{"post localeitems wrong payload", testWrongPostLocaleItems},
 		{"bundles", testBundles},
 		{"langs", testLangs},
+		{"languages registry", testLanguages},
 		{"get all locale items by bundle", testGetLocaleItemsByBundle},
 		{"get all locale items by bundle limited by limit", testGetLocaleItemsByBundleWithLimit},
 		{"get locale item by bundle and lang", testGetLocaleItemsByLang},
 		{"get locale item by key", testGetLocaleItemsByKey},
 		{"delete locale item by bundle", testDeleteLangByBundle},
+		{"rename key keeps comments", testRenameKeyKeepsComments},
 	}
 
 	for _, ct := range apiTest {
@@ -94,7 +98,22 @@ func testBundles(t *testing.T) {
 	req, _ := http.NewRequest("GET", "/api/v1/bundles", nil)
 	r.ServeHTTP(w, req)
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Contains(t, w.Body.String(), "label")
+	assert.Contains(t, w.Body.String(), `"id":"label"`)
+	assert.Contains(t, w.Body.String(), `"num_items"`)
+}
+
+func testLanguages(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/languages?enabled=true", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"tag":"it-IT"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/languages/it_it", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"plural_categories":["one","other"]`)
 }
 
 func testLangs(t *testing.T) {
@@ -183,7 +202,11 @@ func testWrongPostLocaleItems(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, w.Code)
 	assert.JSONEq(t, `{
 		"num_successful": 0,
-		"num_failed": 2
+		"num_failed": 2,
+		"errors": [
+			{"index": 0, "field": "key", "code": "required", "message": "key is required"},
+			{"index": 1, "field": "lang", "code": "required", "message": "lang is required"}
+		]
 	}`, w.Body.String())
 }
 
@@ -309,27 +332,21 @@ func testGetLocaleItemsByKey(t *testing.T) {
 
 func testDeleteLangByBundle(t *testing.T) {
 
-	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("DELETE", "/api/v1/locale-items/message/lang/it-IT", nil)
-	r.ServeHTTP(w, req)
+	w := confirmedDelete(t, "/api/v1/locale-items/message/lang/it-IT")
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.JSONEq(t, `{
 		"num_successful": 1,
 		"num_failed": 0
 	}`, w.Body.String())
 
-	w = httptest.NewRecorder()
-	req, _ = http.NewRequest("DELETE", "/api/v1/locale-items/message/key/@ALERT_ERROR@", nil)
-	r.ServeHTTP(w, req)
+	w = confirmedDelete(t, "/api/v1/locale-items/message/key/@ALERT_ERROR@")
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.JSONEq(t, `{
 		"num_successful": 0,
 		"num_failed": 0
 	}`, w.Body.String())
 
-	w = httptest.NewRecorder()
-	req, _ = http.NewRequest("DELETE", "/api/v1/locale-items/message", nil)
-	r.ServeHTTP(w, req)
+	w = confirmedDelete(t, "/api/v1/locale-items/message")
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.JSONEq(t, `{
 		"num_successful": 0,
@@ -337,11 +354,83 @@ func testDeleteLangByBundle(t *testing.T) {
 	}`, w.Body.String())
 
 	w = httptest.NewRecorder()
-	req, _ = http.NewRequest("DELETE", "/api/v1/locale-items/label", nil)
+	req, _ := http.NewRequest("DELETE", "/api/v1/locale-items/label", nil)
 	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w = confirmedDelete(t, "/api/v1/locale-items/label")
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.JSONEq(t, `{
 		"num_successful": 4,
 		"num_failed": 0
 	}`, w.Body.String())
 }
+
+func testRenameKeyKeepsComments(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/locale-item", strings.NewReader(`{
+		"bundle": "message",
+		"key": "@RENAME_ME@",
+		"lang": "it-IT",
+		"content": "Da rinominare"
+	}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var item storaging.LocaleItem
+	err := json.Unmarshal(w.Body.Bytes(), &item)
+	if err != nil {
+		t.Fatalf("error on parse posted item: %v\n", err)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/locale-item/"+item.ID+"/comments", strings.NewReader(`{"body": "is it the alert title?"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/api/v1/bundles/message/keys/@RENAME_ME@/rename", strings.NewReader(`{"key": "@RENAMED@"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{
+		"num_successful": 1,
+		"num_failed": 0
+	}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/locale-item/"+item.ID, nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"key":"@RENAMED@"`)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/locale-item/"+item.ID+"/comments", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "is it the alert title?")
+
+	w = confirmedDelete(t, "/api/v1/locale-items/message/key/@RENAMED@")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+//confirmedDelete ask for a dry-run and then delete with the returned confirmation token
+func confirmedDelete(t *testing.T, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", path+"?dry_run=true", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var preview storaging.DeletePreview
+	err := json.Unmarshal(w.Body.Bytes(), &preview)
+	if err != nil {
+		t.Fatalf("error on parse dry-run result: %v\n", err)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", path+"?confirmation_token="+url.QueryEscape(preview.ConfirmationToken), nil)
+	r.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,137 @@
+package localizing
+
+import (
+	"strings"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+//text directions
+const (
+	DirectionLTR = "ltr"
+	DirectionRTL = "rtl"
+)
+
+//pseudo-localization languages, they are virtual: content is the one of source lang transformed on read
+const (
+	PseudoAccented = "qps-ploc"
+	PseudoMirrored = "qps-plocm"
+)
+
+//plural categories as named by CLDR, in CLDR order
+const (
+	PluralZero  = "zero"
+	PluralOne   = "one"
+	PluralTwo   = "two"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+var pluralOrder = []string{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther}
+
+var pluralNames = map[plural.Form]string{
+	plural.Zero:  PluralZero,
+	plural.One:   PluralOne,
+	plural.Two:   PluralTwo,
+	plural.Few:   PluralFew,
+	plural.Many:  PluralMany,
+	plural.Other: PluralOther,
+}
+
+//scripts written right to left
+var rtlScripts = map[string]bool{
+	"Adlm": true, "Arab": true, "Hebr": true, "Mand": true, "Nkoo": true,
+	"Rohg": true, "Samr": true, "Syrc": true, "Thaa": true, "Yezi": true,
+}
+
+//Canonicalize return the canonical form of a BCP 47 tag, so it_it, it-it and it-IT are all it-IT
+func Canonicalize(tag string) (string, error) {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return "", err
+	}
+	return t.String(), nil
+}
+
+//CanonicalizeOrKeep return the canonical form of tag or tag itself when it's not a valid BCP 47 tag
+func CanonicalizeOrKeep(tag string) string {
+	if pseudo, ok := Pseudo(tag); ok {
+		return pseudo
+	}
+	canonical, err := Canonicalize(tag)
+	if err != nil {
+		return tag
+	}
+	return canonical
+}
+
+//Pseudo return the pseudo-localization language of tag, in lower case with '-', and if tag is one
+func Pseudo(tag string) (string, bool) {
+	candidate := strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+	if candidate == PseudoAccented || candidate == PseudoMirrored {
+		return candidate, true
+	}
+	return "", false
+}
+
+//DisplayName return the name of the language in the language itself
+func DisplayName(tag language.Tag) string {
+	return display.Self.Name(tag)
+}
+
+//Direction return rtl for languages written in a right to left script, ltr otherwise
+func Direction(tag language.Tag) string {
+	script, _ := tag.Script()
+	if rtlScripts[script.String()] {
+		return DirectionRTL
+	}
+	return DirectionLTR
+}
+
+//Parent return the parent locale of tag, empty for a language without region or script
+func Parent(tag language.Tag) string {
+	parent := tag.Parent()
+	if parent == language.Und {
+		return ""
+	}
+	return parent.String()
+}
+
+//PluralCategories return the cardinal plural categories used by the language, in CLDR order
+func PluralCategories(tag language.Tag) []string {
+	return probeCategories(plural.Cardinal, tag)
+}
+
+//OrdinalCategories return the ordinal plural categories used by the language, in CLDR order
+func OrdinalCategories(tag language.Tag) []string {
+	return probeCategories(plural.Ordinal, tag)
+}
+
+//probeCategories collects the categories rules give to a sample of numbers
+func probeCategories(rules *plural.Rules, tag language.Tag) []string {
+	found := map[string]bool{}
+
+	//integers cover every category but for some languages the ones of decimals
+	for i := 0; i <= 200; i++ {
+		found[pluralNames[rules.MatchPlural(tag, i, 0, 0, 0, 0)]] = true
+	}
+	for _, i := range []int{1000, 10000, 100000, 1000000} {
+		found[pluralNames[rules.MatchPlural(tag, i, 0, 0, 0, 0)]] = true
+	}
+	for i := 0; i <= 20; i++ {
+		for f := 0; f <= 9; f++ {
+			found[pluralNames[rules.MatchPlural(tag, i, 1, f, f, f)]] = true
+		}
+	}
+
+	categories := []string{}
+	for _, category := range pluralOrder {
+		if found[category] {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
@@ -0,0 +1,67 @@
+package localizing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/language"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		tag       string
+		canonical string
+	}{
+		{"en_US", "en-US"},
+		{"it-it", "it-IT"},
+		{"IT_it", "it-IT"},
+		{"zh-hant-tw", "zh-Hant-TW"},
+		{"iw", "he"},
+	}
+
+	for _, tt := range tests {
+		canonical, err := Canonicalize(tt.tag)
+		assert.NoError(t, err, tt.tag)
+		assert.Equal(t, tt.canonical, canonical, tt.tag)
+	}
+
+	_, err := Canonicalize("english!")
+	assert.Error(t, err)
+	assert.Equal(t, "english!", CanonicalizeOrKeep("english!"))
+	assert.Equal(t, "", CanonicalizeOrKeep(""))
+	assert.Equal(t, PseudoMirrored, CanonicalizeOrKeep("QPS_PLOCM"))
+}
+
+func TestPseudo(t *testing.T) {
+	pseudo, ok := Pseudo("qps-PLOC")
+	assert.True(t, ok)
+	assert.Equal(t, PseudoAccented, pseudo)
+
+	_, ok = Pseudo("en-US")
+	assert.False(t, ok)
+}
+
+func TestDirection(t *testing.T) {
+	assert.Equal(t, DirectionRTL, Direction(language.MustParse("ar")))
+	assert.Equal(t, DirectionRTL, Direction(language.MustParse("he-IL")))
+	assert.Equal(t, DirectionLTR, Direction(language.MustParse("it-IT")))
+	assert.Equal(t, DirectionLTR, Direction(language.MustParse("zh-Hant")))
+}
+
+func TestParent(t *testing.T) {
+	assert.Equal(t, "en", Parent(language.MustParse("en-US")))
+	assert.Equal(t, "", Parent(language.MustParse("it")))
+}
+
+func TestPluralCategories(t *testing.T) {
+	assert.Equal(t, []string{PluralOne, PluralOther}, PluralCategories(language.MustParse("it")))
+	assert.Equal(t, []string{PluralOne, PluralFew, PluralMany, PluralOther}, PluralCategories(language.MustParse("ru")))
+	assert.Equal(t, []string{PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther}, PluralCategories(language.MustParse("ar")))
+	assert.Equal(t, []string{PluralOther}, PluralCategories(language.MustParse("ja")))
+}
+
+func TestOrdinalCategories(t *testing.T) {
+	assert.Equal(t, []string{PluralOne, PluralTwo, PluralFew, PluralOther}, OrdinalCategories(language.MustParse("en")))
+	assert.Equal(t, []string{PluralMany, PluralOther}, OrdinalCategories(language.MustParse("it")))
+	assert.Equal(t, []string{PluralOther}, OrdinalCategories(language.MustParse("de")))
+}
@@ -8,4 +8,4 @@ CREATE TABLE IF NOT EXISTS localeitems(
         pKey_localeitems PRIMARY KEY (id),
 	CONSTRAINT
         uKey_localeitems UNIQUE ( key, bundle, lang ) 
-)
\ No newline at end of file
+);
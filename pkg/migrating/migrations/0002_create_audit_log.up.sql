@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS audit_log(
+    id bigserial NOT NULL,
+    user_name VARCHAR(256),
+    method VARCHAR(8),
+    route VARCHAR(256),
+    bundle VARCHAR(128),
+    lang VARCHAR(35),
+    key VARCHAR(512),
+    status INTEGER,
+    num_successful BIGINT,
+    num_failed BIGINT,
+    source_ip VARCHAR(64),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT
+        pKey_audit_log PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+CREATE OR REPLACE RULE audit_log_no_update AS ON UPDATE TO audit_log DO INSTEAD NOTHING;
+CREATE OR REPLACE RULE audit_log_no_delete AS ON DELETE TO audit_log DO INSTEAD NOTHING;
@@ -0,0 +1,3 @@
+DELETE FROM localeitems WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS idx_localeitems_deleted_at;
+ALTER TABLE localeitems DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,2 @@
+ALTER TABLE localeitems ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_localeitems_deleted_at ON localeitems (deleted_at) WHERE deleted_at IS NOT NULL;
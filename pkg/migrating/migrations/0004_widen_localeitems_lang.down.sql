@@ -0,0 +1,9 @@
+-- items with a lang longer than 8 chars, as zh-Hant-TW or qps-plocm, do not fit back: delete or retag them before rolling back
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM localeitems WHERE length(lang) > 8) THEN
+        RAISE EXCEPTION 'cannot roll back 0004: localeitems has langs longer than 8 chars, delete or retag them first';
+    END IF;
+END
+$$;
+ALTER TABLE localeitems ALTER COLUMN lang TYPE VARCHAR(8);
@@ -0,0 +1,2 @@
+-- BCP 47 tags like zh-Hant-TW or sr-Latn-RS-u-nu-latn do not fit in 8 chars
+ALTER TABLE localeitems ALTER COLUMN lang TYPE VARCHAR(35);
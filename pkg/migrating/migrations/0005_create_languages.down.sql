@@ -0,0 +1,2 @@
+-- lang values canonicalized by the up migration are kept
+DROP TABLE IF EXISTS languages;
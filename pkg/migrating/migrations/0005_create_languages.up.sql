@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS languages(
+    tag VARCHAR(35) NOT NULL,
+    display_name VARCHAR(128) NOT NULL,
+    direction VARCHAR(3) NOT NULL DEFAULT 'ltr',
+    plural_categories TEXT[] NOT NULL DEFAULT '{other}',
+    parent_tag VARCHAR(35),
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT
+        pKey_languages PRIMARY KEY (tag),
+    CONSTRAINT
+        cKey_languages_direction CHECK (direction IN ('ltr', 'rtl'))
+);
+
+INSERT INTO languages(tag, display_name, direction, plural_categories, parent_tag) VALUES
+    ('en', 'English', 'ltr', '{one,other}', NULL),
+    ('en-001', 'International English', 'ltr', '{one,other}', 'en'),
+    ('en-US', 'American English', 'ltr', '{one,other}', 'en'),
+    ('en-GB', 'British English', 'ltr', '{one,other}', 'en-001'),
+    ('it', 'italiano', 'ltr', '{one,other}', NULL),
+    ('it-IT', 'italiano', 'ltr', '{one,other}', 'it'),
+    ('de', 'Deutsch', 'ltr', '{one,other}', NULL),
+    ('de-DE', 'Deutsch', 'ltr', '{one,other}', 'de'),
+    ('fr', 'français', 'ltr', '{one,other}', NULL),
+    ('fr-FR', 'français', 'ltr', '{one,other}', 'fr'),
+    ('es', 'español', 'ltr', '{one,other}', NULL),
+    ('es-ES', 'español de España', 'ltr', '{one,other}', 'es'),
+    ('pt', 'português', 'ltr', '{one,other}', NULL),
+    ('pt-BR', 'português', 'ltr', '{one,other}', 'pt'),
+    ('nl', 'Nederlands', 'ltr', '{one,other}', NULL),
+    ('pl', 'polski', 'ltr', '{one,few,many,other}', NULL),
+    ('ru', 'русский', 'ltr', '{one,few,many,other}', NULL),
+    ('ja', '日本語', 'ltr', '{other}', NULL),
+    ('zh', '中文', 'ltr', '{other}', NULL),
+    ('zh-Hans', '简体中文', 'ltr', '{other}', 'zh'),
+    ('zh-Hant', '繁體中文', 'ltr', '{other}', NULL),
+    ('ar', 'العربية', 'rtl', '{zero,one,two,few,many,other}', NULL),
+    ('he', 'עברית', 'rtl', '{one,two,many,other}', NULL)
+ON CONFLICT DO NOTHING;
+
+-- en_US to en-US is the only canonicalization sql can do, new writes are canonicalized by the service
+UPDATE localeitems li SET lang = replace(li.lang, '_', '-')
+WHERE li.lang LIKE '%\_%' ESCAPE '\'
+    AND NOT EXISTS (
+        SELECT 1 FROM localeitems other
+        WHERE other.key = li.key AND other.bundle = li.bundle AND other.lang = replace(li.lang, '_', '-')
+    );
+
+-- languages already in use stay writable
+INSERT INTO languages(tag, display_name)
+SELECT DISTINCT lang, lang FROM localeitems WHERE lang IS NOT NULL AND lang <> ''
+ON CONFLICT DO NOTHING;
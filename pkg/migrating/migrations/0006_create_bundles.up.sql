@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS bundles(
+    id VARCHAR(128) NOT NULL,
+    description VARCHAR(1024) NOT NULL DEFAULT '',
+    owner VARCHAR(128) NOT NULL DEFAULT '',
+    source_lang VARCHAR(35),
+    target_langs TEXT[] NOT NULL DEFAULT '{}',
+    key_pattern VARCHAR(512) NOT NULL DEFAULT '',
+    fallback_chain TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT
+        pKey_bundles PRIMARY KEY (id)
+);
+
+-- bundles already in use get a row with default settings
+INSERT INTO bundles(id)
+SELECT DISTINCT bundle FROM localeitems WHERE bundle IS NOT NULL AND bundle <> ''
+ON CONFLICT DO NOTHING;
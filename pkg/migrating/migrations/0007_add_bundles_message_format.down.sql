@@ -0,0 +1 @@
+ALTER TABLE bundles DROP COLUMN IF EXISTS message_format;
@@ -0,0 +1,2 @@
+ALTER TABLE bundles ADD COLUMN IF NOT EXISTS message_format VARCHAR(16) NOT NULL DEFAULT 'none';
+ALTER TABLE bundles ADD CONSTRAINT cKey_bundles_message_format CHECK (message_format IN ('none', 'icu'));
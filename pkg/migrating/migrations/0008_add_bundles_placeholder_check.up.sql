@@ -0,0 +1,2 @@
+ALTER TABLE bundles ADD COLUMN IF NOT EXISTS placeholder_check VARCHAR(8) NOT NULL DEFAULT 'warn';
+ALTER TABLE bundles ADD CONSTRAINT cKey_bundles_placeholder_check CHECK (placeholder_check IN ('off', 'warn', 'error'));
@@ -0,0 +1 @@
+ALTER TABLE localeitems DROP COLUMN IF EXISTS plurals;
@@ -0,0 +1,2 @@
+-- plural variants by CLDR category, NULL for items without them
+ALTER TABLE localeitems ADD COLUMN IF NOT EXISTS plurals JSONB;
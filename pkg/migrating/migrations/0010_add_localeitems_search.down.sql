@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_localeitems_search_vector;
+ALTER TABLE localeitems DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS localeitems_any_tsquery(TEXT);
+DROP FUNCTION IF EXISTS localeitems_ts_config(TEXT);
+
+DO $$
+DECLARE
+    config TEXT;
+BEGIN
+    FOR config IN SELECT cfgname FROM pg_ts_config WHERE cfgname LIKE 'localeitems\_%' LOOP
+        EXECUTE format('DROP TEXT SEARCH CONFIGURATION IF EXISTS %I', config);
+    END LOOP;
+END
+$$;
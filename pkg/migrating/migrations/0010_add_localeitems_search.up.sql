@@ -0,0 +1,63 @@
+-- full-text search of content: a text search config per language, accents are ignored by unaccent
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+DO $$
+DECLARE
+    dictionary TEXT;
+    config TEXT;
+BEGIN
+    FOREACH dictionary IN ARRAY ARRAY['simple', 'danish', 'dutch', 'english', 'finnish', 'french', 'german', 'hungarian',
+        'italian', 'norwegian', 'portuguese', 'romanian', 'russian', 'spanish', 'swedish', 'turkish'] LOOP
+        config := 'localeitems_' || dictionary;
+        IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = config) THEN
+            EXECUTE format('CREATE TEXT SEARCH CONFIGURATION %I (COPY = pg_catalog.%I)', config, dictionary);
+            EXECUTE format('ALTER TEXT SEARCH CONFIGURATION %I ALTER MAPPING FOR hword, hword_part, word WITH unaccent, %I',
+                config, CASE WHEN dictionary = 'simple' THEN 'simple' ELSE dictionary || '_stem' END);
+        END IF;
+    END LOOP;
+END
+$$;
+
+-- text search config of a BCP 47 tag by its language subtag, simple when there is no stemmer for it
+CREATE OR REPLACE FUNCTION localeitems_ts_config(lang TEXT) RETURNS regconfig
+LANGUAGE SQL IMMUTABLE PARALLEL SAFE AS $$
+    SELECT (CASE lower(split_part(lang, '-', 1))
+        WHEN 'da' THEN 'localeitems_danish'
+        WHEN 'nl' THEN 'localeitems_dutch'
+        WHEN 'en' THEN 'localeitems_english'
+        WHEN 'fi' THEN 'localeitems_finnish'
+        WHEN 'fr' THEN 'localeitems_french'
+        WHEN 'de' THEN 'localeitems_german'
+        WHEN 'hu' THEN 'localeitems_hungarian'
+        WHEN 'it' THEN 'localeitems_italian'
+        WHEN 'no' THEN 'localeitems_norwegian'
+        WHEN 'nb' THEN 'localeitems_norwegian'
+        WHEN 'nn' THEN 'localeitems_norwegian'
+        WHEN 'pt' THEN 'localeitems_portuguese'
+        WHEN 'ro' THEN 'localeitems_romanian'
+        WHEN 'ru' THEN 'localeitems_russian'
+        WHEN 'es' THEN 'localeitems_spanish'
+        WHEN 'sv' THEN 'localeitems_swedish'
+        WHEN 'tr' THEN 'localeitems_turkish'
+        ELSE 'localeitems_simple'
+    END)::regconfig
+$$;
+
+-- query of every text search config in OR, constant for a search so the GIN index can be used
+-- when results are not filtered by lang; the query of the config of the item is checked after
+CREATE OR REPLACE FUNCTION localeitems_any_tsquery(q TEXT) RETURNS tsquery
+LANGUAGE plpgsql STABLE PARALLEL SAFE AS $$
+DECLARE
+    config regconfig;
+    result tsquery := ''::tsquery;
+BEGIN
+    FOR config IN SELECT oid::regconfig FROM pg_ts_config WHERE cfgname LIKE 'localeitems\_%' LOOP
+        result := result || websearch_to_tsquery(config, q);
+    END LOOP;
+    RETURN result;
+END
+$$;
+
+ALTER TABLE localeitems ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector(localeitems_ts_config(lang), coalesce(content, ''))) STORED;
+CREATE INDEX IF NOT EXISTS idx_localeitems_search_vector ON localeitems USING GIN (search_vector);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_localeitems_content_trgm;
@@ -0,0 +1,3 @@
+-- trigram similarity of content, to find near-duplicate strings
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_localeitems_content_trgm ON localeitems USING GIN (content gin_trgm_ops);
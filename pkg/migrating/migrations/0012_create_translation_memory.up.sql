@@ -0,0 +1,16 @@
+-- translation units imported from TMX, suggestions come from them and from stored source/target pairs
+CREATE TABLE IF NOT EXISTS translation_memory(
+    id serial NOT NULL,
+    source_lang VARCHAR(35) NOT NULL,
+    source_text VARCHAR(4096) NOT NULL,
+    target_lang VARCHAR(35) NOT NULL,
+    target_text VARCHAR(4096) NOT NULL,
+    bundle VARCHAR(128),
+    key VARCHAR(512),
+    origin VARCHAR(128) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT
+        pKey_translation_memory PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uKey_translation_memory ON translation_memory (source_lang, target_lang, md5(source_text), md5(target_text));
+CREATE INDEX IF NOT EXISTS idx_translation_memory_source_trgm ON translation_memory USING GIN (source_text gin_trgm_ops);
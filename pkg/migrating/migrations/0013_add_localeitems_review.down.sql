@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_localeitems_needs_review;
+ALTER TABLE localeitems DROP COLUMN IF EXISTS needs_review;
+ALTER TABLE localeitems DROP COLUMN IF EXISTS machine_translated;
@@ -0,0 +1,4 @@
+-- workflow flags: machine translated items need review before they are used as translation memory
+ALTER TABLE localeitems ADD COLUMN IF NOT EXISTS machine_translated BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE localeitems ADD COLUMN IF NOT EXISTS needs_review BOOLEAN NOT NULL DEFAULT false;
+CREATE INDEX IF NOT EXISTS idx_localeitems_needs_review ON localeitems (bundle, lang) WHERE needs_review;
@@ -0,0 +1,14 @@
+-- approved terms per language pair, translations of items whose source has a term are checked on write
+CREATE TABLE IF NOT EXISTS glossary(
+    id serial NOT NULL,
+    source_lang VARCHAR(35) NOT NULL,
+    source_term VARCHAR(256) NOT NULL,
+    target_lang VARCHAR(35) NOT NULL,
+    target_term VARCHAR(256) NOT NULL,
+    do_not_translate BOOLEAN NOT NULL DEFAULT false,
+    note VARCHAR(1024) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT
+        pKey_glossary PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uKey_glossary ON glossary (source_lang, target_lang, lower(source_term));
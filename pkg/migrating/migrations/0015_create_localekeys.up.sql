@@ -0,0 +1,16 @@
+-- metadata of keys, shared by the items of a key in every lang; constraints are checked on every write
+CREATE TABLE IF NOT EXISTS localekeys(
+    bundle VARCHAR(128) NOT NULL,
+    key VARCHAR(512) NOT NULL,
+    max_length INTEGER NOT NULL DEFAULT 0,
+    length_unit VARCHAR(16) NOT NULL DEFAULT 'chars',
+    charset VARCHAR(16) NOT NULL DEFAULT 'any',
+    multiline BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT
+        pKey_localekeys PRIMARY KEY (bundle, key),
+    CONSTRAINT
+        cKey_localekeys_max_length CHECK (max_length >= 0),
+    CONSTRAINT
+        cKey_localekeys_length_unit CHECK (length_unit IN ('chars', 'graphemes'))
+);
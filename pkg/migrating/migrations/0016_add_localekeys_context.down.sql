@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS screenshots;
+DROP INDEX IF EXISTS idx_localekeys_tags;
+ALTER TABLE localekeys
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS note,
+    DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,23 @@
+-- context for translators: description, developer note and tags of a key, with its screenshots
+ALTER TABLE localekeys
+    ADD COLUMN IF NOT EXISTS description VARCHAR(1024) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS note VARCHAR(1024) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_localekeys_tags ON localekeys USING GIN (tags);
+
+-- images are in data or, when stored in a blob directory, in the file blob_path of it
+CREATE TABLE IF NOT EXISTS screenshots(
+    id serial NOT NULL,
+    bundle VARCHAR(128) NOT NULL,
+    key VARCHAR(512) NOT NULL,
+    content_type VARCHAR(64) NOT NULL,
+    size INTEGER NOT NULL,
+    data BYTEA,
+    blob_path VARCHAR(256) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT
+        pKey_screenshots PRIMARY KEY (id),
+    CONSTRAINT
+        fKey_screenshots_localekeys FOREIGN KEY (bundle, key) REFERENCES localekeys (bundle, key) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_screenshots_key ON screenshots (bundle, key);
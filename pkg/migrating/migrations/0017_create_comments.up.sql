@@ -0,0 +1,23 @@
+-- comment threads of translators, by item id so they stay with the item when its key is renamed;
+-- replies have parent_id the first comment of the thread, that holds the resolved state
+CREATE TABLE IF NOT EXISTS comments(
+    id bigserial NOT NULL,
+    localeitem_id INTEGER NOT NULL,
+    parent_id BIGINT,
+    author VARCHAR(256) NOT NULL,
+    body VARCHAR(4096) NOT NULL,
+    mentions TEXT[] NOT NULL DEFAULT '{}',
+    resolved BOOLEAN NOT NULL DEFAULT false,
+    resolved_by VARCHAR(256) NOT NULL DEFAULT '',
+    resolved_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT
+        pKey_comments PRIMARY KEY (id),
+    CONSTRAINT
+        fKey_comments_localeitems FOREIGN KEY (localeitem_id) REFERENCES localeitems (id) ON DELETE CASCADE,
+    CONSTRAINT
+        fKey_comments_parent FOREIGN KEY (parent_id) REFERENCES comments (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_comments_localeitem ON comments (localeitem_id, id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent ON comments (parent_id);
+CREATE INDEX IF NOT EXISTS idx_comments_open ON comments (localeitem_id) WHERE parent_id IS NULL AND NOT resolved;
@@ -0,0 +1,3 @@
+ALTER TABLE screenshots
+    DROP CONSTRAINT IF EXISTS fKey_screenshots_localekeys,
+    ADD CONSTRAINT fKey_screenshots_localekeys FOREIGN KEY (bundle, key) REFERENCES localekeys (bundle, key) ON DELETE CASCADE;
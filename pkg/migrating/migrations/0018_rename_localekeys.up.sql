@@ -0,0 +1,4 @@
+-- a renamed key takes its screenshots along
+ALTER TABLE screenshots
+    DROP CONSTRAINT IF EXISTS fKey_screenshots_localekeys,
+    ADD CONSTRAINT fKey_screenshots_localekeys FOREIGN KEY (bundle, key) REFERENCES localekeys (bundle, key) ON UPDATE CASCADE ON DELETE CASCADE;
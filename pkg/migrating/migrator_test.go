@@ -0,0 +1,72 @@
+package migrating
+
+import (
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatalf("error on load embedded migrations: %v\n", err)
+	}
+
+	for i, migration := range migrations {
+		assert.Equal(t, int64(i+1), migration.Version, "versions must be contiguous")
+		assert.NotEmpty(t, migration.Name)
+	}
+}
+
+func TestSeededParentsAreSeeded(t *testing.T) {
+	source, err := migrationFiles.ReadFile("migrations/0005_create_languages.up.sql")
+	if err != nil {
+		t.Fatalf("error on read languages migration: %v\n", err)
+	}
+
+	seeded := map[string]bool{}
+	parents := map[string]string{}
+	row := regexp.MustCompile(`\('([^']+)', '[^']*', '(?:ltr|rtl)', '[^']*', (?:NULL|'([^']+)')\)`)
+	for _, match := range row.FindAllStringSubmatch(string(source), -1) {
+		seeded[match[1]] = true
+		if match[2] != "" {
+			parents[match[1]] = match[2]
+		}
+	}
+
+	assert.NotEmpty(t, parents)
+	for tag, parent := range parents {
+		assert.True(t, seeded[parent], "parent %s of %s must be seeded", parent, tag)
+	}
+}
+
+func TestLoadMigrationsNeedsUpAndDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_first.up.sql":   {Data: []byte("SELECT 1")},
+		"migrations/0001_first.down.sql": {Data: []byte("SELECT 1")},
+		"migrations/0002_second.up.sql":  {Data: []byte("SELECT 2")},
+	}
+
+	_, err := loadMigrations(fsys)
+	assert.Error(t, err)
+}
+
+func TestLoadMigrationsSortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0010_last.up.sql":    {Data: []byte("SELECT 10")},
+		"migrations/0010_last.down.sql":  {Data: []byte("SELECT -10")},
+		"migrations/0002_first.up.sql":   {Data: []byte("SELECT 2")},
+		"migrations/0002_first.down.sql": {Data: []byte("SELECT -2")},
+	}
+
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("error on load migrations: %v\n", err)
+	}
+
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, "first", migrations[0].Name)
+	assert.Equal(t, "SELECT -10", migrations[1].Down)
+}
@@ -0,0 +1,80 @@
+package problem
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//ContentType is the media type of every error response
+const ContentType = "application/problem+json"
+
+//typePrefix is the base of the type uri, completed by the problem code
+const typePrefix = "urn:locale-mgmt:problem:"
+
+//stable codes clients can switch on, never change their value
+const (
+	CodeBadRequest           = "bad_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodeConfirmationRequired = "confirmation_required"
+	CodeConfirmationInvalid  = "confirmation_invalid"
+	CodeClientClosedRequest  = "client_closed_request"
+	CodeInternal             = "internal_error"
+	CodeUpstreamFailed       = "upstream_failed"
+	CodeUnavailable          = "unavailable"
+	CodeTimeout              = "timeout"
+)
+
+//Problem rappresents an RFC 7807 error body, Code is the stable extension member for clients
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     string      `json:"code"`
+	Errors   interface{} `json:"errors,omitempty"`
+}
+
+//New return a problem for status and code
+func New(status int, code, detail string) Problem {
+	title := http.StatusText(status)
+	if title == "" {
+		title = code
+	}
+
+	return Problem{
+		Type:   typePrefix + code,
+		Title:  title,
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+//Abort stops the chain writing a problem for status and code
+func Abort(c *gin.Context, status int, code, detail string) {
+	AbortWith(c, New(status, code, detail))
+}
+
+//AbortWith stops the chain writing p as application/problem+json
+func AbortWith(c *gin.Context, p Problem) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+//AbortInternal logs err and stops the chain with a generic 500, so internal details do not leak
+func AbortInternal(c *gin.Context, err error) {
+	log.Printf("Internal error on %s %s: %v\n", c.Request.Method, c.Request.URL.Path, err)
+	Abort(c, http.StatusInternalServerError, CodeInternal, "The server could not complete the request")
+}
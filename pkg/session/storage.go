@@ -15,5 +15,7 @@ var (
 func InitSessionStorage() error {
 	Store = sessions.NewCookieStore([]byte(os.Getenv("KEY_FOR_SESSION_STORE")))
 	gob.Register(map[string]interface{}{})
+	//profile claims like roles are arrays
+	gob.Register([]interface{}{})
 	return nil
 }
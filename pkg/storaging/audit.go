@@ -0,0 +1,212 @@
+package storaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/authorizating"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	auditResultKey  = "audit.result"
+	auditFiltersKey = "audit.filters"
+	auditDryRunKey  = "audit.dry_run"
+	//defaultAuditLimit is used when limit is not set
+	defaultAuditLimit = 100
+	//maxAuditLimit is the max number of entries of a page, and the page size of jsonl export
+	maxAuditLimit = 1000
+)
+
+//auditReadRoutes are the POST routes that only read, so not recorded in audit log
+var auditReadRoutes = map[string]bool{
+	"/api/v1/locale-items/:bundle": true,
+}
+
+//AuditEntry rappresents one mutating api call recorded in the append-only audit log
+type AuditEntry struct {
+	ID             string    `json:"id"`
+	User           string    `json:"user"`
+	Method         string    `json:"method"`
+	Route          string    `json:"route"`
+	Bundle         string    `json:"bundle"`
+	Lang           string    `json:"lang"`
+	Key            string    `json:"key"`
+	Status         int       `json:"status"`
+	NumSuccessfull int64     `json:"num_successful"`
+	NumFailed      int64     `json:"num_failed"`
+	SourceIP       string    `json:"source_ip"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+//AuditQueryParams rappresents filters for audit log, from and to are RFC 3339 timestamps;
+//limit is the page size of json format, jsonl exports every entry from offset
+type AuditQueryParams struct {
+	User   string    `form:"user"`
+	Bundle string    `form:"bundle"`
+	Lang   string    `form:"lang"`
+	Key    string    `form:"key"`
+	From   time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To     time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Format string    `form:"format"`
+	Offset int       `form:"offset"`
+	Limit  int       `form:"limit"`
+}
+
+//AuditPersistencer interface for audit log persistence
+type AuditPersistencer interface {
+	PostAuditEntry(ctx context.Context, entry AuditEntry) error
+	GetAuditEntries(ctx context.Context, params AuditQueryParams) ([]AuditEntry, error)
+}
+
+type auditFilters struct {
+	Bundle string
+	Lang   string
+	Key    string
+}
+
+//setAuditFilters overrides filters taken from url when they are in the payload
+func setAuditFilters(c *gin.Context, bundle, lang, key string) {
+	c.Set(auditFiltersKey, auditFilters{bundle, lang, key})
+}
+
+//setAuditResult stores the items count affected by the call
+func setAuditResult(c *gin.Context, result MassiveResult) {
+	c.Set(auditResultKey, result)
+}
+
+//setAuditDryRun marks the call as a dry run, it writes nothing so it is not recorded
+func setAuditDryRun(c *gin.Context) {
+	c.Set(auditDryRunKey, true)
+}
+
+//audited return true when a call to route is recorded in audit log: POST, PUT, PATCH and DELETE but the POST that only read
+func audited(method, route string) bool {
+	switch method {
+	case http.MethodPost:
+		return !auditReadRoutes[route]
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+//AuditTrail is the middleware that records every POST, PUT, PATCH and DELETE call but the POST routes that only read and dry runs
+func (lph LocalePersistenceHandler) AuditTrail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if _, dryRun := c.Get(auditDryRunKey); dryRun || !audited(c.Request.Method, c.FullPath()) {
+			return
+		}
+
+		entry := AuditEntry{
+			User:     authorizating.CurrentUser(c),
+			Method:   c.Request.Method,
+			Route:    c.FullPath(),
+			Bundle:   c.Param("bundle"),
+			Lang:     c.Param("langId"),
+			Key:      c.Param("keyId"),
+			Status:   c.Writer.Status(),
+			SourceIP: c.ClientIP(),
+		}
+		if entry.Lang == "" {
+			entry.Lang = c.Query("lang")
+		}
+		if entry.Key == "" {
+			entry.Key = c.Query("key")
+		}
+		if candidate, ok := c.Get(auditFiltersKey); ok {
+			filters := candidate.(auditFilters)
+			entry.Bundle, entry.Lang, entry.Key = filters.Bundle, filters.Lang, filters.Key
+		}
+		if candidate, ok := c.Get(auditResultKey); ok {
+			result := candidate.(MassiveResult)
+			entry.NumSuccessfull, entry.NumFailed = result.NumSuccessfull, result.NumFailed
+		}
+
+		//the entry is recorded even if the client has gone away
+		ctx, cancel := lph.timeouts.context(context.Background(), opPostAudit)
+		defer cancel()
+		if err := lph.AuditDelegate.PostAuditEntry(ctx, entry); err != nil {
+			log.Printf("Error on record audit entry %v: %v\n", entry, err)
+		}
+	}
+}
+
+//GetAuditEntries return audit log filtered by user, bundle and time range, as json array or json lines;
+//json lines are read and written a page at a time, so the whole log is never in memory
+func (lph LocalePersistenceHandler) GetAuditEntries(c *gin.Context) {
+	var auditQueryParams AuditQueryParams
+	err := c.ShouldBindQuery(&auditQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if auditQueryParams.Format == "jsonl" {
+		lph.exportAuditEntries(c, auditQueryParams)
+		return
+	}
+
+	if auditQueryParams.Limit <= 0 {
+		auditQueryParams.Limit = defaultAuditLimit
+	} else if auditQueryParams.Limit > maxAuditLimit {
+		abortValidation(c, "Audit query has invalid params",
+			[]FieldError{{"limit", fieldTooLong, fmt.Sprintf("limit is %d, max is %d", auditQueryParams.Limit, maxAuditLimit)}})
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetAudit)
+	defer cancel()
+	entries, err := lph.AuditDelegate.GetAuditEntries(ctx, auditQueryParams)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+//exportAuditEntries writes audit log as json lines, by pages of maxAuditLimit entries each with its own timeout
+func (lph LocalePersistenceHandler) exportAuditEntries(c *gin.Context, params AuditQueryParams) {
+	params.Limit = maxAuditLimit
+	encoder := json.NewEncoder(c.Writer)
+	for started := false; ; params.Offset += maxAuditLimit {
+		ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetAudit)
+		entries, err := lph.AuditDelegate.GetAuditEntries(ctx, params)
+		if err != nil {
+			if started {
+				log.Printf("Error on export audit page at offset %d: %v\n", params.Offset, err)
+			} else {
+				respondError(c, ctx, err)
+			}
+			cancel()
+			return
+		}
+		cancel()
+
+		if !started {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Header("Content-Disposition", "attachment; filename=audit.jsonl")
+			c.Status(http.StatusOK)
+			started = true
+		}
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				log.Printf("Error on export audit entry %s: %v\n", entry.ID, err)
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		if len(entries) < maxAuditLimit {
+			return
+		}
+	}
+}
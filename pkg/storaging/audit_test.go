@@ -0,0 +1,85 @@
+package storaging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+//memoryAudit is an in memory audit log, pages as postgresql does
+type memoryAudit struct {
+	entries []AuditEntry
+	queries []AuditQueryParams
+}
+
+func (ma *memoryAudit) PostAuditEntry(ctx context.Context, entry AuditEntry) error {
+	ma.entries = append(ma.entries, entry)
+	return nil
+}
+
+func (ma *memoryAudit) GetAuditEntries(ctx context.Context, params AuditQueryParams) ([]AuditEntry, error) {
+	ma.queries = append(ma.queries, params)
+	if params.Offset >= len(ma.entries) {
+		return []AuditEntry{}, nil
+	}
+	end := len(ma.entries)
+	if params.Limit > 0 && params.Offset+params.Limit < end {
+		end = params.Offset + params.Limit
+	}
+	return ma.entries[params.Offset:end], nil
+}
+
+func TestAudited(t *testing.T) {
+	assert.False(t, audited(http.MethodPost, "/api/v1/locale-items/:bundle"))
+	assert.True(t, audited(http.MethodDelete, "/api/v1/locale-items/:bundle"))
+	assert.True(t, audited(http.MethodPost, "/api/v1/locale-items"))
+	assert.True(t, audited(http.MethodPut, "/api/v1/bundles/:id/keys/:key"))
+	assert.False(t, audited(http.MethodGet, "/api/v1/audit"))
+}
+
+func TestAuditTrailSkipsDryRuns(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audit := &memoryAudit{}
+	lph := LocalePersistenceHandler{AuditDelegate: audit}
+	router := gin.New()
+	router.DELETE("/api/v1/locale-items/:bundle", lph.AuditTrail(), func(c *gin.Context) {
+		setAuditDryRun(c)
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/locale-items/label?dry_run=true", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, audit.entries)
+}
+
+func TestGetAuditEntriesPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audit := &memoryAudit{}
+	for i := 0; i < maxAuditLimit+5; i++ {
+		audit.entries = append(audit.entries, AuditEntry{ID: fmt.Sprint(i)})
+	}
+	lph := LocalePersistenceHandler{AuditDelegate: audit}
+	router := gin.New()
+	router.GET("/api/v1/audit", lph.GetAuditEntries)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/audit?format=jsonl", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, maxAuditLimit+5, strings.Count(w.Body.String(), "\n"))
+	assert.Len(t, audit.queries, 2)
+
+	audit.queries = nil
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/audit", nil))
+	assert.Equal(t, defaultAuditLimit, audit.queries[0].Limit)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprint("/api/v1/audit?limit=", maxAuditLimit+1), nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
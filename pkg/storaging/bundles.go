@@ -0,0 +1,289 @@
+package storaging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/gin-gonic/gin"
+)
+
+const maxDescriptionLength = 1024
+
+//message formats of bundle content, with icu content is parsed as ICU MessageFormat on write
+const (
+	messageFormatNone = "none"
+	messageFormatICU  = "icu"
+)
+
+//placeholder checks of translations against the source lang item
+const (
+	placeholderCheckOff   = "off"
+	placeholderCheckWarn  = "warn"
+	placeholderCheckError = "error"
+)
+
+//Bundle rappresents a bundle with its settings, managed is false for bundles that exist only as items
+type Bundle struct {
+	ID               string           `json:"id"`
+	Managed          bool             `json:"managed"`
+	Description      string           `json:"description"`
+	Owner            string           `json:"owner"`
+	SourceLang       string           `json:"source_lang"`
+	TargetLangs      []string         `json:"target_langs"`
+	KeyPattern       string           `json:"key_pattern"`
+	FallbackChain    []string         `json:"fallback_chain"`
+	MessageFormat    string           `json:"message_format"`
+	PlaceholderCheck string           `json:"placeholder_check"`
+	NumItems         int64            `json:"num_items"`
+	NumItemsByLang   map[string]int64 `json:"num_items_by_lang"`
+}
+
+//BundlePatch rappresents changes to a bundle, nil fields are left as they are
+type BundlePatch struct {
+	Description      *string   `json:"description"`
+	Owner            *string   `json:"owner"`
+	SourceLang       *string   `json:"source_lang"`
+	TargetLangs      *[]string `json:"target_langs"`
+	KeyPattern       *string   `json:"key_pattern"`
+	FallbackChain    *[]string `json:"fallback_chain"`
+	MessageFormat    *string   `json:"message_format"`
+	PlaceholderCheck *string   `json:"placeholder_check"`
+}
+
+//BundlePersistencer interface for bundle settings persistence
+type BundlePersistencer interface {
+	GetBundles(ctx context.Context, ids []string, withCounts bool) ([]Bundle, error)
+	PostBundle(ctx context.Context, bundle Bundle) error
+	PatchBundle(ctx context.Context, id string, patch BundlePatch) error
+	DeleteBundle(ctx context.Context, id string) error
+}
+
+//acceptsLang is true for source lang and target langs, every lang is accepted when there are no target langs
+func (b Bundle) acceptsLang(lang string) bool {
+	if len(b.TargetLangs) == 0 || lang == b.SourceLang {
+		return true
+	}
+	for _, target := range b.TargetLangs {
+		if target == lang {
+			return true
+		}
+	}
+	return false
+}
+
+//checkBundle checks the settings of a bundle, nil ones are not checked; langs are canonicalized
+//and must be in languages registry
+func checkBundle(patch BundlePatch, languages map[string]Language) []FieldError {
+	errs := []FieldError{}
+
+	if patch.Description != nil && *patch.Description != "" {
+		errs = append(errs, checkText("description", *patch.Description, maxDescriptionLength, true)...)
+	}
+	if patch.Owner != nil && *patch.Owner != "" {
+		errs = append(errs, checkText("owner", *patch.Owner, maxBundleLength, false)...)
+	}
+	if patch.SourceLang != nil && *patch.SourceLang != "" {
+		*patch.SourceLang = localizing.CanonicalizeOrKeep(*patch.SourceLang)
+		errs = append(errs, checkRegistered("source_lang", *patch.SourceLang, languages)...)
+	}
+	if patch.TargetLangs != nil {
+		for i := range *patch.TargetLangs {
+			(*patch.TargetLangs)[i] = localizing.CanonicalizeOrKeep((*patch.TargetLangs)[i])
+			errs = append(errs, checkRegistered("target_langs", (*patch.TargetLangs)[i], languages)...)
+		}
+	}
+	if patch.FallbackChain != nil {
+		for i := range *patch.FallbackChain {
+			(*patch.FallbackChain)[i] = localizing.CanonicalizeOrKeep((*patch.FallbackChain)[i])
+			errs = append(errs, checkRegistered("fallback_chain", (*patch.FallbackChain)[i], languages)...)
+		}
+	}
+	if patch.KeyPattern != nil && *patch.KeyPattern != "" {
+		if _, err := regexp.Compile(*patch.KeyPattern); err != nil {
+			errs = append(errs, FieldError{"key_pattern", fieldInvalidName, fmt.Sprintf("key_pattern is not a valid regular expression: %v", err)})
+		}
+	}
+	if patch.MessageFormat != nil && *patch.MessageFormat != messageFormatNone && *patch.MessageFormat != messageFormatICU {
+		errs = append(errs, FieldError{"message_format", fieldInvalidName, "message_format must be none or icu"})
+	}
+	if patch.PlaceholderCheck != nil && *patch.PlaceholderCheck != placeholderCheckOff &&
+		*patch.PlaceholderCheck != placeholderCheckWarn && *patch.PlaceholderCheck != placeholderCheckError {
+		errs = append(errs, FieldError{"placeholder_check", fieldInvalidName, "placeholder_check must be off, warn or error"})
+	}
+
+	return errs
+}
+
+func checkRegistered(field, lang string, languages map[string]Language) []FieldError {
+	if _, ok := languages[lang]; !ok {
+		return []FieldError{{field, fieldNotEnabled, fmt.Sprintf("lang %s is not enabled in languages registry", lang)}}
+	}
+	return nil
+}
+
+//bundlePatch return the patch that sets every setting of bundle
+func bundlePatch(bundle *Bundle) BundlePatch {
+	if bundle.TargetLangs == nil {
+		bundle.TargetLangs = []string{}
+	}
+	if bundle.FallbackChain == nil {
+		bundle.FallbackChain = []string{}
+	}
+	if bundle.MessageFormat == "" {
+		bundle.MessageFormat = messageFormatNone
+	}
+	if bundle.PlaceholderCheck == "" {
+		bundle.PlaceholderCheck = placeholderCheckWarn
+	}
+	return BundlePatch{&bundle.Description, &bundle.Owner, &bundle.SourceLang, &bundle.TargetLangs, &bundle.KeyPattern, &bundle.FallbackChain,
+		&bundle.MessageFormat, &bundle.PlaceholderCheck}
+}
+
+//getBundle return the bundle with id with its item counts
+func (lph LocalePersistenceHandler) getBundle(ctx context.Context, id string) (*Bundle, error) {
+	bundles, err := lph.BundleDelegate.GetBundles(ctx, []string{id}, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(bundles) == 0 {
+		return nil, newError(ErrNotFound, "No bundle found for id "+id, nil)
+	}
+	return &bundles[0], nil
+}
+
+//GetAllBundles return all bundles, managed or implicit, with settings and item counts
+func (lph LocalePersistenceHandler) GetAllBundles(c *gin.Context) {
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetBundles)
+	defer cancel()
+	result, err := lph.BundleDelegate.GetBundles(ctx, nil, true)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+//GetBundle return one bundle with settings and item counts
+func (lph LocalePersistenceHandler) GetBundle(c *gin.Context) {
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetBundles)
+	defer cancel()
+	bundle, err := lph.getBundle(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, bundle)
+}
+
+//PostBundle adds settings for a new bundle or for one that exists only as items
+func (lph LocalePersistenceHandler) PostBundle(c *gin.Context) {
+	var bundle Bundle
+	err := c.ShouldBindJSON(&bundle)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	bundle.ID = c.Param("id")
+
+	setAuditFilters(c, bundle.ID, "", "")
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPostBundle)
+	defer cancel()
+	languages, err := lph.enabledLanguages(ctx)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	fieldErrs := checkText("id", bundle.ID, maxBundleLength, false)
+	if bundle.ID != "" && !bundleNamePattern.MatchString(bundle.ID) {
+		fieldErrs = append(fieldErrs, FieldError{"id", fieldInvalidName, "id must start with a letter or digit and contain only letters, digits, '_', '.' and '-'"})
+	}
+	fieldErrs = append(fieldErrs, checkBundle(bundlePatch(&bundle), languages)...)
+	if len(fieldErrs) > 0 {
+		abortValidation(c, "Bundle has invalid fields", fieldErrs)
+		return
+	}
+
+	err = lph.BundleDelegate.PostBundle(ctx, bundle)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	created, err := lph.getBundle(ctx, bundle.ID)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.JSON(http.StatusCreated, created)
+}
+
+//PatchBundle changes settings of a bundle
+func (lph LocalePersistenceHandler) PatchBundle(c *gin.Context) {
+	id := c.Param("id")
+	setAuditFilters(c, id, "", "")
+
+	var patch BundlePatch
+	err := c.ShouldBindJSON(&patch)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPatchBundle)
+	defer cancel()
+	languages, err := lph.enabledLanguages(ctx)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	if fieldErrs := checkBundle(patch, languages); len(fieldErrs) > 0 {
+		abortValidation(c, "Bundle has invalid fields", fieldErrs)
+		return
+	}
+
+	err = lph.BundleDelegate.PatchBundle(ctx, id, patch)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	bundle, err := lph.getBundle(ctx, id)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.JSON(http.StatusOK, bundle)
+}
+
+//DeleteBundle removes settings of a bundle without items, trashed ones included
+func (lph LocalePersistenceHandler) DeleteBundle(c *gin.Context) {
+	id := c.Param("id")
+	setAuditFilters(c, id, "", "")
+
+	if !requireAdmin(c, "Delete of bundle settings") {
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opDeleteBundle)
+	defer cancel()
+	err := lph.BundleDelegate.DeleteBundle(ctx, id)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,323 @@
+package storaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/authorizating"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/gin-gonic/gin"
+)
+
+//maxCommentLength is the max length, in characters, of a comment as defined on comments table
+const maxCommentLength = 4096
+
+//types of the events of the history of a locale item
+const (
+	historyWrite    = "write"
+	historyComment  = "comment"
+	historyResolved = "resolved"
+)
+
+//mentionPattern matches @user, not in an email address; a user is letters, digits and '_', '.', '-'
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\pL\pN_][\pL\pN_.\-]*)`)
+
+//Comment rappresents a comment on a locale item, the first comment of a thread has the replies and the resolved state
+type Comment struct {
+	ID           string     `json:"id"`
+	LocaleItemID string     `json:"locale_item_id"`
+	ParentID     string     `json:"parent_id,omitempty"`
+	Author       string     `json:"author"`
+	Body         string     `json:"body"`
+	Mentions     []string   `json:"mentions"`
+	Resolved     bool       `json:"resolved"`
+	ResolvedBy   string     `json:"resolved_by,omitempty"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Replies      []Comment  `json:"replies,omitempty"`
+}
+
+//CommentPost rappresents a new comment, a reply when ParentID is set
+type CommentPost struct {
+	Body     string `json:"body"`
+	ParentID string `json:"parent_id"`
+}
+
+//CommentPatch rappresents the change of the resolved state of a thread
+type CommentPatch struct {
+	Resolved *bool `json:"resolved"`
+}
+
+//CommentQueryParams rappresents filters of the threads of a locale item, nil Resolved for every thread
+type CommentQueryParams struct {
+	Resolved *bool `form:"resolved"`
+}
+
+//Question rappresents an unresolved thread with the key and lang of its locale item
+type Question struct {
+	Comment
+	Bundle     string `json:"bundle"`
+	Key        string `json:"key"`
+	Lang       string `json:"lang"`
+	NumReplies int    `json:"num_replies"`
+}
+
+//QuestionQueryParams rappresents filters of the open questions of a bundle,
+//with Mention only threads where the user is mentioned
+type QuestionQueryParams struct {
+	Bundle  string `form:"-"`
+	Lang    string `form:"lang"`
+	Mention string `form:"mention"`
+	Offset  int    `form:"offset"`
+	Limit   int    `form:"limit"`
+}
+
+//HistoryEvent rappresents a change of a locale item: a write recorded in audit log, a comment or a resolved thread
+type HistoryEvent struct {
+	Type    string      `json:"type"`
+	User    string      `json:"user"`
+	At      time.Time   `json:"at"`
+	Audit   *AuditEntry `json:"audit,omitempty"`
+	Comment *Comment    `json:"comment,omitempty"`
+}
+
+//CommentPersistencer interface for comments persistence
+type CommentPersistencer interface {
+	GetComments(ctx context.Context, localeItemID string) ([]Comment, error)
+	GetComment(ctx context.Context, id string) (*Comment, error)
+	PostComment(ctx context.Context, comment Comment) (*Comment, error)
+	ResolveComment(ctx context.Context, localeItemID, id string, resolved bool, user string) (*Comment, error)
+	GetOpenQuestions(ctx context.Context, params QuestionQueryParams) ([]Question, error)
+}
+
+//mentionsOf return the users mentioned in body, in order and without duplicates
+func mentionsOf(body string) []string {
+	mentions := []string{}
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		user := strings.TrimRight(match[1], ".-")
+		if user == "" || seen[user] {
+			continue
+		}
+		seen[user] = true
+		mentions = append(mentions, user)
+	}
+	return mentions
+}
+
+//checkCommentBody return invalid fields of the body of a comment
+func checkCommentBody(body string) []FieldError {
+	if strings.TrimSpace(body) == "" {
+		return []FieldError{{"body", fieldRequired, "body is required"}}
+	}
+	return checkText("body", body, maxCommentLength, true)
+}
+
+//threadsOf return the first comments of threads with their replies, oldest first;
+//with resolved only the threads in that state
+func threadsOf(comments []Comment, resolved *bool) []Comment {
+	replies := map[string][]Comment{}
+	for _, comment := range comments {
+		if comment.ParentID != "" {
+			replies[comment.ParentID] = append(replies[comment.ParentID], comment)
+		}
+	}
+
+	threads := []Comment{}
+	for _, comment := range comments {
+		if comment.ParentID != "" || (resolved != nil && comment.Resolved != *resolved) {
+			continue
+		}
+		comment.Replies = replies[comment.ID]
+		threads = append(threads, comment)
+	}
+	return threads
+}
+
+//historyOf return the events of item by time: its writes in audit log, comments and resolved threads.
+//Writes are the calls recorded with the key of item and its lang, or no lang for the ones on the key in every lang;
+//calls on many keys, as bulk writes, machine translation and deletes of a whole bundle or lang, are not recorded by key
+//so they are not in history, nor are writes recorded before a rename of the key. Audit entries of comments are skipped
+//since comments are events themselves, comments stay with item through renames
+func historyOf(item LocaleItem, entries []AuditEntry, comments []Comment) []HistoryEvent {
+	events := []HistoryEvent{}
+	for i := range entries {
+		if entries[i].Bundle != item.Bundle || entries[i].Key != item.Key || (entries[i].Lang != item.Lang && entries[i].Lang != "") {
+			continue
+		}
+		if strings.Contains(entries[i].Route, "/comments") || entries[i].Status >= http.StatusBadRequest {
+			continue
+		}
+		events = append(events, HistoryEvent{Type: historyWrite, User: entries[i].User, At: entries[i].CreatedAt, Audit: &entries[i]})
+	}
+	for i := range comments {
+		events = append(events, HistoryEvent{Type: historyComment, User: comments[i].Author, At: comments[i].CreatedAt, Comment: &comments[i]})
+		if comments[i].Resolved && comments[i].ResolvedAt != nil {
+			events = append(events, HistoryEvent{Type: historyResolved, User: comments[i].ResolvedBy, At: *comments[i].ResolvedAt, Comment: &comments[i]})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	return events
+}
+
+//GetComments return the comment threads of a locale item
+func (lph LocalePersistenceHandler) GetComments(c *gin.Context) {
+	var commentQueryParams CommentQueryParams
+	err := c.ShouldBindQuery(&commentQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetComments)
+	defer cancel()
+	item, err := lph.PersistenceDelegate.GetLocaleItem(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	comments, err := lph.CommentDelegate.GetComments(ctx, item.ID)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, threadsOf(comments, commentQueryParams.Resolved))
+}
+
+//PostComment adds a comment to a locale item, as author the logged user; a reply to a reply goes in the same thread
+func (lph LocalePersistenceHandler) PostComment(c *gin.Context) {
+	var commentPost CommentPost
+	err := c.ShouldBindJSON(&commentPost)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if fieldErrs := checkCommentBody(commentPost.Body); len(fieldErrs) > 0 {
+		abortValidation(c, "Comment has invalid fields", fieldErrs)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPostComment)
+	defer cancel()
+	item, err := lph.PersistenceDelegate.GetLocaleItem(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	setAuditFilters(c, item.Bundle, item.Lang, item.Key)
+
+	comment := Comment{LocaleItemID: item.ID, Author: authorizating.CurrentUser(c), Body: commentPost.Body, Mentions: mentionsOf(commentPost.Body)}
+	if commentPost.ParentID != "" {
+		parent, err := lph.CommentDelegate.GetComment(ctx, commentPost.ParentID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			respondError(c, ctx, err)
+			return
+		}
+		if parent == nil || parent.LocaleItemID != item.ID {
+			abortValidation(c, "Comment has invalid fields", []FieldError{{"parent_id", fieldInvalidName,
+				fmt.Sprintf("parent_id %s is not a comment of locale item %s", commentPost.ParentID, item.ID)}})
+			return
+		}
+		comment.ParentID = parent.ID
+		if parent.ParentID != "" {
+			comment.ParentID = parent.ParentID
+		}
+	}
+
+	created, err := lph.CommentDelegate.PostComment(ctx, comment)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.JSON(http.StatusCreated, created)
+}
+
+//PatchComment resolves or reopens a thread, by its first comment
+func (lph LocalePersistenceHandler) PatchComment(c *gin.Context) {
+	var commentPatch CommentPatch
+	err := c.ShouldBindJSON(&commentPatch)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if commentPatch.Resolved == nil {
+		abortValidation(c, "Comment has invalid fields", []FieldError{{"resolved", fieldRequired, "resolved is required"}})
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPatchComment)
+	defer cancel()
+	item, err := lph.PersistenceDelegate.GetLocaleItem(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	setAuditFilters(c, item.Bundle, item.Lang, item.Key)
+
+	comment, err := lph.CommentDelegate.ResolveComment(ctx, item.ID, c.Param("commentId"), *commentPatch.Resolved, authorizating.CurrentUser(c))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.JSON(http.StatusOK, comment)
+}
+
+//GetOpenQuestions return the unresolved threads on the items of a bundle, oldest first
+func (lph LocalePersistenceHandler) GetOpenQuestions(c *gin.Context) {
+	var questionQueryParams QuestionQueryParams
+	err := c.ShouldBindQuery(&questionQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	questionQueryParams.Bundle = c.Param("id")
+	questionQueryParams.Lang = localizing.CanonicalizeOrKeep(questionQueryParams.Lang)
+	questionQueryParams.Mention = strings.TrimPrefix(questionQueryParams.Mention, "@")
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetQuestions)
+	defer cancel()
+	questions, err := lph.CommentDelegate.GetOpenQuestions(ctx, questionQueryParams)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, questions)
+}
+
+//GetLocaleItemHistory return the events of a locale item: its writes recorded in audit log, comments and resolved threads;
+//writes on many keys at once are not in history, see historyOf
+func (lph LocalePersistenceHandler) GetLocaleItemHistory(c *gin.Context) {
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetHistory)
+	defer cancel()
+	item, err := lph.PersistenceDelegate.GetLocaleItem(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	entries, err := lph.AuditDelegate.GetAuditEntries(ctx, AuditQueryParams{Bundle: item.Bundle, Key: item.Key})
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	comments, err := lph.CommentDelegate.GetComments(ctx, item.ID)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, historyOf(*item, entries, comments))
+}
@@ -0,0 +1,77 @@
+package storaging
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMentionsOf(t *testing.T) {
+	assert.Equal(t, []string{"anna", "marco.rossi", "zoë"},
+		mentionsOf("@anna is it the hotel check-in? cc @marco.rossi, @anna and @zoë. Not mail@example.com nor @@x"))
+	assert.Equal(t, []string{}, mentionsOf("no mentions here"))
+}
+
+func TestCheckCommentBody(t *testing.T) {
+	assert.Empty(t, checkCommentBody("Which tense?\nPast or present"))
+	assert.Equal(t, []FieldError{{"body", fieldRequired, "body is required"}}, checkCommentBody(" \n"))
+	assert.Len(t, checkCommentBody(strings.Repeat("a", maxCommentLength+1)), 1)
+}
+
+func TestThreadsOf(t *testing.T) {
+	resolved, open := true, false
+	comments := []Comment{
+		{ID: "1", Body: "question"},
+		{ID: "2", Body: "other question", Resolved: true},
+		{ID: "3", ParentID: "1", Body: "answer"},
+		{ID: "4", ParentID: "1", Body: "thanks"},
+	}
+
+	threads := threadsOf(comments, nil)
+	assert.Len(t, threads, 2)
+	assert.Equal(t, []Comment{comments[2], comments[3]}, threads[0].Replies)
+	assert.Empty(t, threads[1].Replies)
+
+	assert.Equal(t, "2", threadsOf(comments, &resolved)[0].ID)
+	assert.Equal(t, "1", threadsOf(comments, &open)[0].ID)
+	assert.Len(t, threadsOf(comments, &open), 1)
+}
+
+func TestHistoryOf(t *testing.T) {
+	at := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	resolvedAt := at.Add(3 * time.Hour)
+	item := LocaleItem{ID: "7", Key: "SAVE", Bundle: "label", Lang: "it-IT"}
+	entries := []AuditEntry{
+		{User: "paolo", Route: "/api/v1/locale-item", Bundle: "label", Lang: "it-IT", Key: "SAVE", Status: 200, CreatedAt: at},
+		{User: "anna", Route: "/api/v1/locale-item/:id/comments", Bundle: "label", Lang: "it-IT", Key: "SAVE", Status: 201, CreatedAt: at.Add(time.Hour)},
+		{User: "anna", Route: "/api/v1/locale-item", Bundle: "label", Lang: "it-IT", Key: "SAVE", Status: 400, CreatedAt: at.Add(time.Hour)},
+		{User: "anna", Route: "/api/v1/bundles/:id/keys/:key", Bundle: "label", Key: "SAVE", Status: 200, CreatedAt: at.Add(4 * time.Hour)},
+		{User: "anna", Route: "/api/v1/locale-item", Bundle: "label", Lang: "de", Key: "SAVE", Status: 200, CreatedAt: at.Add(5 * time.Hour)},
+	}
+	comments := []Comment{
+		{ID: "1", Author: "anna", CreatedAt: at.Add(time.Hour), Resolved: true, ResolvedBy: "paolo", ResolvedAt: &resolvedAt},
+		{ID: "2", ParentID: "1", Author: "paolo", CreatedAt: at.Add(2 * time.Hour)},
+	}
+
+	events := historyOf(item, entries, comments)
+	var types, users []string
+	for _, event := range events {
+		types, users = append(types, event.Type), append(users, event.User)
+	}
+	assert.Equal(t, []string{historyWrite, historyComment, historyComment, historyResolved, historyWrite}, types)
+	assert.Equal(t, []string{"paolo", "anna", "paolo", "paolo", "anna"}, users)
+}
+
+func TestHistoryOfSkipsWritesOnManyKeys(t *testing.T) {
+	item := LocaleItem{ID: "7", Key: "SAVE", Bundle: "label", Lang: "it-IT"}
+	entries := []AuditEntry{
+		{Route: "/api/v1/locale-items", Status: 201},
+		{Route: "/api/v1/bundles/:id/translate", Bundle: "label", Status: 200},
+		{Route: "/api/v1/locale-items/:bundle/lang/:langId", Bundle: "label", Lang: "it-IT", Status: 200},
+		{Route: "/api/v1/trash/restore", Bundle: "label", Lang: "it-IT", Status: 200},
+	}
+
+	assert.Empty(t, historyOf(item, entries, nil))
+}
@@ -0,0 +1,83 @@
+package storaging
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//defaultConfirmationTTL is used when DELETE_TOKEN_TTL is not set
+	defaultConfirmationTTL = 5 * time.Minute
+	//deleteSampleSize is the max number of items returned by a dry-run delete
+	deleteSampleSize = 10
+)
+
+//DeletePreview rappresents the result of a dry-run delete
+type DeletePreview struct {
+	Count             int64        `json:"count"`
+	Sample            []LocaleItem `json:"sample"`
+	ConfirmationToken string       `json:"confirmation_token"`
+	ExpiresAt         time.Time    `json:"expires_at"`
+}
+
+//loadConfirmationTTL reads DELETE_TOKEN_TTL, how long a confirmation token of a dry-run delete is valid
+func loadConfirmationTTL() (time.Duration, error) {
+	ttl, err := durationFromEnv("DELETE_TOKEN_TTL", defaultConfirmationTTL)
+	if err != nil {
+		return 0, err
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("invalid DELETE_TOKEN_TTL: %s, it must be positive", ttl)
+	}
+
+	return ttl, nil
+}
+
+//newConfirmationToken return a signed token valid only for the same user and filters for ttl, defaultConfirmationTTL when not set
+func newConfirmationToken(ttl time.Duration, user, key, bundle, lang string) (string, time.Time) {
+	if ttl <= 0 {
+		ttl = defaultConfirmationTTL
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	signature := signConfirmation(expiry, user, key, bundle, lang)
+
+	return expiry + "." + signature, expiresAt
+}
+
+//checkConfirmationToken verifies signature and expiry of a token made by newConfirmationToken
+func checkConfirmationToken(token, user, key, bundle, lang string) error {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return errors.New("malformed confirmation token")
+	}
+
+	expectedSignature := signConfirmation(parts[0], user, key, bundle, lang)
+	if !hmac.Equal([]byte(parts[1]), []byte(expectedSignature)) {
+		return errors.New("confirmation token does not match the delete request")
+	}
+
+	expiry, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return errors.New("malformed confirmation token")
+	}
+	if time.Now().After(time.Unix(expiry, 0)) {
+		return errors.New("confirmation token expired")
+	}
+
+	return nil
+}
+
+func signConfirmation(expiry, user, key, bundle, lang string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("KEY_FOR_SESSION_STORE")))
+	mac.Write([]byte(strings.Join([]string{expiry, user, key, bundle, lang}, "\x00")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
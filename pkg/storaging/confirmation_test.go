@@ -0,0 +1,36 @@
+package storaging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfirmationTTL(t *testing.T) {
+	t.Setenv("DELETE_TOKEN_TTL", "")
+	ttl, err := loadConfirmationTTL()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultConfirmationTTL, ttl)
+
+	t.Setenv("DELETE_TOKEN_TTL", "90s")
+	ttl, err = loadConfirmationTTL()
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, ttl)
+
+	for _, invalid := range []string{"0s", "-1m", "soon"} {
+		t.Setenv("DELETE_TOKEN_TTL", invalid)
+		_, err = loadConfirmationTTL()
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestConfirmationToken(t *testing.T) {
+	token, expiresAt := newConfirmationToken(time.Minute, "anna", "SAVE", "label", "it-IT")
+	assert.WithinDuration(t, time.Now().Add(time.Minute), expiresAt, 2*time.Second)
+	assert.NoError(t, checkConfirmationToken(token, "anna", "SAVE", "label", "it-IT"))
+	assert.EqualError(t, checkConfirmationToken(token, "paolo", "SAVE", "label", "it-IT"), "confirmation token does not match the delete request")
+
+	_, expiresAt = newConfirmationToken(0, "anna", "SAVE", "label", "it-IT")
+	assert.WithinDuration(t, time.Now().Add(defaultConfirmationTTL), expiresAt, 2*time.Second)
+}
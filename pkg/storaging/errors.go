@@ -0,0 +1,129 @@
+package storaging
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+//statusClientClosedRequest is the non standard status for a request cancelled by the client
+const statusClientClosedRequest = 499
+
+//kinds of storage errors, handlers map them to http status without looking at db details
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrValidation  = errors.New("validation failed")
+	ErrUnavailable = errors.New("storage unavailable")
+)
+
+//Error is a storage error of a Kind, Detail is safe to show to clients while Err is only logged
+type Error struct {
+	Kind   error
+	Detail string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error() + ": " + e.Detail
+	}
+	return e.Kind.Error() + ": " + e.Detail + ": " + e.Err.Error()
+}
+
+//Is makes errors.Is(err, ErrNotFound) and similar work
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func newError(kind error, detail string, err error) *Error {
+	return &Error{kind, detail, err}
+}
+
+//translateError turns driver errors into storage errors of the right kind, other errors are returned untouched
+func translateError(err error) error {
+	var storageErr *Error
+	if err == nil || errors.As(err, &storageErr) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return newError(ErrNotFound, "No item found", err)
+	}
+
+	if errors.Is(err, driver.ErrBadConn) {
+		return newError(ErrUnavailable, "Database connection lost", err)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return newError(ErrUnavailable, "Database not reachable", err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", "53":
+			return newError(ErrUnavailable, "Database not available", err)
+		case "57":
+			if pqErr.Code != "57014" {
+				return newError(ErrUnavailable, "Database not available", err)
+			}
+		case "22":
+			return newError(ErrValidation, "Value not accepted by storage", err)
+		case "23":
+			if pqErr.Code.Name() == "unique_violation" {
+				return newError(ErrConflict, "Item already exists", err)
+			}
+			return newError(ErrValidation, "Value violates a storage constraint", err)
+		}
+	}
+
+	return err
+}
+
+//respondError maps err to a problem response, ctx is the one used for the failed call
+func respondError(c *gin.Context, ctx context.Context, err error) {
+	detail := ""
+	var storageErr *Error
+	if errors.As(err, &storageErr) {
+		detail = storageErr.Detail
+		if storageErr.Err != nil {
+			log.Printf("Storage error on %s %s: %v\n", c.Request.Method, c.Request.URL.Path, storageErr)
+		}
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || (ctx != nil && errors.Is(ctx.Err(), context.DeadlineExceeded)):
+		problem.Abort(c, http.StatusGatewayTimeout, problem.CodeTimeout, "The query did not complete in time")
+	case errors.Is(err, context.Canceled) || (ctx != nil && errors.Is(ctx.Err(), context.Canceled)):
+		problem.Abort(c, statusClientClosedRequest, problem.CodeClientClosedRequest, "The request was cancelled by the client")
+	case errors.Is(err, ErrNotFound):
+		problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, detail)
+	case errors.Is(err, ErrConflict):
+		problem.Abort(c, http.StatusConflict, problem.CodeConflict, detail)
+	case errors.Is(err, ErrValidation):
+		problem.Abort(c, http.StatusBadRequest, problem.CodeValidationFailed, detail)
+	case errors.Is(err, ErrUnavailable):
+		problem.Abort(c, http.StatusServiceUnavailable, problem.CodeUnavailable, detail)
+	default:
+		problem.AbortInternal(c, err)
+	}
+}
+
+//respondBindError answers 400 for payload or query params that can not be parsed
+func respondBindError(c *gin.Context, err error) {
+	problem.Abort(c, http.StatusBadRequest, problem.CodeBadRequest, "Error on parsing request: "+err.Error())
+}
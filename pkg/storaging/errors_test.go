@@ -0,0 +1,59 @@
+package storaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondError(t *testing.T) {
+	expiredCtx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	cancelledCtx, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", context.Background(), newError(ErrNotFound, "No item found for id 1", nil), http.StatusNotFound, problem.CodeNotFound},
+		{"unique violation", context.Background(), translateError(&pq.Error{Code: "23505"}), http.StatusConflict, problem.CodeConflict},
+		{"value too long", context.Background(), translateError(&pq.Error{Code: "22001"}), http.StatusBadRequest, problem.CodeValidationFailed},
+		{"connection failure", context.Background(), translateError(&pq.Error{Code: "08006"}), http.StatusServiceUnavailable, problem.CodeUnavailable},
+		{"timeout", expiredCtx, errors.New("pq: canceling statement due to user request"), http.StatusGatewayTimeout, problem.CodeTimeout},
+		{"client gone", cancelledCtx, errors.New("pq: canceling statement due to user request"), statusClientClosedRequest, problem.CodeClientClosedRequest},
+		{"unknown", context.Background(), errors.New("pq: relation \"localeitems\" does not exist"), http.StatusInternalServerError, problem.CodeInternal},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/locale-item/1", nil)
+
+			respondError(c, tt.ctx, tt.err)
+
+			var p problem.Problem
+			err := json.Unmarshal(w.Body.Bytes(), &p)
+			if err != nil {
+				t.Fatalf("error on parse problem: %v\n", err)
+			}
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.code, p.Code)
+			assert.NotContains(t, p.Detail, "pq:")
+		})
+	}
+}
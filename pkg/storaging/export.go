@@ -0,0 +1,157 @@
+package storaging
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/exchanging"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+//formats of a bundle export
+const (
+	exportFormatXLIFF = "xliff"
+	exportFormatPO    = "po"
+)
+
+//ExportParams rappresents the target lang and format of a bundle export, with Tag only keys with that tag
+type ExportParams struct {
+	Format string `form:"format"`
+	Lang   string `form:"lang"`
+	Tag    string `form:"tag"`
+}
+
+//catalogOf return the catalog of the source items of a bundle with their translations and the context of their keys, by key
+func catalogOf(bundle Bundle, lang string, categories []string, sources, targets []LocaleItem, keys []KeyMetadata) exchanging.Catalog {
+	catalog := exchanging.Catalog{Name: bundle.ID, SourceLang: bundle.SourceLang, TargetLang: lang, TargetCategories: categories,
+		Messages: make([]exchanging.Message, 0, len(sources))}
+
+	targetByKey := make(map[string]LocaleItem, len(targets))
+	for _, target := range targets {
+		targetByKey[target.Key] = target
+	}
+	metadataByKey := make(map[string]KeyMetadata, len(keys))
+	for _, km := range keys {
+		metadataByKey[km.Key] = km
+	}
+
+	for _, source := range sources {
+		message := exchanging.Message{ID: source.Key, Source: source.Content, SourcePlurals: source.Plurals}
+		if target, ok := targetByKey[source.Key]; ok {
+			message.Target, message.TargetPlurals, message.NeedsReview = target.Content, target.Plurals, target.NeedsReview
+		}
+		if km, ok := metadataByKey[source.Key]; ok {
+			message.Description, message.Note, message.Tags, message.MaxLength = km.Description, km.Note, km.Tags, km.MaxLength
+			for _, screenshot := range km.Screenshots {
+				message.Screenshots = append(message.Screenshots, screenshot.URL)
+			}
+		}
+		catalog.Messages = append(catalog.Messages, message)
+	}
+
+	sort.Slice(catalog.Messages, func(i, j int) bool { return catalog.Messages[i].ID < catalog.Messages[j].ID })
+	return catalog
+}
+
+//ExportBundle return the source items of a bundle with their translations in lang as XLIFF 1.2 or gettext PO,
+//with description, note, tags and screenshots of their keys as notes and comments; translations in a pseudo-localization
+//lang are the source items pseudo-localized
+func (lph LocalePersistenceHandler) ExportBundle(c *gin.Context) {
+	var exportParams ExportParams
+	err := c.ShouldBindQuery(&exportParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	if exportParams.Format == "" {
+		exportParams.Format = exportFormatXLIFF
+	}
+	lang := localizing.CanonicalizeOrKeep(exportParams.Lang)
+
+	fieldErrs := checkText("lang", lang, maxLangLength, false)
+	if exportParams.Format != exportFormatXLIFF && exportParams.Format != exportFormatPO {
+		fieldErrs = append(fieldErrs, FieldError{"format", fieldInvalidName, "format must be xliff or po"})
+	}
+	if len(fieldErrs) > 0 {
+		abortValidation(c, "Export has invalid params", fieldErrs)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opExportBundle)
+	defer cancel()
+	bundle, err := lph.getBundle(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	if bundle.SourceLang == "" {
+		problem.Abort(c, http.StatusConflict, problem.CodeConflict, fmt.Sprintf("Bundle %s has no source_lang to export from", bundle.ID))
+		return
+	}
+	_, isPseudo := localizing.Pseudo(lang)
+	if lang == bundle.SourceLang || (!isPseudo && !bundle.acceptsLang(lang)) {
+		abortValidation(c, "Export has invalid params", []FieldError{{"lang", fieldNotEnabled, fmt.Sprintf("lang %s is not a target language of bundle %s", lang, bundle.ID)}})
+		return
+	}
+
+	languages, err := lph.enabledLanguages(ctx)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	//pseudo-localized items have the plural variants of source lang
+	categoriesLang := lang
+	if isPseudo {
+		categoriesLang = bundle.SourceLang
+	}
+	categories := languages[categoriesLang].PluralCategories
+	if len(categories) == 0 {
+		categories = localizing.PluralCategories(language.Make(categoriesLang))
+	}
+
+	sources, err := lph.PersistenceDelegate.GetLocaleItems(ctx, "", bundle.ID, bundle.SourceLang, "", exportParams.Tag, 0, 0)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	targets, err := lph.readItems(ctx, "", bundle.SourceLang, lang, func(lang string) ([]LocaleItem, error) {
+		if lang == bundle.SourceLang {
+			return sources, nil
+		}
+		return lph.PersistenceDelegate.GetLocaleItems(ctx, "", bundle.ID, lang, "", exportParams.Tag, 0, 0)
+	})
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	keys, err := lph.KeyDelegate.GetKeys(ctx, bundle.ID, nil)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	if err = lph.withScreenshots(ctx, bundle.ID, keys); err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	catalog := catalogOf(*bundle, lang, categories, sources, targets, keys)
+	if exportParams.Format == exportFormatPO {
+		c.Header("Content-Type", "text/x-gettext-translation; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s.po"`, bundle.ID, lang))
+		c.Status(http.StatusOK)
+		err = exchanging.WritePO(c.Writer, catalog)
+	} else {
+		c.Header("Content-Type", "application/x-xliff+xml; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s.xlf"`, bundle.ID, lang))
+		c.Status(http.StatusOK)
+		err = exchanging.WriteXLIFF(c.Writer, catalog)
+	}
+	if err != nil {
+		log.Printf("Error on writing %s export of bundle %s: %v\n", exportParams.Format, bundle.ID, err)
+	}
+}
@@ -0,0 +1,44 @@
+package storaging
+
+import (
+	"testing"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/exchanging"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogOf(t *testing.T) {
+	bundle := Bundle{ID: "checkout", SourceLang: "en"}
+	sources := []LocaleItem{
+		{Key: "PAY", Bundle: "checkout", Lang: "en", Content: "Pay"},
+		{Key: "ITEMS", Bundle: "checkout", Lang: "en", Content: "{n} items", Plurals: PluralForms{"one": "{n} item", "other": "{n} items"}},
+	}
+	targets := []LocaleItem{
+		{Key: "PAY", Bundle: "checkout", Lang: "it-IT", Content: "Paga", NeedsReview: true},
+		{Key: "ORPHAN", Bundle: "checkout", Lang: "it-IT", Content: "Senza sorgente"},
+	}
+	keys := []KeyMetadata{{Bundle: "checkout", Key: "PAY", MaxLength: 10, Description: "Pay button", Tags: []string{"button"},
+		Screenshots: []Screenshot{{ID: "3", URL: "/api/v1/bundles/checkout/keys/PAY/screenshots/3"}}}}
+
+	catalog := catalogOf(bundle, "it-IT", []string{"one", "many", "other"}, sources, targets, keys)
+	assert.Equal(t, exchanging.Catalog{
+		Name:             "checkout",
+		SourceLang:       "en",
+		TargetLang:       "it-IT",
+		TargetCategories: []string{"one", "many", "other"},
+		Messages: []exchanging.Message{
+			{ID: "ITEMS", Source: "{n} items", SourcePlurals: map[string]string{"one": "{n} item", "other": "{n} items"}},
+			{ID: "PAY", Source: "Pay", Target: "Paga", NeedsReview: true, Description: "Pay button", Tags: []string{"button"}, MaxLength: 10,
+				Screenshots: []string{"/api/v1/bundles/checkout/keys/PAY/screenshots/3"}},
+		},
+	}, catalog)
+}
+
+func TestCatalogOfPseudo(t *testing.T) {
+	bundle := Bundle{ID: "checkout", SourceLang: "en"}
+	sources := []LocaleItem{{Key: "PAY", Bundle: "checkout", Lang: "en", Content: "Pay"}}
+
+	catalog := catalogOf(bundle, localizing.PseudoAccented, []string{"one", "other"}, sources, pseudoItems(localizing.PseudoAccented, sources, 0), nil)
+	assert.Equal(t, []exchanging.Message{{ID: "PAY", Source: "Pay", Target: "[Þáý]"}}, catalog.Messages)
+}
@@ -0,0 +1,403 @@
+package storaging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/exchanging"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/formatting"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	//max length, in characters, of glossary fields as defined on glossary table
+	maxTermLength = 256
+	maxNoteLength = 1024
+	//glossaryAnyLang as target lang makes a do not translate term apply to every target lang
+	glossaryAnyLang = "*"
+	//fieldGlossaryTerm is the code of translations without the approved term of a glossary entry in their source
+	fieldGlossaryTerm = "glossary_term_missing"
+)
+
+//GlossaryEntry rappresents the approved translation of a term from source lang to target lang,
+//a do not translate term is translated as itself
+type GlossaryEntry struct {
+	ID             string `json:"id"`
+	SourceLang     string `json:"source_lang"`
+	SourceTerm     string `json:"source_term"`
+	TargetLang     string `json:"target_lang"`
+	TargetTerm     string `json:"target_term"`
+	DoNotTranslate bool   `json:"do_not_translate"`
+	Note           string `json:"note"`
+}
+
+//GlossaryEntryPatch rappresents changes to a glossary entry, nil fields are left as they are
+type GlossaryEntryPatch struct {
+	TargetTerm     *string `json:"target_term"`
+	DoNotTranslate *bool   `json:"do_not_translate"`
+	Note           *string `json:"note"`
+}
+
+//GlossaryQueryParams rappresents filters of the glossary, entries of TargetLang include the ones for every lang;
+//Q matches part of source or target term
+type GlossaryQueryParams struct {
+	SourceLang string `form:"source_lang"`
+	TargetLang string `form:"target_lang"`
+	Q          string `form:"q"`
+	Offset     int    `form:"offset"`
+	Limit      int    `form:"limit"`
+}
+
+//GlossaryImportParams rappresents the lang of source terms of an imported glossary
+type GlossaryImportParams struct {
+	SourceLang string `form:"source_lang"`
+}
+
+//GlossaryPersistencer interface for glossary persistence
+type GlossaryPersistencer interface {
+	GetGlossaryEntries(ctx context.Context, params GlossaryQueryParams) ([]GlossaryEntry, error)
+	GetGlossaryEntry(ctx context.Context, id string) (*GlossaryEntry, error)
+	PostGlossaryEntry(ctx context.Context, entry GlossaryEntry) (*GlossaryEntry, error)
+	PostGlossaryEntries(ctx context.Context, entries []GlossaryEntry) (int64, error)
+	PatchGlossaryEntry(ctx context.Context, id string, patch GlossaryEntryPatch) (*GlossaryEntry, error)
+	DeleteGlossaryEntry(ctx context.Context, id string) error
+}
+
+//glossaryKey identifies the entries of a language pair
+type glossaryKey struct {
+	sourceLang string
+	targetLang string
+}
+
+//checkGlossaryEntry return invalid fields of entry, langs are canonicalized and a do not translate term is its own target term
+func checkGlossaryEntry(entry *GlossaryEntry) []FieldError {
+	entry.SourceLang = localizing.CanonicalizeOrKeep(entry.SourceLang)
+	entry.SourceTerm = strings.TrimSpace(entry.SourceTerm)
+	entry.TargetTerm = strings.TrimSpace(entry.TargetTerm)
+	if entry.DoNotTranslate {
+		entry.TargetTerm = entry.SourceTerm
+	}
+
+	errs := checkSourceLang("source_lang", entry.SourceLang)
+	if entry.TargetLang == glossaryAnyLang {
+		if !entry.DoNotTranslate {
+			errs = append(errs, FieldError{"target_lang", fieldInvalidLang, "target_lang * is only for do not translate terms"})
+		}
+	} else {
+		entry.TargetLang = localizing.CanonicalizeOrKeep(entry.TargetLang)
+		errs = append(errs, checkSourceLang("target_lang", entry.TargetLang)...)
+		if entry.TargetLang != "" && entry.TargetLang == entry.SourceLang {
+			errs = append(errs, FieldError{"target_lang", fieldInvalidLang, "target_lang must be different from source_lang"})
+		}
+	}
+	errs = append(errs, checkText("source_term", entry.SourceTerm, maxTermLength, false)...)
+	errs = append(errs, checkText("target_term", entry.TargetTerm, maxTermLength, false)...)
+	errs = append(errs, checkText("note", entry.Note, maxNoteLength, true)...)
+	return errs
+}
+
+//checkGlossaryPatch return invalid fields of patch
+func checkGlossaryPatch(patch *GlossaryEntryPatch) []FieldError {
+	errs := []FieldError{}
+	if patch.TargetTerm != nil {
+		*patch.TargetTerm = strings.TrimSpace(*patch.TargetTerm)
+		errs = append(errs, checkText("target_term", *patch.TargetTerm, maxTermLength, false)...)
+	}
+	if patch.Note != nil {
+		errs = append(errs, checkText("note", *patch.Note, maxNoteLength, true)...)
+	}
+	return errs
+}
+
+//entriesOfConcepts return an entry for every term of a concept in a lang other than sourceLang and errors by index of concept;
+//a do not translate concept is one entry for every target lang
+func entriesOfConcepts(sourceLang string, concepts []exchanging.Concept) ([]GlossaryEntry, []ItemError) {
+	entries := []GlossaryEntry{}
+	errs := []ItemError{}
+
+	for i, concept := range concepts {
+		sourceTerm := ""
+		for _, term := range concept.Terms {
+			if localizing.CanonicalizeOrKeep(term.Lang) == sourceLang {
+				sourceTerm = term.Text
+				break
+			}
+		}
+		if sourceTerm == "" {
+			errs = append(errs, ItemError{i, FieldError{"term", fieldRequired, fmt.Sprintf("concept %s has no term in source lang %s", concept.ID, sourceLang)}})
+			continue
+		}
+
+		candidates := []GlossaryEntry{}
+		if concept.DoNotTranslate {
+			candidates = append(candidates, GlossaryEntry{SourceLang: sourceLang, SourceTerm: sourceTerm, TargetLang: glossaryAnyLang, DoNotTranslate: true, Note: concept.Note})
+		} else {
+			for _, term := range concept.Terms {
+				if lang := localizing.CanonicalizeOrKeep(term.Lang); lang != sourceLang {
+					candidates = append(candidates, GlossaryEntry{SourceLang: sourceLang, SourceTerm: sourceTerm, TargetLang: lang, TargetTerm: term.Text, Note: concept.Note})
+				}
+			}
+		}
+
+		for _, entry := range candidates {
+			fieldErrs := checkGlossaryEntry(&entry)
+			for _, fe := range fieldErrs {
+				errs = append(errs, ItemError{i, fe})
+			}
+			if len(fieldErrs) == 0 {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return entries, errs
+}
+
+//spacelessScripts are written without spaces between words, terms in them match without word boundaries
+var spacelessScripts = []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar}
+
+//termPattern return the case insensitive pattern of term as a whole word
+func termPattern(term string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(term)
+	if first, _ := utf8.DecodeRuneInString(term); isWordRune(first) && !unicode.In(first, spacelessScripts...) {
+		pattern = `(?:^|[^\p{L}\p{N}])` + pattern
+	}
+	if last, _ := utf8.DecodeLastRuneInString(term); isWordRune(last) && !unicode.In(last, spacelessScripts...) {
+		pattern += `(?:$|[^\p{L}\p{N}])`
+	}
+	return regexp.MustCompile(`(?i)` + pattern)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+//translatableText return the text of content without placeholders, that are replaced by a space
+func translatableText(content string) string {
+	var sb strings.Builder
+	for _, segment := range formatting.Segments(content) {
+		if segment.Translatable {
+			sb.WriteString(segment.Text)
+		} else {
+			sb.WriteString(" ")
+		}
+	}
+	return sb.String()
+}
+
+//glossaryIssues return a warning for every entry whose source term is in source and whose target term is not in item;
+//an entry of the target lang wins over the do not translate one for every lang of the same term
+func glossaryIssues(item, source LocaleItem, entries []GlossaryEntry) []FieldError {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	specific := map[string]bool{}
+	for _, entry := range entries {
+		if entry.TargetLang != glossaryAnyLang {
+			specific[strings.ToLower(entry.SourceTerm)] = true
+		}
+	}
+
+	errs := []FieldError{}
+	for _, pair := range variantPairs(item, source) {
+		sourceText := translatableText(pair.source)
+		itemText := translatableText(pair.text)
+		for _, entry := range entries {
+			if entry.TargetLang == glossaryAnyLang && specific[strings.ToLower(entry.SourceTerm)] {
+				continue
+			}
+			if !termPattern(entry.SourceTerm).MatchString(sourceText) || termPattern(entry.TargetTerm).MatchString(itemText) {
+				continue
+			}
+			if entry.DoNotTranslate {
+				errs = append(errs, FieldError{pair.field, fieldGlossaryTerm,
+					fmt.Sprintf("term %q of source %s is not to be translated", entry.SourceTerm, source.Lang)})
+				continue
+			}
+			errs = append(errs, FieldError{pair.field, fieldGlossaryTerm,
+				fmt.Sprintf("term %q of source %s must be translated as %q", entry.SourceTerm, source.Lang, entry.TargetTerm)})
+		}
+	}
+	return errs
+}
+
+//loadGlossary adds to rules the glossary entries of translations in items, by their bundle source lang and lang
+func (lph LocalePersistenceHandler) loadGlossary(ctx context.Context, items []LocaleItem, rules *writeRules) error {
+	for _, item := range items {
+		bundle, ok := rules.bundles[item.Bundle]
+		if !ok || bundle.SourceLang == "" || item.Lang == bundle.SourceLang {
+			continue
+		}
+		gk := glossaryKey{bundle.SourceLang, item.Lang}
+		if _, ok := rules.glossary[gk]; ok {
+			continue
+		}
+
+		entries, err := lph.GlossaryDelegate.GetGlossaryEntries(ctx, GlossaryQueryParams{SourceLang: gk.sourceLang, TargetLang: gk.targetLang})
+		if err != nil {
+			return err
+		}
+		rules.glossary[gk] = entries
+	}
+	return nil
+}
+
+//GetGlossary return glossary entries, filtered by langs and term
+func (lph LocalePersistenceHandler) GetGlossary(c *gin.Context) {
+	var glossaryQueryParams GlossaryQueryParams
+	err := c.ShouldBindQuery(&glossaryQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	glossaryQueryParams.SourceLang = localizing.CanonicalizeOrKeep(glossaryQueryParams.SourceLang)
+	glossaryQueryParams.TargetLang = localizing.CanonicalizeOrKeep(glossaryQueryParams.TargetLang)
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetGlossary)
+	defer cancel()
+	entries, err := lph.GlossaryDelegate.GetGlossaryEntries(ctx, glossaryQueryParams)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+//PostGlossaryEntry adds a term to the glossary, it needs admin role
+func (lph LocalePersistenceHandler) PostGlossaryEntry(c *gin.Context) {
+	var entry GlossaryEntry
+	err := c.ShouldBindJSON(&entry)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if !requireAdmin(c, "Change of glossary") {
+		return
+	}
+
+	if fieldErrs := checkGlossaryEntry(&entry); len(fieldErrs) > 0 {
+		abortValidation(c, "Glossary entry has invalid fields", fieldErrs)
+		return
+	}
+	setAuditFilters(c, "", entry.TargetLang, "")
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPostGlossary)
+	defer cancel()
+	created, err := lph.GlossaryDelegate.PostGlossaryEntry(ctx, entry)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.JSON(http.StatusCreated, created)
+}
+
+//PatchGlossaryEntry changes the target term of a glossary entry, it needs admin role
+func (lph LocalePersistenceHandler) PatchGlossaryEntry(c *gin.Context) {
+	var patch GlossaryEntryPatch
+	err := c.ShouldBindJSON(&patch)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if !requireAdmin(c, "Change of glossary") {
+		return
+	}
+
+	if fieldErrs := checkGlossaryPatch(&patch); len(fieldErrs) > 0 {
+		abortValidation(c, "Glossary entry has invalid fields", fieldErrs)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPatchGlossary)
+	defer cancel()
+	entry, err := lph.GlossaryDelegate.PatchGlossaryEntry(ctx, c.Param("id"), patch)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditFilters(c, "", entry.TargetLang, "")
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.JSON(http.StatusOK, entry)
+}
+
+//DeleteGlossaryEntry removes a term from the glossary, it needs admin role
+func (lph LocalePersistenceHandler) DeleteGlossaryEntry(c *gin.Context) {
+	if !requireAdmin(c, "Change of glossary") {
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opDeleteGlossary)
+	defer cancel()
+	err := lph.GlossaryDelegate.DeleteGlossaryEntry(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.Status(http.StatusNoContent)
+}
+
+//ImportGlossary stores the concepts of a CSV, when content type is text/csv, or TBX document;
+//an imported term replaces the one of the glossary with the same source term
+func (lph LocalePersistenceHandler) ImportGlossary(c *gin.Context) {
+	if !requireAdmin(c, "Import of glossary") {
+		return
+	}
+
+	var glossaryImportParams GlossaryImportParams
+	err := c.ShouldBindQuery(&glossaryImportParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	sourceLang := localizing.CanonicalizeOrKeep(glossaryImportParams.SourceLang)
+	if errs := checkSourceLang("source_lang", sourceLang); len(errs) > 0 {
+		abortValidation(c, "Import has invalid params", errs)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	var concepts []exchanging.Concept
+	if c.ContentType() == "text/csv" {
+		concepts, err = exchanging.ReadConceptsCSV(c.Request.Body)
+	} else {
+		concepts, err = exchanging.ReadTBX(c.Request.Body)
+	}
+	if err != nil {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeBadRequest, "Error on parsing request: "+err.Error())
+		return
+	}
+
+	entries, errs := entriesOfConcepts(sourceLang, concepts)
+	failed := map[int]bool{}
+	for _, ie := range errs {
+		failed[ie.Index] = true
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opImportGlossary)
+	defer cancel()
+	numUpserted, err := lph.GlossaryDelegate.PostGlossaryEntries(ctx, entries)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	result := MassiveResult{NumSuccessfull: numUpserted, NumFailed: int64(len(failed)), Errors: errs}
+	setAuditResult(c, result)
+	c.JSON(http.StatusOK, result)
+}
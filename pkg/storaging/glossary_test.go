@@ -0,0 +1,80 @@
+package storaging
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/exchanging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckGlossaryEntry(t *testing.T) {
+	entry := GlossaryEntry{SourceLang: "en_us", SourceTerm: " Workspace ", TargetLang: "it_it", TargetTerm: "Area di lavoro"}
+	assert.Empty(t, checkGlossaryEntry(&entry))
+	assert.Equal(t, GlossaryEntry{SourceLang: "en-US", SourceTerm: "Workspace", TargetLang: "it-IT", TargetTerm: "Area di lavoro"}, entry)
+
+	entry = GlossaryEntry{SourceLang: "en", SourceTerm: "Dashboard", TargetLang: glossaryAnyLang, TargetTerm: "ignored", DoNotTranslate: true}
+	assert.Empty(t, checkGlossaryEntry(&entry))
+	assert.Equal(t, "Dashboard", entry.TargetTerm)
+
+	entry = GlossaryEntry{SourceLang: "en", SourceTerm: "Save", TargetLang: glossaryAnyLang}
+	assert.Equal(t, []FieldError{
+		{"target_lang", fieldInvalidLang, "target_lang * is only for do not translate terms"},
+		{"target_term", fieldRequired, "target_term is required"},
+	}, checkGlossaryEntry(&entry))
+
+	entry = GlossaryEntry{SourceLang: "en", SourceTerm: "Save", TargetLang: "en", TargetTerm: "Save"}
+	assert.Equal(t, []FieldError{{"target_lang", fieldInvalidLang, "target_lang must be different from source_lang"}}, checkGlossaryEntry(&entry))
+}
+
+func TestEntriesOfConcepts(t *testing.T) {
+	entries, errs := entriesOfConcepts("en", []exchanging.Concept{
+		{ID: "1", Note: "team area", Terms: []exchanging.Variant{{Lang: "en", Text: "Workspace"}, {Lang: "it_IT", Text: "Area di lavoro"}, {Lang: "de", Text: "Arbeitsbereich"}}},
+		{ID: "2", DoNotTranslate: true, Terms: []exchanging.Variant{{Lang: "en", Text: "Dashboard"}}},
+		{ID: "3", Terms: []exchanging.Variant{{Lang: "it", Text: "Salva"}}},
+	})
+
+	assert.Equal(t, []GlossaryEntry{
+		{SourceLang: "en", SourceTerm: "Workspace", TargetLang: "it-IT", TargetTerm: "Area di lavoro", Note: "team area"},
+		{SourceLang: "en", SourceTerm: "Workspace", TargetLang: "de", TargetTerm: "Arbeitsbereich", Note: "team area"},
+		{SourceLang: "en", SourceTerm: "Dashboard", TargetLang: glossaryAnyLang, TargetTerm: "Dashboard", DoNotTranslate: true},
+	}, entries)
+	assert.Equal(t, []ItemError{{2, FieldError{"term", fieldRequired, "concept 3 has no term in source lang en"}}}, errs)
+}
+
+func TestTermPattern(t *testing.T) {
+	assert.True(t, termPattern("Workspace").MatchString("Open the workspace."))
+	assert.False(t, termPattern("Workspace").MatchString("Open the workspaces"))
+	assert.True(t, termPattern("C++").MatchString("Learn C++ now"))
+	assert.True(t, termPattern("ワークスペース").MatchString("ワークスペースを開く"))
+}
+
+func TestCheckGlossary(t *testing.T) {
+	v := &itemValidator{keyPatterns: map[string]*regexp.Regexp{}}
+	source := LocaleItem{Key: "OPEN", Bundle: "label", Lang: "en", Content: "Open {workspace} in the Workspace Dashboard"}
+	rules := writeRules{
+		bundles: map[string]Bundle{"label": {ID: "label", SourceLang: "en", PlaceholderCheck: placeholderCheckOff}},
+		sources: map[itemKey]LocaleItem{{"label", "OPEN"}: source},
+		glossary: map[glossaryKey][]GlossaryEntry{{"en", "it-IT"}: {
+			{SourceLang: "en", SourceTerm: "Dashboard", TargetLang: glossaryAnyLang, TargetTerm: "Dashboard", DoNotTranslate: true},
+			{SourceLang: "en", SourceTerm: "workspace", TargetLang: "it-IT", TargetTerm: "Area di lavoro"},
+			{SourceLang: "en", SourceTerm: "Workspace", TargetLang: glossaryAnyLang, TargetTerm: "Workspace", DoNotTranslate: true},
+			{SourceLang: "en", SourceTerm: "Save", TargetLang: "it-IT", TargetTerm: "Salva"},
+		}},
+	}
+
+	errs, warnings := v.check(LocaleItem{Key: "OPEN", Bundle: "label", Lang: "it-IT", Content: "Apri {workspace} nel Cruscotto dell'area di lavoro"}, rules)
+	assert.Empty(t, errs)
+	assert.Equal(t, []FieldError{{"content", fieldGlossaryTerm, `term "Dashboard" of source en is not to be translated`}}, warnings)
+
+	errs, warnings = v.check(LocaleItem{Key: "OPEN", Bundle: "label", Lang: "it-IT", Content: "Apri {workspace} nella Dashboard"}, rules)
+	assert.Empty(t, errs)
+	assert.Equal(t, []FieldError{{"content", fieldGlossaryTerm, `term "workspace" of source en must be translated as "Area di lavoro"`}}, warnings)
+
+	_, warnings = v.check(LocaleItem{Key: "OPEN", Bundle: "label", Lang: "de", Content: "Öffnen"}, rules)
+	assert.Empty(t, warnings)
+
+	_, warnings = v.check(LocaleItem{Key: "OPEN", Bundle: "label", Lang: "it-IT", Content: "Apri {workspace} nella Dashboard dell'area di lavoro",
+		Plurals: PluralForms{"one": "Apri {workspace} nella Dashboard dell'area di lavoro", "other": "Apri {workspace} nel Cruscotto dell'area di lavoro"}}, rules)
+	assert.Equal(t, []FieldError{{"plurals.other", fieldGlossaryTerm, `term "Dashboard" of source en is not to be translated`}}, warnings)
+}
@@ -4,25 +4,96 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/authorizating"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/translating"
 	"github.com/gin-gonic/gin"
 )
 
 //LocalePersistenceHandler manages route for persistence
 type LocalePersistenceHandler struct {
 	PersistenceDelegate LocalePersistencer
+	AuditDelegate       AuditPersistencer
+	TrashDelegate       TrashPersistencer
+	LanguageDelegate    LanguagePersistencer
+	BundleDelegate      BundlePersistencer
+	SearchDelegate      SearchPersistencer
+	SimilarityDelegate  SimilarityPersistencer
+	MemoryDelegate      MemoryPersistencer
+	GlossaryDelegate    GlossaryPersistencer
+	KeyDelegate         KeyPersistencer
+	ScreenshotDelegate  ScreenshotPersistencer
+	CommentDelegate     CommentPersistencer
+	timeouts            queryTimeouts
+	translator          *translating.Runner
+	pseudoExpansion     int
+	confirmationTTL     time.Duration
+	screenshotDir       string
+	validator           *itemValidator
 }
 
 //NewPersistenceHandler handles persitence request
 func NewPersistenceHandler() (*LocalePersistenceHandler, error) {
 	lph := &LocalePersistenceHandler{}
 
+	timeouts, err := loadQueryTimeouts()
+	if err != nil {
+		return nil, err
+	}
+	lph.timeouts = timeouts
+
+	validator, err := newItemValidator()
+	if err != nil {
+		return nil, err
+	}
+	lph.validator = validator
+
+	translator, err := translating.NewRunnerFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	lph.translator = translator
+
+	pseudoExpansion, err := loadPseudoExpansion()
+	if err != nil {
+		return nil, err
+	}
+	lph.pseudoExpansion = pseudoExpansion
+
+	confirmationTTL, err := loadConfirmationTTL()
+	if err != nil {
+		return nil, err
+	}
+	lph.confirmationTTL = confirmationTTL
+
+	screenshotDir, err := loadScreenshotDir()
+	if err != nil {
+		return nil, err
+	}
+	lph.screenshotDir = screenshotDir
+
 	lp, err := NewPostgresPersistenceService()
 	if err != nil {
 		return nil, err
 	}
 
 	lph.PersistenceDelegate = *lp
+	lph.AuditDelegate = *lp
+	lph.TrashDelegate = *lp
+	lph.LanguageDelegate = *lp
+	lph.BundleDelegate = *lp
+	lph.SearchDelegate = *lp
+	lph.SimilarityDelegate = *lp
+	lph.MemoryDelegate = *lp
+	lph.GlossaryDelegate = *lp
+	lph.KeyDelegate = *lp
+	lph.ScreenshotDelegate = *lp
+	lph.CommentDelegate = *lp
+
+	go purgeTrash(lph.TrashDelegate, lph.timeouts)
 
 	return lph, nil
 }
@@ -32,24 +103,34 @@ func (lph LocalePersistenceHandler) PostLocaleItem(c *gin.Context) {
 	var localeItem LocaleItem
 	err := c.ShouldBind(&localeItem)
 	if err != nil {
-		msg := ErrorMessage{fmt.Sprintf("Error on bind payload: %v", err)}
-		c.JSON(http.StatusBadRequest, msg)
+		respondBindError(c, err)
 		return
 	}
 
-	if localeItem.isValid() == false {
-		msg := ErrorMessage{fmt.Sprintf("Localeitem not valid: %v", localeItem)}
-		c.JSON(http.StatusBadRequest, msg)
+	prepareItem(&localeItem)
+	setAuditFilters(c, localeItem.Bundle, localeItem.Lang, localeItem.Key)
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPostLocaleItem)
+	defer cancel()
+	rules, err := lph.writeRules(ctx, []LocaleItem{localeItem})
+	if err != nil {
+		respondError(c, ctx, err)
 		return
 	}
 
-	localeItemReturned, err := lph.PersistenceDelegate.PostLocaleItem(localeItem)
+	fieldErrs, warnings := lph.validator.check(localeItem, rules)
+	if len(fieldErrs) > 0 {
+		abortValidation(c, "Localeitem has invalid fields", fieldErrs)
+		return
+	}
+
+	localeItemReturned, err := lph.PersistenceDelegate.PostLocaleItem(ctx, localeItem)
 	if err != nil {
-		msg := ErrorMessage{fmt.Sprintf("Error on persist item: %v", err)}
-		c.JSON(http.StatusInternalServerError, msg)
+		respondError(c, ctx, err)
 		return
 	}
-	c.JSON(http.StatusCreated, localeItemReturned)
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.JSON(http.StatusCreated, LocaleItemResult{*localeItemReturned, warnings})
 }
 
 //PostLocaleItemHandler handle persitensce of an array locale items
@@ -57,24 +138,44 @@ func (lph LocalePersistenceHandler) PostLocaleItems(c *gin.Context) {
 	var localeItems []LocaleItem
 	err := c.ShouldBind(&localeItems)
 	if err != nil {
-		msg := ErrorMessage{fmt.Sprintf("Error on bind payload: %v", err)}
-		c.JSON(http.StatusBadRequest, msg)
+		respondBindError(c, err)
 		return
 	}
 
-	numInserted, err := lph.PersistenceDelegate.PostLocaleItems(localeItems)
+	for i := range localeItems {
+		prepareItem(&localeItems[i])
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPostLocaleItems)
+	defer cancel()
+	rules, err := lph.writeRules(ctx, localeItems)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, err.Error())
+		respondError(c, ctx, err)
 		return
 	}
 
+	validItems, itemErrs, itemWarnings := lph.validator.validateAll(localeItems, rules)
+
+	var numInserted int64
+	if len(validItems) > 0 {
+		numInserted, err = lph.PersistenceDelegate.PostLocaleItems(ctx, validItems)
+		if err != nil {
+			respondError(c, ctx, err)
+			return
+		}
+	}
+
 	result := MassiveResult{}
 	result.NumSuccessfull = numInserted
 	result.NumFailed = int64(len(localeItems)) - numInserted
+	result.Errors = itemErrs
+	result.Warnings = itemWarnings
+	setAuditResult(c, result)
 	c.JSON(http.StatusCreated, result)
 }
 
-//GetLocaleItemHandler handle retrive for locale items
+//GetLocaleItemHandler handle retrive for locale items,
+//a pseudo-localization lang return the items of source lang of the bundle pseudo-localized
 func (lph LocalePersistenceHandler) GetLocaleItemByBundleKeyLang(c *gin.Context) {
 	var localeItems []LocaleItem
 	var localeItemQueryParams LocaleItemQueryParams
@@ -82,57 +183,112 @@ func (lph LocalePersistenceHandler) GetLocaleItemByBundleKeyLang(c *gin.Context)
 
 	err := c.ShouldBind(&localeItemQueryParams)
 	if err != nil {
-		msg := ErrorMessage{"Error on parsing query params"}
-		c.JSON(http.StatusInternalServerError, msg)
+		respondBindError(c, err)
 		return
 	}
 
-	localeItems, err = lph.PersistenceDelegate.GetLocaleItems(localeItemQueryParams.Key, bundleId, localeItemQueryParams.Lang, localeItemQueryParams.Content, localeItemQueryParams.Limit, localeItemQueryParams.Offset)
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetLocaleItems)
+	defer cancel()
+	lang := localizing.CanonicalizeOrKeep(localeItemQueryParams.Lang)
+	localeItems, err = lph.readItems(ctx, bundleId, "", lang, func(lang string) ([]LocaleItem, error) {
+		return lph.PersistenceDelegate.GetLocaleItems(ctx, localeItemQueryParams.Key, bundleId, lang, localeItemQueryParams.Content,
+			localeItemQueryParams.Tag, localeItemQueryParams.Limit, localeItemQueryParams.Offset)
+	})
 	if err != nil {
-		msg := ErrorMessage{fmt.Sprintf("Error on retrive items for %s, %s, %s : %v", localeItemQueryParams.Key, bundleId, localeItemQueryParams.Lang, err)}
-		c.JSON(http.StatusInternalServerError, msg)
+		respondError(c, ctx, err)
 		return
 	}
 	c.JSON(http.StatusOK, localeItems)
 }
 
-//GetLocaleItemHandler handle retrive locale item by id
+//GetLocaleItemHandler handle retrive locale item by id,
+//with lang the item of the same bundle and key in lang, pseudo-localized from source lang for a pseudo-localization lang
 func (lph LocalePersistenceHandler) GetLocaleItemById(c *gin.Context) {
 	pId := c.Param("id")
-	var msg ErrorMessage
 
-	localeItem, err := lph.PersistenceDelegate.GetLocaleItem(pId)
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetLocaleItem)
+	defer cancel()
+	localeItem, err := lph.PersistenceDelegate.GetLocaleItem(ctx, pId)
 	if err != nil {
-		msg = ErrorMessage{fmt.Sprintf("Error on retrive items for %s: %v", pId, err)}
-		c.JSON(http.StatusInternalServerError, msg)
+		respondError(c, ctx, err)
+		return
+	}
+
+	lang := localizing.CanonicalizeOrKeep(c.Query("lang"))
+	if lang == "" || lang == localeItem.Lang {
+		c.JSON(http.StatusOK, localeItem)
 		return
 	}
 
-	if err == nil && localeItem == nil {
-		msg = ErrorMessage{fmt.Sprintf("No item found for id %s", pId)}
-		c.JSON(http.StatusNotFound, msg)
+	items, err := lph.readItems(ctx, localeItem.Bundle, "", lang, func(lang string) ([]LocaleItem, error) {
+		return lph.PersistenceDelegate.GetLocaleItemsByKeys(ctx, localeItem.Bundle, lang, []string{localeItem.Key})
+	})
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	if len(items) == 0 {
+		respondError(c, ctx, newError(ErrNotFound, fmt.Sprintf("No item found for key %s of bundle %s in lang %s", localeItem.Key, localeItem.Bundle, lang), nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, localeItem)
+	c.JSON(http.StatusOK, items[0])
 }
 
-//DeleteLocaleItemHandler handle retrive for delete locale items
+//DeleteLocaleItemHandler handle retrive for delete locale items,
+//with dry_run it returns matching items and the confirmation token the real delete requires
 func (lph LocalePersistenceHandler) DeleteLocaleItemByBundleKeyLang(c *gin.Context) {
-	var localeItemQueryParams LocaleItemQueryParams
+	var deleteQueryParams DeleteQueryParams
 	var bundleId string = c.Param("bundle")
 
-	err := c.ShouldBindQuery(&localeItemQueryParams)
+	err := c.ShouldBindQuery(&deleteQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	lang := deleteQueryParams.Lang
+	if langId := c.Param("langId"); langId != "" {
+		lang = langId
+	}
+	if lang != "" {
+		lang = localizing.CanonicalizeOrKeep(lang)
+	}
+	key := deleteQueryParams.Key
+	if keyId := c.Param("keyId"); keyId != "" {
+		key = keyId
+	}
+
+	//whole bundle or whole language
+	if key == "" && !authorizating.HasRole(c, authorizating.RoleAdmin) {
+		problem.Abort(c, http.StatusForbidden, problem.CodeForbidden, fmt.Sprintf("Delete of whole bundle %s or lang needs %s role", bundleId, authorizating.RoleAdmin))
+		return
+	}
+
+	user := authorizating.CurrentUser(c)
+
+	if deleteQueryParams.DryRun {
+		setAuditDryRun(c)
+		lph.previewDelete(c, user, key, bundleId, lang)
+		return
+	}
+
+	if deleteQueryParams.ConfirmationToken == "" {
+		problem.Abort(c, http.StatusPreconditionRequired, problem.CodeConfirmationRequired, "Missing confirmation_token, ask for it with dry_run=true")
+		return
+	}
+
+	err = checkConfirmationToken(deleteQueryParams.ConfirmationToken, user, key, bundleId, lang)
 	if err != nil {
-		msg := ErrorMessage{"Error on parsing query params"}
-		c.JSON(http.StatusInternalServerError, msg)
+		problem.Abort(c, http.StatusPreconditionFailed, problem.CodeConfirmationInvalid, fmt.Sprintf("Invalid confirmation_token: %v", err))
 		return
 	}
 
-	numDeleteItems, err := lph.PersistenceDelegate.DeleteLocaleItems(localeItemQueryParams.Key, bundleId, localeItemQueryParams.Lang)
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opDeleteLocaleItems)
+	defer cancel()
+	numDeleteItems, err := lph.PersistenceDelegate.DeleteLocaleItems(ctx, key, bundleId, lang)
 	if err != nil {
-		msg := ErrorMessage{fmt.Sprintf("Error on delete items for %s, %s, %s : %v", localeItemQueryParams.Key, bundleId, localeItemQueryParams.Lang, err)}
-		c.JSON(http.StatusInternalServerError, msg)
+		respondError(c, ctx, err)
 		return
 	}
 
@@ -140,29 +296,52 @@ func (lph LocalePersistenceHandler) DeleteLocaleItemByBundleKeyLang(c *gin.Conte
 	result.NumSuccessfull = numDeleteItems
 	result.NumFailed = 0
 
+	setAuditResult(c, result)
 	c.JSON(http.StatusOK, result)
 }
 
-//GetAllLangs return all lang
-func (lph LocalePersistenceHandler) GetAllLangs(c *gin.Context) {
-	candidateBundle := c.Param("bundleId")
-	log.Printf("Bundle of filter lang %v\n", candidateBundle)
-	result, err := lph.PersistenceDelegate.GetLangs(candidateBundle)
+func (lph LocalePersistenceHandler) previewDelete(c *gin.Context, user, key, bundleId, lang string) {
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opCountLocaleItems)
+	defer cancel()
+	count, err := lph.PersistenceDelegate.CountLocaleItems(ctx, key, bundleId, lang)
 	if err != nil {
-		msg := ErrorMessage{fmt.Sprintf("Error on retrive langs: %v", err)}
-		c.JSON(http.StatusInternalServerError, msg)
+		respondError(c, ctx, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, result)
+	var sample []LocaleItem
+	if key == "" {
+		sample, err = lph.PersistenceDelegate.GetLocaleItems(ctx, "", bundleId, lang, "", "", deleteSampleSize, 0)
+	} else {
+		sample, err = lph.PersistenceDelegate.GetLocaleItemsByKeys(ctx, bundleId, lang, []string{key})
+	}
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	if len(sample) > deleteSampleSize {
+		sample = sample[:deleteSampleSize]
+	}
+
+	token, expiresAt := newConfirmationToken(lph.confirmationTTL, user, key, bundleId, lang)
+
+	c.JSON(http.StatusOK, DeletePreview{
+		Count:             count,
+		Sample:            sample,
+		ConfirmationToken: token,
+		ExpiresAt:         expiresAt,
+	})
 }
 
-//GetAllLangs return all bundles
-func (lph LocalePersistenceHandler) GetAllBundles(c *gin.Context) {
-	result, err := lph.PersistenceDelegate.GetBundles()
+//GetAllLangs return all lang
+func (lph LocalePersistenceHandler) GetAllLangs(c *gin.Context) {
+	candidateBundle := c.Param("bundleId")
+	log.Printf("Bundle of filter lang %v\n", candidateBundle)
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetLangs)
+	defer cancel()
+	result, err := lph.PersistenceDelegate.GetLangs(ctx, candidateBundle)
 	if err != nil {
-		msg := ErrorMessage{fmt.Sprintf("Error on retrive bundles: %v", err)}
-		c.JSON(http.StatusInternalServerError, msg)
+		respondError(c, ctx, err)
 		return
 	}
 
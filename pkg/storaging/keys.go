@@ -0,0 +1,397 @@
+package storaging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rivo/uniseg"
+)
+
+//units of max length of a key
+const (
+	lengthUnitChars     = "chars"
+	lengthUnitGraphemes = "graphemes"
+)
+
+//charsets a key can allow, any allows every char
+const (
+	charsetAny    = "any"
+	charsetASCII  = "ascii"
+	charsetLatin1 = "latin1"
+	charsetGSM7   = "gsm7"
+	charsetBMP    = "bmp"
+)
+
+//codes of constraint violations, too long ones are fieldTooLong
+const (
+	fieldInvalidChar = "invalid_char"
+	fieldSingleLine  = "single_line"
+)
+
+//max of tags of a key and of characters of a tag
+const (
+	maxTags      = 32
+	maxTagLength = 64
+)
+
+//gsm7Chars are the chars of GSM 03.38 basic table and its extension, the ones an SMS can have without UCS-2
+const gsm7Chars = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà" +
+	"\f^{}\\[~]|€"
+
+//charsetAllows return true when the char r is in charset
+var charsetAllows = map[string]func(r rune) bool{
+	charsetAny:    func(r rune) bool { return true },
+	charsetASCII:  func(r rune) bool { return r < utf8.RuneSelf },
+	charsetLatin1: func(r rune) bool { return r <= 0xFF },
+	charsetGSM7:   func(r rune) bool { return strings.ContainsRune(gsm7Chars, r) },
+	charsetBMP:    func(r rune) bool { return r <= 0xFFFF },
+}
+
+//KeyMetadata rappresents the settings of a key shared by its items in every lang:
+//MaxLength in LengthUnit, 0 for no limit, the Charset of content and if it can have more lines,
+//and the context translators get as Description, developer Note, Tags and Screenshots
+type KeyMetadata struct {
+	Bundle      string       `json:"bundle"`
+	Key         string       `json:"key"`
+	MaxLength   int          `json:"max_length"`
+	LengthUnit  string       `json:"length_unit"`
+	Charset     string       `json:"charset"`
+	Multiline   bool         `json:"multiline"`
+	Description string       `json:"description"`
+	Note        string       `json:"note"`
+	Tags        []string     `json:"tags"`
+	Screenshots []Screenshot `json:"screenshots"`
+}
+
+//KeyRename rappresents the new name of a key
+type KeyRename struct {
+	Key string `json:"key"`
+}
+
+//ConstraintsReport rappresents the items of a bundle that violate the constraints of their key
+type ConstraintsReport struct {
+	Bundle     string      `json:"bundle"`
+	NumKeys    int         `json:"num_keys"`
+	NumChecked int         `json:"num_checked"`
+	Violations []LintIssue `json:"violations"`
+}
+
+//KeyPersistencer interface for key metadata persistence
+type KeyPersistencer interface {
+	GetKeys(ctx context.Context, bundle string, keys []string) ([]KeyMetadata, error)
+	PutKey(ctx context.Context, metadata KeyMetadata) error
+	DeleteKey(ctx context.Context, bundle, key string) error
+	RenameKey(ctx context.Context, bundle, key, newKey string) (int64, error)
+}
+
+//newKeyMetadata return the metadata of a key with defaults: no max length, any char and more lines
+func newKeyMetadata(bundle, key string) KeyMetadata {
+	return KeyMetadata{Bundle: bundle, Key: key, LengthUnit: lengthUnitChars, Charset: charsetAny, Multiline: true,
+		Tags: []string{}, Screenshots: []Screenshot{}}
+}
+
+//checkTags return invalid tags, tags are trimmed and the empty and repeated ones removed
+func checkTags(tags *[]string) []FieldError {
+	errs := []FieldError{}
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range *tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+
+		errs = append(errs, checkText("tags", tag, maxTagLength, false)...)
+		if strings.Contains(tag, ",") {
+			errs = append(errs, FieldError{"tags", fieldInvalidName, fmt.Sprintf("tag %q must not contain ','", tag)})
+		}
+	}
+	if len(normalized) > maxTags {
+		errs = append(errs, FieldError{"tags", fieldTooLong, fmt.Sprintf("tags are %d, max is %d", len(normalized), maxTags)})
+	}
+
+	*tags = normalized
+	return errs
+}
+
+//checkKeyMetadata return invalid fields of metadata, empty unit and charset are set to the default ones
+func checkKeyMetadata(metadata *KeyMetadata) []FieldError {
+	errs := checkText("key", metadata.Key, maxKeyLength, false)
+	errs = append(errs, checkText("description", metadata.Description, maxNoteLength, true)...)
+	errs = append(errs, checkText("note", metadata.Note, maxNoteLength, true)...)
+	errs = append(errs, checkTags(&metadata.Tags)...)
+	if metadata.LengthUnit == "" {
+		metadata.LengthUnit = lengthUnitChars
+	}
+	if metadata.Charset == "" {
+		metadata.Charset = charsetAny
+	}
+
+	if metadata.MaxLength < 0 || metadata.MaxLength > maxContentLength {
+		errs = append(errs, FieldError{"max_length", fieldInvalidName, fmt.Sprintf("max_length must be from 0, no limit, to %d", maxContentLength)})
+	}
+	if metadata.LengthUnit != lengthUnitChars && metadata.LengthUnit != lengthUnitGraphemes {
+		errs = append(errs, FieldError{"length_unit", fieldInvalidName, "length_unit must be chars or graphemes"})
+	}
+	if _, ok := charsetAllows[metadata.Charset]; !ok {
+		errs = append(errs, FieldError{"charset", fieldInvalidName, "charset must be any, ascii, latin1, gsm7 or bmp"})
+	}
+	return errs
+}
+
+//contentLength return the length of value in unit
+func contentLength(value, unit string) int {
+	if unit == lengthUnitGraphemes {
+		return uniseg.GraphemeClusterCount(value)
+	}
+	return utf8.RuneCountInString(value)
+}
+
+//checkConstraints return the violations of item content and plural variants of the constraints of its key
+func checkConstraints(item LocaleItem, metadata KeyMetadata) []FieldError {
+	errs := []FieldError{}
+	check := func(field, value string) {
+		if length := contentLength(value, metadata.LengthUnit); metadata.MaxLength > 0 && length > metadata.MaxLength {
+			errs = append(errs, FieldError{field, fieldTooLong,
+				fmt.Sprintf("%s is %d %s, max of key %s is %d", field, length, metadata.LengthUnit, metadata.Key, metadata.MaxLength)})
+		}
+		if !metadata.Multiline && strings.ContainsAny(value, "\n\r") {
+			errs = append(errs, FieldError{field, fieldSingleLine, fmt.Sprintf("%s has more lines, key %s is single line", field, metadata.Key)})
+		}
+		if allows, ok := charsetAllows[metadata.Charset]; ok {
+			for i, r := range value {
+				if !allows(r) {
+					errs = append(errs, FieldError{field, fieldInvalidChar,
+						fmt.Sprintf("%s has char %q (%U) at byte %d, not in charset %s of key %s", field, r, r, i, metadata.Charset, metadata.Key)})
+					break
+				}
+			}
+		}
+	}
+
+	check("content", item.Content)
+	for _, category := range item.Plurals.categories() {
+		check("plurals."+category, item.Plurals[category])
+	}
+	return errs
+}
+
+//loadKeys adds to rules the metadata of the keys of items
+func (lph LocalePersistenceHandler) loadKeys(ctx context.Context, items []LocaleItem, rules *writeRules) error {
+	keysByBundle := map[string][]string{}
+	for _, item := range items {
+		keysByBundle[item.Bundle] = append(keysByBundle[item.Bundle], item.Key)
+	}
+
+	for bundle, keys := range keysByBundle {
+		metadata, err := lph.KeyDelegate.GetKeys(ctx, bundle, keys)
+		if err != nil {
+			return err
+		}
+		for _, km := range metadata {
+			rules.keys[itemKey{km.Bundle, km.Key}] = km
+		}
+	}
+	return nil
+}
+
+//withScreenshots adds to metadata of keys of bundle their screenshots
+func (lph LocalePersistenceHandler) withScreenshots(ctx context.Context, bundle string, keys []KeyMetadata) error {
+	names := make([]string, len(keys))
+	for i, km := range keys {
+		names[i] = km.Key
+	}
+	screenshots, err := lph.ScreenshotDelegate.GetScreenshots(ctx, bundle, names)
+	if err != nil {
+		return err
+	}
+
+	byKey := map[string][]Screenshot{}
+	for _, screenshot := range screenshots {
+		byKey[screenshot.Key] = append(byKey[screenshot.Key], screenshot)
+	}
+	for i := range keys {
+		keys[i].Screenshots = byKey[keys[i].Key]
+		if keys[i].Screenshots == nil {
+			keys[i].Screenshots = []Screenshot{}
+		}
+	}
+	return nil
+}
+
+//GetKeys return the metadata of the keys of a bundle that have one, with their screenshots
+func (lph LocalePersistenceHandler) GetKeys(c *gin.Context) {
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetKeys)
+	defer cancel()
+	keys, err := lph.KeyDelegate.GetKeys(ctx, c.Param("id"), nil)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	if err = lph.withScreenshots(ctx, c.Param("id"), keys); err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+//PutKey sets the metadata of a key, items written later are checked against its constraints
+func (lph LocalePersistenceHandler) PutKey(c *gin.Context) {
+	metadata := newKeyMetadata(c.Param("id"), c.Param("key"))
+	err := c.ShouldBindJSON(&metadata)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	metadata.Bundle, metadata.Key = c.Param("id"), c.Param("key")
+	if metadata.Tags == nil {
+		metadata.Tags = []string{}
+	}
+	setAuditFilters(c, metadata.Bundle, "", metadata.Key)
+
+	if fieldErrs := checkKeyMetadata(&metadata); len(fieldErrs) > 0 {
+		abortValidation(c, "Key has invalid fields", fieldErrs)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPutKey)
+	defer cancel()
+	if _, err = lph.getBundle(ctx, metadata.Bundle); err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	if err = lph.KeyDelegate.PutKey(ctx, metadata); err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	keys := []KeyMetadata{metadata}
+	if err = lph.withScreenshots(ctx, metadata.Bundle, keys); err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.JSON(http.StatusOK, keys[0])
+}
+
+//DeleteKey removes the metadata of a key and with it its screenshots, their blobs included; its items are kept
+func (lph LocalePersistenceHandler) DeleteKey(c *gin.Context) {
+	bundle, key := c.Param("id"), c.Param("key")
+	setAuditFilters(c, bundle, "", key)
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opDeleteKey)
+	defer cancel()
+	screenshots, err := lph.ScreenshotDelegate.GetScreenshots(ctx, bundle, []string{key})
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	err = lph.KeyDelegate.DeleteKey(ctx, bundle, key)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	for _, screenshot := range screenshots {
+		lph.removeBlob(screenshot.BlobPath)
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.Status(http.StatusNoContent)
+}
+
+//RenameKey renames a key in place: its items in every lang, trashed ones included, keep their id and so their comments,
+//its metadata and screenshots move with it; audit entries recorded before keep the old key
+func (lph LocalePersistenceHandler) RenameKey(c *gin.Context) {
+	bundleID, key := c.Param("id"), c.Param("key")
+	setAuditFilters(c, bundleID, "", key)
+
+	var rename KeyRename
+	err := c.ShouldBindJSON(&rename)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opRenameKey)
+	defer cancel()
+	bundle, err := lph.getBundle(ctx, bundleID)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	fieldErrs, err := lph.validator.checkKey(*bundle, rename.Key)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	if rename.Key == key {
+		fieldErrs = append(fieldErrs, FieldError{"key", fieldInvalidName, "key is already " + key})
+	}
+	if len(fieldErrs) > 0 {
+		abortValidation(c, "Key rename has invalid fields", fieldErrs)
+		return
+	}
+
+	numRenamed, err := lph.KeyDelegate.RenameKey(ctx, bundle.ID, key, rename.Key)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	result := MassiveResult{NumSuccessfull: numRenamed}
+	setAuditResult(c, result)
+	c.JSON(http.StatusOK, result)
+}
+
+//GetConstraintViolations checks the stored items of a bundle against the constraints of their keys,
+//so violations of items written before a constraint are found too
+func (lph LocalePersistenceHandler) GetConstraintViolations(c *gin.Context) {
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetViolations)
+	defer cancel()
+	bundle, err := lph.getBundle(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	keys, err := lph.KeyDelegate.GetKeys(ctx, bundle.ID, nil)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	report := ConstraintsReport{Bundle: bundle.ID, NumKeys: len(keys), Violations: []LintIssue{}}
+	if len(keys) == 0 {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	byKey := make(map[string]KeyMetadata, len(keys))
+	for _, km := range keys {
+		byKey[km.Key] = km
+	}
+	items, err := lph.PersistenceDelegate.GetLocaleItems(ctx, "", bundle.ID, "", "", "", 0, 0)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	for _, item := range items {
+		km, ok := byKey[item.Key]
+		if !ok {
+			continue
+		}
+		report.NumChecked++
+		for _, fe := range checkConstraints(item, km) {
+			report.Violations = append(report.Violations, LintIssue{ID: item.ID, Key: item.Key, Lang: item.Lang, FieldError: fe})
+		}
+	}
+
+	c.JSON(http.StatusOK, report)
+}
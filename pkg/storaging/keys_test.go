@@ -0,0 +1,94 @@
+package storaging
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckKeyMetadata(t *testing.T) {
+	metadata := KeyMetadata{Bundle: "label", Key: "SAVE", MaxLength: 10}
+	assert.Empty(t, checkKeyMetadata(&metadata))
+	assert.Equal(t, lengthUnitChars, metadata.LengthUnit)
+	assert.Equal(t, charsetAny, metadata.Charset)
+
+	metadata = KeyMetadata{Bundle: "label", Key: "SAVE", MaxLength: -1, LengthUnit: "bytes", Charset: "utf7"}
+	assert.Equal(t, []FieldError{
+		{"max_length", fieldInvalidName, "max_length must be from 0, no limit, to 4096"},
+		{"length_unit", fieldInvalidName, "length_unit must be chars or graphemes"},
+		{"charset", fieldInvalidName, "charset must be any, ascii, latin1, gsm7 or bmp"},
+	}, checkKeyMetadata(&metadata))
+}
+
+func TestCheckConstraints(t *testing.T) {
+	chars := KeyMetadata{Key: "HELLO", MaxLength: 5, LengthUnit: lengthUnitChars, Charset: charsetAny, Multiline: true}
+	graphemes := chars
+	graphemes.LengthUnit = lengthUnitGraphemes
+
+	//5 graphemes in 7 chars: a flag is 2 regional indicators, é is e and a combining accent
+	content := "\U0001F1EE\U0001F1F9 héy"
+	assert.Empty(t, checkConstraints(LocaleItem{Key: "HELLO", Content: content}, graphemes))
+	assert.Equal(t, []FieldError{{"content", fieldTooLong, "content is 7 chars, max of key HELLO is 5"}},
+		checkConstraints(LocaleItem{Key: "HELLO", Content: content}, chars))
+
+	sms := KeyMetadata{Key: "SMS", LengthUnit: lengthUnitChars, Charset: charsetGSM7}
+	assert.Empty(t, checkConstraints(LocaleItem{Key: "SMS", Content: "Ciao! Costa 5€ [più] IVA"}, sms))
+	assert.Equal(t, []FieldError{
+		{"content", fieldSingleLine, "content has more lines, key SMS is single line"},
+		{"content", fieldInvalidChar, `content has char 'ç' (U+00E7) at byte 4, not in charset gsm7 of key SMS`},
+		{"plurals.other", fieldInvalidChar, `plurals.other has char '“' (U+201C) at byte 0, not in charset gsm7 of key SMS`},
+	}, checkConstraints(LocaleItem{Key: "SMS", Content: "Gar\nçon", Plurals: PluralForms{"one": "1 SMS", "other": "“{n}” SMS"}}, sms))
+
+	assert.True(t, charsetAllows[charsetBMP]('€'))
+	assert.False(t, charsetAllows[charsetBMP]('\U0001F600'))
+	assert.False(t, charsetAllows[charsetLatin1]('€'))
+}
+
+func TestValidateConstraints(t *testing.T) {
+	v := &itemValidator{keyPatterns: map[string]*regexp.Regexp{}}
+	rules := writeRules{keys: map[itemKey]KeyMetadata{
+		{"label", "OK"}: {Bundle: "label", Key: "OK", MaxLength: 2, LengthUnit: lengthUnitChars, Charset: charsetASCII, Multiline: true},
+	}}
+
+	assert.Empty(t, v.validate(LocaleItem{Key: "OK", Bundle: "label", Lang: "it-IT", Content: "Sì"}, writeRules{}))
+	assert.Equal(t, []FieldError{{"content", fieldInvalidChar, `content has char 'ì' (U+00EC) at byte 1, not in charset ascii of key OK`}},
+		v.validate(LocaleItem{Key: "OK", Bundle: "label", Lang: "it-IT", Content: "Sì"}, rules))
+	assert.Empty(t, v.validate(LocaleItem{Key: "OK", Bundle: "other", Lang: "it-IT", Content: "Va bene"}, rules))
+}
+
+func TestCheckTags(t *testing.T) {
+	tags := []string{" checkout ", "", "button", "checkout", "a,b"}
+	assert.Equal(t, []FieldError{{"tags", fieldInvalidName, `tag "a,b" must not contain ','`}}, checkTags(&tags))
+	assert.Equal(t, []string{"checkout", "button", "a,b"}, tags)
+
+	tags = make([]string, maxTags+1)
+	for i := range tags {
+		tags[i] = fmt.Sprint("tag", i)
+	}
+	assert.Equal(t, []FieldError{{"tags", fieldTooLong, "tags are 33, max is 32"}}, checkTags(&tags))
+}
+
+func TestCheckKey(t *testing.T) {
+	v := &itemValidator{keyPatterns: map[string]*regexp.Regexp{"*": regexp.MustCompile(`^[A-Z_]+$`)}}
+
+	errs, err := v.checkKey(Bundle{ID: "label"}, "SAVE_ALL")
+	assert.NoError(t, err)
+	assert.Empty(t, errs)
+
+	errs, err = v.checkKey(Bundle{ID: "label"}, "save")
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldError{{"key", fieldPatternMatch, "key must match ^[A-Z_]+$ in bundle label"}}, errs)
+
+	errs, err = v.checkKey(Bundle{ID: "label", KeyPattern: `^@[a-z]+@$`}, "@save@")
+	assert.NoError(t, err)
+	assert.Empty(t, errs)
+
+	errs, err = v.checkKey(Bundle{ID: "label"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldError{{"key", fieldRequired, "key is required"}}, errs)
+
+	_, err = v.checkKey(Bundle{ID: "label", KeyPattern: `(`}, "SAVE")
+	assert.Error(t, err)
+}
@@ -0,0 +1,269 @@
+package storaging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/authorizating"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+//Language rappresents a locale of the registry, only enabled ones accept writes
+type Language struct {
+	Tag              string   `json:"tag"`
+	DisplayName      string   `json:"display_name"`
+	Direction        string   `json:"direction"`
+	PluralCategories []string `json:"plural_categories"`
+	ParentTag        string   `json:"parent_tag"`
+	Enabled          bool     `json:"enabled"`
+}
+
+//LanguagePatch rappresents changes to a language, nil fields are left as they are
+type LanguagePatch struct {
+	DisplayName      *string   `json:"display_name"`
+	Direction        *string   `json:"direction"`
+	PluralCategories *[]string `json:"plural_categories"`
+	ParentTag        *string   `json:"parent_tag"`
+	Enabled          *bool     `json:"enabled"`
+}
+
+//LanguageQueryParams rappresents filters for the registry
+type LanguageQueryParams struct {
+	Enabled *bool `form:"enabled"`
+}
+
+//LanguagePersistencer interface for languages registry persistence
+type LanguagePersistencer interface {
+	GetLanguages(ctx context.Context, enabled *bool) ([]Language, error)
+	GetLanguage(ctx context.Context, tag string) (*Language, error)
+	PostLanguage(ctx context.Context, lang Language) (*Language, error)
+	PatchLanguage(ctx context.Context, tag string, patch LanguagePatch) (*Language, error)
+	DeleteLanguage(ctx context.Context, tag string) error
+}
+
+//newLanguage return the language for tag with defaults from CLDR for every field not set in candidate
+func newLanguage(candidate Language) (Language, []FieldError) {
+	if errs := checkNotPseudo("tag", candidate.Tag); len(errs) > 0 {
+		return candidate, errs
+	}
+	t, err := language.Parse(candidate.Tag)
+	if err != nil {
+		return candidate, []FieldError{{"tag", fieldInvalidLang, fmt.Sprintf("tag %q is not a valid BCP 47 tag", candidate.Tag)}}
+	}
+
+	lang := candidate
+	lang.Tag = t.String()
+	if lang.DisplayName == "" {
+		lang.DisplayName = localizing.DisplayName(t)
+	}
+	if lang.Direction == "" {
+		lang.Direction = localizing.Direction(t)
+	}
+	if len(lang.PluralCategories) == 0 {
+		lang.PluralCategories = localizing.PluralCategories(t)
+	}
+	if lang.ParentTag == "" {
+		lang.ParentTag = localizing.Parent(t)
+	}
+
+	return lang, checkLanguage(&lang.DisplayName, &lang.Direction, &lang.PluralCategories, &lang.ParentTag)
+}
+
+//checkLanguage checks fields of a language, nil ones are not checked; parent tag is canonicalized
+func checkLanguage(displayName, direction *string, pluralCategories *[]string, parentTag *string) []FieldError {
+	errs := []FieldError{}
+	if displayName != nil {
+		errs = append(errs, checkText("display_name", *displayName, maxBundleLength, false)...)
+	}
+	if direction != nil && *direction != localizing.DirectionLTR && *direction != localizing.DirectionRTL {
+		errs = append(errs, FieldError{"direction", fieldInvalidName, "direction must be ltr or rtl"})
+	}
+	if pluralCategories != nil {
+		errs = append(errs, checkPluralCategories(*pluralCategories)...)
+	}
+	if parentTag != nil && *parentTag != "" {
+		canonical, err := localizing.Canonicalize(*parentTag)
+		if err != nil {
+			errs = append(errs, FieldError{"parent_tag", fieldInvalidLang, fmt.Sprintf("parent_tag %q is not a valid BCP 47 tag", *parentTag)})
+		} else {
+			*parentTag = canonical
+		}
+	}
+	return errs
+}
+
+func checkPluralCategories(categories []string) []FieldError {
+	known := map[string]bool{
+		localizing.PluralZero: true, localizing.PluralOne: true, localizing.PluralTwo: true,
+		localizing.PluralFew: true, localizing.PluralMany: true, localizing.PluralOther: true,
+	}
+	hasOther := false
+	for _, category := range categories {
+		if !known[category] {
+			return []FieldError{{"plural_categories", fieldInvalidName, fmt.Sprintf("unknown plural category %q", category)}}
+		}
+		hasOther = hasOther || category == localizing.PluralOther
+	}
+	if !hasOther {
+		return []FieldError{{"plural_categories", fieldRequired, "plural_categories must contain other"}}
+	}
+	return nil
+}
+
+//abortValidation answers with a validation_failed problem listing invalid fields
+func abortValidation(c *gin.Context, detail string, errs []FieldError) {
+	p := problem.New(http.StatusBadRequest, problem.CodeValidationFailed, detail)
+	p.Errors = errs
+	problem.AbortWith(c, p)
+}
+
+//requireAdmin aborts with forbidden when the user has not the admin role
+func requireAdmin(c *gin.Context, action string) bool {
+	if authorizating.HasRole(c, authorizating.RoleAdmin) {
+		return true
+	}
+	problem.Abort(c, http.StatusForbidden, problem.CodeForbidden, fmt.Sprintf("%s needs %s role", action, authorizating.RoleAdmin))
+	return false
+}
+
+//enabledLanguages return the enabled languages by tag
+func (lph LocalePersistenceHandler) enabledLanguages(ctx context.Context) (map[string]Language, error) {
+	enabled := true
+	languages, err := lph.LanguageDelegate.GetLanguages(ctx, &enabled)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]Language, len(languages))
+	for _, l := range languages {
+		result[l.Tag] = l
+	}
+	return result, nil
+}
+
+//GetLanguages return the registry, filtered by enabled
+func (lph LocalePersistenceHandler) GetLanguages(c *gin.Context) {
+	var languageQueryParams LanguageQueryParams
+	err := c.ShouldBindQuery(&languageQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetLanguages)
+	defer cancel()
+	languages, err := lph.LanguageDelegate.GetLanguages(ctx, languageQueryParams.Enabled)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, languages)
+}
+
+//GetLanguage return one language of the registry, tag is canonicalized
+func (lph LocalePersistenceHandler) GetLanguage(c *gin.Context) {
+	tag := localizing.CanonicalizeOrKeep(c.Param("tag"))
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetLanguage)
+	defer cancel()
+	lang, err := lph.LanguageDelegate.GetLanguage(ctx, tag)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lang)
+}
+
+//PostLanguage adds a language to the registry, missing fields are filled from CLDR
+func (lph LocalePersistenceHandler) PostLanguage(c *gin.Context) {
+	candidate := Language{Enabled: true}
+	err := c.ShouldBindJSON(&candidate)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	setAuditFilters(c, "", candidate.Tag, "")
+
+	if !requireAdmin(c, "Change of languages") {
+		return
+	}
+
+	lang, fieldErrs := newLanguage(candidate)
+	if len(fieldErrs) > 0 {
+		abortValidation(c, "Language has invalid fields", fieldErrs)
+		return
+	}
+	setAuditFilters(c, "", lang.Tag, "")
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPostLanguage)
+	defer cancel()
+	created, err := lph.LanguageDelegate.PostLanguage(ctx, lang)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.JSON(http.StatusCreated, created)
+}
+
+//PatchLanguage changes a language of the registry, disabling it stops writes for it
+func (lph LocalePersistenceHandler) PatchLanguage(c *gin.Context) {
+	tag := localizing.CanonicalizeOrKeep(c.Param("tag"))
+	setAuditFilters(c, "", tag, "")
+
+	var patch LanguagePatch
+	err := c.ShouldBindJSON(&patch)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if !requireAdmin(c, "Change of languages") {
+		return
+	}
+
+	if fieldErrs := checkLanguage(patch.DisplayName, patch.Direction, patch.PluralCategories, patch.ParentTag); len(fieldErrs) > 0 {
+		abortValidation(c, "Language has invalid fields", fieldErrs)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPatchLanguage)
+	defer cancel()
+	lang, err := lph.LanguageDelegate.PatchLanguage(ctx, tag, patch)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.JSON(http.StatusOK, lang)
+}
+
+//DeleteLanguage removes a language not used by any item, trashed ones included
+func (lph LocalePersistenceHandler) DeleteLanguage(c *gin.Context) {
+	tag := localizing.CanonicalizeOrKeep(c.Param("tag"))
+	setAuditFilters(c, "", tag, "")
+
+	if !requireAdmin(c, "Change of languages") {
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opDeleteLanguage)
+	defer cancel()
+	err := lph.LanguageDelegate.DeleteLanguage(ctx, tag)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,30 @@
+package storaging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLanguage(t *testing.T) {
+	lang, errs := newLanguage(Language{Tag: "ar_eg", Enabled: true})
+	assert.Empty(t, errs)
+	assert.Equal(t, Language{
+		Tag:              "ar-EG",
+		DisplayName:      "العربية",
+		Direction:        "rtl",
+		PluralCategories: []string{"zero", "one", "two", "few", "many", "other"},
+		ParentTag:        "ar",
+		Enabled:          true,
+	}, lang)
+
+	lang, errs = newLanguage(Language{Tag: "it", DisplayName: "Italiano", PluralCategories: []string{"one", "other"}})
+	assert.Empty(t, errs)
+	assert.Equal(t, "Italiano", lang.DisplayName)
+
+	_, errs = newLanguage(Language{Tag: "it", Direction: "up", PluralCategories: []string{"one"}})
+	assert.Equal(t, []string{"direction", "plural_categories"}, []string{errs[0].Field, errs[1].Field})
+
+	_, errs = newLanguage(Language{Tag: "english!"})
+	assert.Equal(t, fieldInvalidLang, errs[0].Code)
+}
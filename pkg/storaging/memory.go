@@ -0,0 +1,349 @@
+package storaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/exchanging"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+const (
+	//defaultMatchThreshold is the min similarity of source texts of suggestions when threshold is not set
+	defaultMatchThreshold = 0.7
+	//maxImportSize is the max size in bytes of an imported TMX document
+	maxImportSize = 32 << 20
+	//memoryOriginItems is the origin of units from stored items, the same of the source lang item of a key
+	memoryOriginItems = "localeitems"
+	//memoryOriginTMX is the origin of imported units, followed by the creation tool of the document
+	memoryOriginTMX = "tmx"
+	//props of TMX units with bundle and key of the items
+	propBundle = "x-bundle"
+	propKey    = "x-key"
+)
+
+//MemoryUnit rappresents a source text with its translation in a target lang
+type MemoryUnit struct {
+	SourceLang string `json:"source_lang"`
+	SourceText string `json:"source_text"`
+	TargetLang string `json:"target_lang"`
+	TargetText string `json:"target_text"`
+	Bundle     string `json:"bundle,omitempty"`
+	Key        string `json:"key,omitempty"`
+	Origin     string `json:"origin"`
+}
+
+//MemorySuggestion rappresents a translation suggested for a text, Match is the similarity percentage of source texts
+type MemorySuggestion struct {
+	MemoryUnit
+	Match int `json:"match"`
+}
+
+//SuggestionQueryParams rappresents the text in SourceLang to translate, all target langs when TargetLang is not set
+type SuggestionQueryParams struct {
+	Text          string  `form:"text"`
+	SourceLang    string  `form:"source_lang"`
+	TargetLang    string  `form:"target_lang"`
+	Threshold     float64 `form:"threshold"`
+	Limit         int     `form:"limit"`
+	excludeBundle string
+	excludeKey    string
+}
+
+//MemoryExportParams rappresents filters of exported units
+type MemoryExportParams struct {
+	SourceLang string `form:"source_lang"`
+	TargetLang string `form:"target_lang"`
+	Bundle     string `form:"bundle"`
+}
+
+//MemoryPersistencer interface for translation memory persistence
+type MemoryPersistencer interface {
+	GetSuggestions(ctx context.Context, params SuggestionQueryParams) ([]MemorySuggestion, error)
+	GetMemoryUnits(ctx context.Context, params MemoryExportParams) ([]MemoryUnit, error)
+	PostMemoryUnits(ctx context.Context, units []MemoryUnit) (int64, error)
+}
+
+//checkSourceLang return an error when lang is missing or not a valid BCP 47 tag
+func checkSourceLang(field, lang string) []FieldError {
+	if errs := checkText(field, lang, maxLangLength, false); len(errs) > 0 {
+		return errs
+	}
+	if _, err := language.Parse(lang); err != nil {
+		return []FieldError{{field, fieldInvalidLang, fmt.Sprintf("%s %q is not a valid BCP 47 tag", field, lang)}}
+	}
+	return nil
+}
+
+//checkSuggestions return invalid fields of params and sets defaults of threshold and limit
+func checkSuggestions(params *SuggestionQueryParams) []FieldError {
+	errs := checkText("text", params.Text, maxContentLength, true)
+	if params.Text == "" {
+		errs = append(errs, FieldError{"text", fieldRequired, "text is required"})
+	}
+	errs = append(errs, checkSourceLang("source_lang", params.SourceLang)...)
+	errs = append(errs, checkThreshold(&params.Threshold, defaultMatchThreshold)...)
+
+	if params.Limit <= 0 {
+		params.Limit = defaultSimilarLimit
+	} else if params.Limit > maxSearchLimit {
+		errs = append(errs, FieldError{"limit", fieldTooLong, fmt.Sprintf("limit is %d, max is %d", params.Limit, maxSearchLimit)})
+	}
+
+	return errs
+}
+
+//unitsOfMemory return a unit for every variant of a TMX unit other than the source one and errors by index of TMX unit;
+//the source variant is the one in srclang of the unit, the first one when any lang can be the source
+func unitsOfMemory(memory *exchanging.Memory) ([]MemoryUnit, []ItemError) {
+	units := []MemoryUnit{}
+	errs := []ItemError{}
+	origin := memoryOriginTMX
+	if memory.CreationTool != "" {
+		origin += ":" + memory.CreationTool
+	}
+
+	for i, tu := range memory.Units {
+		variants := []exchanging.Variant{}
+		valid := true
+		for _, variant := range tu.Variants {
+			variant.Lang = localizing.CanonicalizeOrKeep(variant.Lang)
+			fieldErrs := checkSourceLang("xml:lang", variant.Lang)
+			fieldErrs = append(fieldErrs, checkText("seg", variant.Text, maxContentLength, true)...)
+			for _, fe := range fieldErrs {
+				errs = append(errs, ItemError{i, fe})
+				valid = false
+			}
+			variants = append(variants, variant)
+		}
+		if !valid || len(variants) == 0 {
+			continue
+		}
+
+		source := -1
+		sourceLang := localizing.CanonicalizeOrKeep(tu.SourceLang)
+		for j, variant := range variants {
+			if sourceLang == "" || variant.Lang == sourceLang {
+				source = j
+				break
+			}
+		}
+		if source < 0 {
+			errs = append(errs, ItemError{i, FieldError{"srclang", fieldRequired, fmt.Sprintf("unit has no variant in source lang %s", sourceLang)}})
+			continue
+		}
+		if variants[source].Text == "" {
+			continue
+		}
+
+		for j, variant := range variants {
+			if j == source || variant.Lang == variants[source].Lang || variant.Text == "" {
+				continue
+			}
+			units = append(units, MemoryUnit{
+				SourceLang: variants[source].Lang,
+				SourceText: variants[source].Text,
+				TargetLang: variant.Lang,
+				TargetText: variant.Text,
+				Bundle:     tu.Props[propBundle],
+				Key:        tu.Props[propKey],
+				Origin:     origin,
+			})
+		}
+	}
+
+	return units, errs
+}
+
+//memoryOfUnits return a TMX memory with a unit for every source text of a key, with all its translations
+func memoryOfUnits(sourceLang string, units []MemoryUnit) exchanging.Memory {
+	type unitKey struct{ bundle, key, text string }
+	memory := exchanging.Memory{SourceLang: sourceLang}
+	indexes := map[unitKey]int{}
+
+	for _, unit := range units {
+		uk := unitKey{unit.Bundle, unit.Key, unit.SourceText}
+		i, ok := indexes[uk]
+		if !ok {
+			i = len(memory.Units)
+			indexes[uk] = i
+			tu := exchanging.Unit{
+				ID:         fmt.Sprint(i + 1),
+				SourceLang: unit.SourceLang,
+				Props:      map[string]string{},
+				Variants:   []exchanging.Variant{{Lang: unit.SourceLang, Text: unit.SourceText}},
+			}
+			if unit.Key != "" {
+				tu.ID = unit.Bundle + "/" + unit.Key
+				tu.Props[propBundle] = unit.Bundle
+				tu.Props[propKey] = unit.Key
+			}
+			memory.Units = append(memory.Units, tu)
+		}
+		memory.Units[i].Variants = append(memory.Units[i].Variants, exchanging.Variant{Lang: unit.TargetLang, Text: unit.TargetText})
+	}
+
+	for _, tu := range memory.Units {
+		targets := tu.Variants[1:]
+		sort.SliceStable(targets, func(i, j int) bool { return targets[i].Lang < targets[j].Lang })
+	}
+	return memory
+}
+
+//GetSuggestions return translations of stored and imported units whose source text matches text, best match first
+func (lph LocalePersistenceHandler) GetSuggestions(c *gin.Context) {
+	var suggestionQueryParams SuggestionQueryParams
+	err := c.ShouldBindQuery(&suggestionQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	suggestionQueryParams.SourceLang = localizing.CanonicalizeOrKeep(suggestionQueryParams.SourceLang)
+	suggestionQueryParams.TargetLang = localizing.CanonicalizeOrKeep(suggestionQueryParams.TargetLang)
+	lph.respondSuggestions(c, suggestionQueryParams)
+}
+
+//GetLocaleItemSuggestions return translations suggested for the content of the item, from keys other than its own
+func (lph LocalePersistenceHandler) GetLocaleItemSuggestions(c *gin.Context) {
+	var suggestionQueryParams SuggestionQueryParams
+	err := c.ShouldBindQuery(&suggestionQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetLocaleItem)
+	defer cancel()
+	item, err := lph.PersistenceDelegate.GetLocaleItem(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	if item.Content == "" {
+		c.JSON(http.StatusOK, []MemorySuggestion{})
+		return
+	}
+
+	suggestionQueryParams.Text = item.Content
+	suggestionQueryParams.SourceLang = item.Lang
+	suggestionQueryParams.TargetLang = localizing.CanonicalizeOrKeep(suggestionQueryParams.TargetLang)
+	suggestionQueryParams.excludeBundle = item.Bundle
+	suggestionQueryParams.excludeKey = item.Key
+	lph.respondSuggestions(c, suggestionQueryParams)
+}
+
+func (lph LocalePersistenceHandler) respondSuggestions(c *gin.Context, params SuggestionQueryParams) {
+	if errs := checkSuggestions(&params); len(errs) > 0 {
+		abortValidation(c, "Suggestions have invalid params", errs)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetSuggestions)
+	defer cancel()
+	suggestions, err := lph.MemoryDelegate.GetSuggestions(ctx, params)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+//ImportMemory stores the units of a TMX document, units already in memory are skipped
+func (lph LocalePersistenceHandler) ImportMemory(c *gin.Context) {
+	if !requireAdmin(c, "Import of translation memory") {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	memory, err := exchanging.ReadTMX(c.Request.Body)
+	if err != nil {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeBadRequest, "Error on parsing request: "+err.Error())
+		return
+	}
+
+	units, errs := unitsOfMemory(memory)
+	failed := map[int]bool{}
+	for _, ie := range errs {
+		failed[ie.Index] = true
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opImportMemory)
+	defer cancel()
+	numInserted, err := lph.MemoryDelegate.PostMemoryUnits(ctx, units)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	result := MassiveResult{NumSuccessfull: numInserted, NumFailed: int64(len(failed)), Errors: errs}
+	setAuditResult(c, result)
+	c.JSON(http.StatusOK, result)
+}
+
+//pseudoMemoryUnits return the source lang items of params as units with their pseudo-localization as target
+func (lph LocalePersistenceHandler) pseudoMemoryUnits(ctx context.Context, params MemoryExportParams) ([]MemoryUnit, error) {
+	var sources []LocaleItem
+	targets, err := lph.readItems(ctx, "", params.SourceLang, params.TargetLang, func(lang string) ([]LocaleItem, error) {
+		var err error
+		sources, err = lph.PersistenceDelegate.GetLocaleItems(ctx, "", params.Bundle, lang, "", "", 0, 0)
+		return sources, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	units := make([]MemoryUnit, 0, len(sources))
+	for i, source := range sources {
+		if source.Content == "" {
+			continue
+		}
+		units = append(units, MemoryUnit{SourceLang: source.Lang, SourceText: source.Content, TargetLang: targets[i].Lang, TargetText: targets[i].Content,
+			Bundle: source.Bundle, Key: source.Key, Origin: memoryOriginItems})
+	}
+	return units, nil
+}
+
+//ExportMemory writes stored and imported units from source_lang as a TMX document
+func (lph LocalePersistenceHandler) ExportMemory(c *gin.Context) {
+	var memoryExportParams MemoryExportParams
+	err := c.ShouldBindQuery(&memoryExportParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	memoryExportParams.SourceLang = localizing.CanonicalizeOrKeep(memoryExportParams.SourceLang)
+	memoryExportParams.TargetLang = localizing.CanonicalizeOrKeep(memoryExportParams.TargetLang)
+	if errs := checkSourceLang("source_lang", memoryExportParams.SourceLang); len(errs) > 0 {
+		abortValidation(c, "Export has invalid params", errs)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opExportMemory)
+	defer cancel()
+	var units []MemoryUnit
+	if _, isPseudo := localizing.Pseudo(memoryExportParams.TargetLang); isPseudo {
+		units, err = lph.pseudoMemoryUnits(ctx, memoryExportParams)
+	} else {
+		units, err = lph.MemoryDelegate.GetMemoryUnits(ctx, memoryExportParams)
+	}
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/x-tmx+xml; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="memory-%s.tmx"`, memoryExportParams.SourceLang))
+	c.Status(http.StatusOK)
+	if err = exchanging.WriteTMX(c.Writer, memoryOfUnits(memoryExportParams.SourceLang, units)); err != nil {
+		log.Printf("Error on writing TMX: %v\n", err)
+	}
+}
@@ -0,0 +1,68 @@
+package storaging
+
+import (
+	"testing"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/exchanging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitsOfMemory(t *testing.T) {
+	memory := &exchanging.Memory{
+		SourceLang:   "en-US",
+		CreationTool: "Agency",
+		Units: []exchanging.Unit{
+			{SourceLang: "en_us", Props: map[string]string{propBundle: "label", propKey: "HELLO"},
+				Variants: []exchanging.Variant{{Lang: "it_it", Text: "Ciao"}, {Lang: "en-US", Text: "Hello"}, {Lang: "de-DE", Text: "Hallo"}}},
+			{SourceLang: "en-US", Variants: []exchanging.Variant{{Lang: "it-IT", Text: "Solo italiano"}}},
+			{SourceLang: "en-US", Variants: []exchanging.Variant{{Lang: "en-US", Text: "Bad\x00"}, {Lang: "not a lang", Text: "x"}}},
+			{Variants: []exchanging.Variant{{Lang: "fr", Text: "Bonjour"}, {Lang: "es", Text: "Hola"}}},
+		},
+	}
+
+	units, errs := unitsOfMemory(memory)
+	assert.Equal(t, []MemoryUnit{
+		{"en-US", "Hello", "it-IT", "Ciao", "label", "HELLO", "tmx:Agency"},
+		{"en-US", "Hello", "de-DE", "Hallo", "label", "HELLO", "tmx:Agency"},
+		{"fr", "Bonjour", "es", "Hola", "", "", "tmx:Agency"},
+	}, units)
+
+	codes := []string{}
+	indexes := []int{}
+	for _, ie := range errs {
+		codes = append(codes, ie.Code)
+		indexes = append(indexes, ie.Index)
+	}
+	assert.Equal(t, []string{fieldRequired, fieldControlChar, fieldInvalidLang}, codes)
+	assert.Equal(t, []int{1, 2, 2}, indexes)
+}
+
+func TestMemoryOfUnits(t *testing.T) {
+	memory := memoryOfUnits("en-US", []MemoryUnit{
+		{"en-US", "Hello", "it-IT", "Ciao", "label", "HELLO", memoryOriginItems},
+		{"en-US", "Hello", "de-DE", "Hallo", "label", "HELLO", memoryOriginItems},
+		{"en-US", "Good bye", "it-IT", "Arrivederci", "", "", "tmx"},
+	})
+
+	assert.Equal(t, "en-US", memory.SourceLang)
+	assert.Len(t, memory.Units, 2)
+	assert.Equal(t, "label/HELLO", memory.Units[0].ID)
+	assert.Equal(t, map[string]string{propBundle: "label", propKey: "HELLO"}, memory.Units[0].Props)
+	assert.Equal(t, []exchanging.Variant{{Lang: "en-US", Text: "Hello"}, {Lang: "de-DE", Text: "Hallo"}, {Lang: "it-IT", Text: "Ciao"}}, memory.Units[0].Variants)
+	assert.Equal(t, "2", memory.Units[1].ID)
+	assert.Empty(t, memory.Units[1].Props)
+}
+
+func TestCheckSuggestions(t *testing.T) {
+	params := SuggestionQueryParams{Text: "Hello", SourceLang: "en-US"}
+	assert.Empty(t, checkSuggestions(&params))
+	assert.Equal(t, defaultMatchThreshold, params.Threshold)
+	assert.Equal(t, defaultSimilarLimit, params.Limit)
+
+	params = SuggestionQueryParams{SourceLang: "english!", Threshold: 2}
+	codes := []string{}
+	for _, fe := range checkSuggestions(&params) {
+		codes = append(codes, fe.Code)
+	}
+	assert.Equal(t, []string{fieldRequired, fieldInvalidLang, fieldInvalidName}, codes)
+}
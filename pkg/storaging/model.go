@@ -1,20 +1,21 @@
 package storaging
 
 import (
+	"context"
 	"time"
 )
 
 //LocaleItem rappresents the item used for rappresent content in UI for every locale
 type LocaleItem struct {
-	ID      string `json:"id"`
-	Key     string `json:"key"`
-	Bundle  string `json:"bundle"`
-	Lang    string `json:"lang"`
-	Content string `json:"content"`
-}
-
-func (li LocaleItem) isValid() bool {
-	return li.Key != "" && li.Bundle != "" && li.Lang != ""
+	ID      string      `json:"id"`
+	Key     string      `json:"key"`
+	Bundle  string      `json:"bundle"`
+	Lang    string      `json:"lang"`
+	Content string      `json:"content"`
+	Plurals PluralForms `json:"plurals,omitempty"`
+	//MachineTranslated and NeedsReview are set by machine translation, a write without them clears them
+	MachineTranslated bool `json:"machine_translated"`
+	NeedsReview       bool `json:"needs_review"`
 }
 
 //LocaleItemHistory rappresents history traking for locale items
@@ -25,31 +26,44 @@ type LocaleItemHistory struct {
 	ModificationDate time.Time
 }
 
-//ErrorMessage rappresents error message
-type ErrorMessage struct {
-	Message string
+//LocaleItemResult rappresents a written locale item with the warnings of its checks
+type LocaleItemResult struct {
+	LocaleItem
+	Warnings []FieldError `json:"warnings,omitempty"`
 }
 
 type MassiveResult struct {
-	NumSuccessfull int64 `json:"num_successful"`
-	NumFailed      int64 `json:"num_failed"`
+	NumSuccessfull int64       `json:"num_successful"`
+	NumFailed      int64       `json:"num_failed"`
+	Errors         []ItemError `json:"errors,omitempty"`
+	Warnings       []ItemError `json:"warnings,omitempty"`
 }
 
 type LocaleItemQueryParams struct {
 	Lang    string `json:"lang"`
 	Content string `json:"content"`
 	Key     string `json:"key"`
+	Tag     string `json:"tag"`
 	Offset  int    `json:"offset"`
 	Limit   int    `json:"limit"`
 }
 
+//DeleteQueryParams rappresents filters and confirmation for delete of locale items
+type DeleteQueryParams struct {
+	Lang              string `form:"lang"`
+	Key               string `form:"key"`
+	DryRun            bool   `form:"dry_run"`
+	ConfirmationToken string `form:"confirmation_token"`
+}
+
 //LocalePersistencer interface for persistence service
 type LocalePersistencer interface {
-	PostLocaleItem(item LocaleItem) (*LocaleItem, error)
-	PostLocaleItems(items []LocaleItem) (int64, error)
-	GetLocaleItem(id string) (*LocaleItem, error)
-	GetLocaleItems(key, bundle, lang, content string, limit, offset int) ([]LocaleItem, error)
-	DeleteLocaleItems(key, bundle, lang string) (int64, error)
-	GetLangs(bundle string) ([]string, error)
-	GetBundles() ([]string, error)
+	PostLocaleItem(ctx context.Context, item LocaleItem) (*LocaleItem, error)
+	PostLocaleItems(ctx context.Context, items []LocaleItem) (int64, error)
+	GetLocaleItem(ctx context.Context, id string) (*LocaleItem, error)
+	GetLocaleItems(ctx context.Context, key, bundle, lang, content, tag string, limit, offset int) ([]LocaleItem, error)
+	GetLocaleItemsByKeys(ctx context.Context, bundle, lang string, keys []string) ([]LocaleItem, error)
+	CountLocaleItems(ctx context.Context, key, bundle, lang string) (int64, error)
+	DeleteLocaleItems(ctx context.Context, key, bundle, lang string) (int64, error)
+	GetLangs(ctx context.Context, bundle string) ([]string, error)
 }
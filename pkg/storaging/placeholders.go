@@ -0,0 +1,122 @@
+package storaging
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/formatting"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/gin-gonic/gin"
+)
+
+//codes of placeholder issues, as errors or warnings
+const (
+	fieldPlaceholderMissing   = "placeholder_missing"
+	fieldPlaceholderExtra     = "placeholder_extra"
+	fieldPlaceholderReordered = "placeholder_reordered"
+)
+
+//LintQueryParams rappresents filters of the placeholder lint
+type LintQueryParams struct {
+	Lang string `form:"lang"`
+}
+
+//LintIssue rappresents a placeholder issue of a translation
+type LintIssue struct {
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Lang string `json:"lang"`
+	FieldError
+}
+
+//LintReport rappresents the placeholder issues of every translation of a bundle
+type LintReport struct {
+	Bundle         string      `json:"bundle"`
+	SourceLang     string      `json:"source_lang"`
+	NumChecked     int         `json:"num_checked"`
+	MissingSources []string    `json:"missing_sources"`
+	Issues         []LintIssue `json:"issues"`
+}
+
+//placeholderIssues compares placeholders of item content and plural variants with the ones of source
+func placeholderIssues(item, source LocaleItem) []FieldError {
+	errs := []FieldError{}
+	for _, pair := range variantPairs(item, source) {
+		issues := formatting.ComparePlaceholders(formatting.ExtractPlaceholders(pair.source), formatting.ExtractPlaceholders(pair.text))
+		for _, issue := range issues {
+			switch issue.Code {
+			case formatting.IssueMissing:
+				errs = append(errs, FieldError{pair.field, fieldPlaceholderMissing,
+					fmt.Sprintf("placeholder %s of source %s is missing", issue.Placeholder.Text, source.Lang)})
+			case formatting.IssueExtra:
+				errs = append(errs, FieldError{pair.field, fieldPlaceholderExtra,
+					fmt.Sprintf("placeholder %s is not in source %s", issue.Placeholder.Text, source.Lang)})
+			case formatting.IssueReordered:
+				errs = append(errs, FieldError{pair.field, fieldPlaceholderReordered,
+					fmt.Sprintf("placeholder %s is not in the same order as in source %s, use positional ones as %%1$s", issue.Placeholder.Text, source.Lang)})
+			}
+		}
+	}
+	return errs
+}
+
+//LintBundle checks placeholders of every translation of the bundle against its source lang item
+func (lph LocalePersistenceHandler) LintBundle(c *gin.Context) {
+	var lintQueryParams LintQueryParams
+	err := c.ShouldBindQuery(&lintQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+	lang := localizing.CanonicalizeOrKeep(lintQueryParams.Lang)
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opLintBundle)
+	defer cancel()
+	bundle, err := lph.getBundle(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	if bundle.SourceLang == "" {
+		problem.Abort(c, http.StatusConflict, problem.CodeConflict, fmt.Sprintf("Bundle %s has no source_lang to lint against", bundle.ID))
+		return
+	}
+
+	items, err := lph.PersistenceDelegate.GetLocaleItems(ctx, "", bundle.ID, "", "", "", 0, 0)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	sources := map[string]LocaleItem{}
+	for _, item := range items {
+		if item.Lang == bundle.SourceLang {
+			sources[item.Key] = item
+		}
+	}
+
+	report := LintReport{Bundle: bundle.ID, SourceLang: bundle.SourceLang, MissingSources: []string{}, Issues: []LintIssue{}}
+	missing := map[string]bool{}
+	for _, item := range items {
+		if item.Lang == bundle.SourceLang || (lang != "" && item.Lang != lang) {
+			continue
+		}
+
+		source, ok := sources[item.Key]
+		if !ok {
+			if !missing[item.Key] {
+				missing[item.Key] = true
+				report.MissingSources = append(report.MissingSources, item.Key)
+			}
+			continue
+		}
+
+		report.NumChecked++
+		for _, issue := range placeholderIssues(item, source) {
+			report.Issues = append(report.Issues, LintIssue{item.ID, item.Key, item.Lang, issue})
+		}
+	}
+
+	c.JSON(http.StatusOK, report)
+}
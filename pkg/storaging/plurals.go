@@ -0,0 +1,136 @@
+package storaging
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+)
+
+//codes of plural variants errors
+const (
+	fieldPluralMissing = "plural_missing"
+	fieldPluralUnused  = "plural_unused"
+)
+
+//PluralForms rappresents the plural variants of an item by CLDR category, stored as jsonb
+type PluralForms map[string]string
+
+//Value implements driver.Valuer, an item without variants is NULL
+func (pf PluralForms) Value() (driver.Value, error) {
+	if len(pf) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(pf)
+}
+
+//Scan implements sql.Scanner
+func (pf *PluralForms) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*pf = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(value, pf)
+	case string:
+		return json.Unmarshal([]byte(value), pf)
+	default:
+		return fmt.Errorf("cannot scan %T into plurals", src)
+	}
+}
+
+//categories return the categories of the variants in CLDR order, unknown ones at the end
+func (pf PluralForms) categories() []string {
+	order := map[string]int{}
+	for i, category := range []string{localizing.PluralZero, localizing.PluralOne, localizing.PluralTwo,
+		localizing.PluralFew, localizing.PluralMany, localizing.PluralOther} {
+		order[category] = i + 1
+	}
+
+	categories := make([]string, 0, len(pf))
+	for category := range pf {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		oi, oj := order[categories[i]], order[categories[j]]
+		if oi == 0 || oj == 0 {
+			return oi != 0 || (oj == 0 && categories[i] < categories[j])
+		}
+		return oi < oj
+	})
+	return categories
+}
+
+//variantPair rappresents a text of an item, by field, with the text of source it translates
+type variantPair struct {
+	field  string
+	text   string
+	source string
+}
+
+//variantPairs return content of item with content of source, then every plural variant of item with the same variant
+//of source, or its other variant when source does not have it, or its content when source has no variants
+func variantPairs(item, source LocaleItem) []variantPair {
+	pairs := []variantPair{{"content", item.Content, source.Content}}
+	for _, category := range item.Plurals.categories() {
+		sourceText, ok := source.Plurals[category]
+		if !ok {
+			sourceText, ok = source.Plurals[localizing.PluralOther]
+		}
+		if !ok {
+			sourceText = source.Content
+		}
+		pairs = append(pairs, variantPair{"plurals." + category, item.Plurals[category], sourceText})
+	}
+	return pairs
+}
+
+//checkPlurals checks that variants are exactly the plural categories of lang and that each is valid text;
+//categories are checked only when lang is in the registry
+func checkPlurals(item LocaleItem, rules writeRules) []FieldError {
+	if len(item.Plurals) == 0 {
+		return nil
+	}
+
+	errs := []FieldError{}
+	for _, category := range item.Plurals.categories() {
+		for _, fe := range checkText("plurals."+category, item.Plurals[category], maxContentLength, true) {
+			if fe.Code != fieldRequired {
+				errs = append(errs, fe)
+			}
+		}
+	}
+
+	lang, ok := rules.languages[item.Lang]
+	if !ok {
+		return errs
+	}
+
+	expected := map[string]bool{}
+	for _, category := range lang.PluralCategories {
+		expected[category] = true
+		if _, ok := item.Plurals[category]; !ok {
+			errs = append(errs, FieldError{"plurals", fieldPluralMissing,
+				fmt.Sprintf("plural %s is missing, %s needs %s", category, item.Lang, strings.Join(lang.PluralCategories, ", "))})
+		}
+	}
+	for _, category := range item.Plurals.categories() {
+		if !expected[category] {
+			errs = append(errs, FieldError{"plurals", fieldPluralUnused,
+				fmt.Sprintf("plural %s is not used by %s, it needs %s", category, item.Lang, strings.Join(lang.PluralCategories, ", "))})
+		}
+	}
+
+	return errs
+}
+
+//prepareItem canonicalizes lang and, for items with plural variants and no content, uses the other variant as content
+func prepareItem(item *LocaleItem) {
+	item.Lang = localizing.CanonicalizeOrKeep(item.Lang)
+	if item.Content == "" && len(item.Plurals) > 0 {
+		item.Content = item.Plurals[localizing.PluralOther]
+	}
+}
@@ -0,0 +1,78 @@
+package storaging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPlurals(t *testing.T) {
+	v := &itemValidator{}
+	rules := writeRules{
+		languages: map[string]Language{
+			"pl": {PluralCategories: []string{"one", "few", "many", "other"}},
+			"ja": {PluralCategories: []string{"other"}},
+			"en": {PluralCategories: []string{"one", "other"}},
+		},
+		bundles: map[string]Bundle{
+			"web": {ID: "web", MessageFormat: messageFormatICU},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		item  LocaleItem
+		codes []string
+	}{
+		{"polish", LocaleItem{Key: "FILES", Bundle: "app", Lang: "pl", Plurals: PluralForms{"one": "plik", "few": "pliki", "many": "plików", "other": "pliku"}}, nil},
+		{"polish without few and many", LocaleItem{Key: "FILES", Bundle: "app", Lang: "pl", Plurals: PluralForms{"one": "plik", "other": "pliku"}}, []string{fieldPluralMissing, fieldPluralMissing}},
+		{"japanese", LocaleItem{Key: "FILES", Bundle: "app", Lang: "ja", Plurals: PluralForms{"other": "ファイル"}}, nil},
+		{"japanese with one", LocaleItem{Key: "FILES", Bundle: "app", Lang: "ja", Plurals: PluralForms{"one": "ファイル", "other": "ファイル"}}, []string{fieldPluralUnused}},
+		{"unknown category", LocaleItem{Key: "FILES", Bundle: "app", Lang: "en", Plurals: PluralForms{"one": "file", "other": "files", "several": "files"}}, []string{fieldPluralUnused}},
+		{"control char in variant", LocaleItem{Key: "FILES", Bundle: "app", Lang: "en", Plurals: PluralForms{"one": "file\x00", "other": "files"}}, []string{fieldControlChar}},
+		{"icu variant", LocaleItem{Key: "FILES", Bundle: "web", Lang: "en", Plurals: PluralForms{"one": "{n} file", "other": "{n} files"}}, nil},
+		{"icu syntax in variant", LocaleItem{Key: "FILES", Bundle: "web", Lang: "en", Plurals: PluralForms{"one": "{n file", "other": "{n} files"}}, []string{fieldSyntax}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes := []string{}
+			for _, fe := range v.validate(tt.item, rules) {
+				codes = append(codes, fe.Code)
+			}
+			if tt.codes == nil {
+				tt.codes = []string{}
+			}
+			assert.Equal(t, tt.codes, codes)
+		})
+	}
+}
+
+func TestPluralFormsValue(t *testing.T) {
+	value, err := PluralForms{}.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+
+	value, err = PluralForms{"one": "file", "other": "files"}.Value()
+	assert.NoError(t, err)
+
+	var pf PluralForms
+	assert.NoError(t, pf.Scan(value))
+	assert.Equal(t, PluralForms{"one": "file", "other": "files"}, pf)
+	assert.Equal(t, []string{"one", "other"}, pf.categories())
+
+	assert.NoError(t, pf.Scan(nil))
+	assert.Nil(t, pf)
+	assert.Error(t, pf.Scan(42))
+}
+
+func TestPrepareItem(t *testing.T) {
+	item := LocaleItem{Lang: "en_us", Plurals: PluralForms{"one": "file", "other": "files"}}
+	prepareItem(&item)
+	assert.Equal(t, "en-US", item.Lang)
+	assert.Equal(t, "files", item.Content)
+
+	item = LocaleItem{Lang: "en", Content: "{n} files", Plurals: PluralForms{"other": "files"}}
+	prepareItem(&item)
+	assert.Equal(t, "{n} files", item.Content)
+}
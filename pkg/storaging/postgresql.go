@@ -1,24 +1,43 @@
 package storaging
 
 import (
+	"context"
 	"database/sql"
 	"errors"
-	"io/ioutil"
+	"fmt"
 	"log"
 	"os"
 	"strconv"
-	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/migrating"
+	"github.com/lib/pq"
 )
 
+//localeItemColumns are the columns parseResult scans
+const localeItemColumns = "id, bundle, lang, key, content, plurals, machine_translated, needs_review"
+
+//glossaryColumns are the columns scanGlossaryEntry scans
+const glossaryColumns = "id, source_lang, source_term, target_lang, target_term, do_not_translate, note"
+
+//keyColumns are the columns of localekeys in the order scanKeyMetadata reads them
+const keyColumns = "bundle, key, max_length, length_unit, charset, multiline, description, note, tags"
+
+//screenshotColumns are the columns of screenshots in the order scanScreenshot reads them
+const screenshotColumns = "id, bundle, key, content_type, size, blob_path, created_at"
+
+//commentColumns are the columns of comments in the order scanComment reads them
+const commentColumns = "comments.id, comments.localeitem_id, comments.parent_id, comments.author, comments.body, comments.mentions, " +
+	"comments.resolved, comments.resolved_by, comments.resolved_at, comments.created_at"
+
 //LocalePersistenceService manages persistence with db
 type LocalePersistenceService struct {
 	DBDelegate *sql.DB
+	statements *preparedStatements
 }
 
-//NewPostgresPersistenceService return a new persistence service for postgresql db
-func NewPostgresPersistenceService() (*LocalePersistenceService, error) {
+//OpenPostgres return a connection pool to the db at DATABASE_URL
+func OpenPostgres() (*sql.DB, error) {
 	connStr := os.Getenv("DATABASE_URL")
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
@@ -30,177 +49,157 @@ func NewPostgresPersistenceService() (*LocalePersistenceService, error) {
 		return nil, err
 	}
 
-	initStmt, err := ioutil.ReadFile(os.Getenv("SQL_PATH") + "sql/init.sql")
-	_, err = db.Exec(string(initStmt))
+	return db, nil
+}
+
+//NewPostgresPersistenceService return a new persistence service for postgresql db, with schema migrated to last version
+func NewPostgresPersistenceService() (*LocalePersistenceService, error) {
+	db, err := OpenPostgres()
 	if err != nil {
 		return nil, err
 	}
 
-	lps := LocalePersistenceService{db}
-
-	return &lps, nil
-}
-
-//PostLocaleItem implements LocalePersistencer interface with postgresql implementation
-func (lps LocalePersistenceService) PostLocaleItem(item LocaleItem) (*LocaleItem, error) {
-
-	insertStmtStr, err := ioutil.ReadFile(os.Getenv("SQL_PATH") + "sql/upsert.sql")
+	migrator, err := migrating.NewMigrator(db)
 	if err != nil {
 		return nil, err
 	}
 
-	insertStmt, err := lps.DBDelegate.Prepare(string(insertStmtStr))
+	err = migrator.Up(context.Background())
 	if err != nil {
 		return nil, err
 	}
 
-	insertResult := insertStmt.QueryRow(item.Key, item.Bundle, item.Lang, item.Content)
-	insertResult.Scan(&item.ID)
+	statements, err := prepareStatements(db)
 	if err != nil {
 		return nil, err
 	}
 
-	return &item, nil
-}
+	lps := LocalePersistenceService{db, statements}
 
-//PostLocaleItems implements LocalePersistencer interface with postgresql implementation
-func (lps LocalePersistenceService) PostLocaleItems(items []LocaleItem) (int64, error) {
+	return &lps, nil
+}
 
-	insertStmtStr, err := ioutil.ReadFile(os.Getenv("SQL_PATH") + "sql/upsert.sql")
+//PostLocaleItem implements LocalePersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PostLocaleItem(ctx context.Context, item LocaleItem) (*LocaleItem, error) {
+	insertResult := lps.statements.upsertLocaleItem.QueryRowContext(ctx, item.Key, item.Bundle, item.Lang, item.Content, item.Plurals, item.MachineTranslated, item.NeedsReview)
+	err := insertResult.Scan(&item.ID)
 	if err != nil {
-		return 0, err
+		return nil, translateError(err)
 	}
 
-	tx, err := lps.DBDelegate.Begin()
+	return &item, nil
+}
+
+//PostLocaleItems implements LocalePersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PostLocaleItems(ctx context.Context, items []LocaleItem) (int64, error) {
+	tx, err := lps.DBDelegate.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, err
+		return 0, translateError(err)
 	}
 	defer tx.Rollback()
 
-	insertStmt, err := tx.Prepare(string(insertStmtStr))
-	if err != nil {
-		return 0, err
-	}
+	insertStmt := tx.StmtContext(ctx, lps.statements.upsertLocaleItem)
 	defer insertStmt.Close()
 
 	var itemInserted int64 = 0
 	for _, item := range items {
-		if item.isValid() {
-			if _, err = insertStmt.Exec(item.Key, item.Bundle, item.Lang, item.Content); err != nil {
-				return 0, err
-			}
-			itemInserted++
+		if _, err = insertStmt.ExecContext(ctx, item.Key, item.Bundle, item.Lang, item.Content, item.Plurals, item.MachineTranslated, item.NeedsReview); err != nil {
+			return 0, translateError(err)
 		}
+		itemInserted++
 	}
 
 	if err = tx.Commit(); err != nil {
-		return 0, err
+		return 0, translateError(err)
 	}
 
 	return itemInserted, nil
 }
 
-//GetLocaleItem return one localeitem for key, bundle, lang
-func (lps LocalePersistenceService) GetLocaleItems(key, bundle, lang, content string, limit, offset int) ([]LocaleItem, error) {
-	selectStmt := "SELECT id, bundle, lang, key, content FROM localeitems WHERE"
-
-	whereClause, params := evaluateLocaleItemParams(key, bundle, lang, content, limit, offset)
-	selectStmt += whereClause
+//GetLocaleItem return one localeitem for key, bundle, lang; with tag only items of keys with that tag
+func (lps LocalePersistenceService) GetLocaleItems(ctx context.Context, key, bundle, lang, content, tag string, limit, offset int) ([]LocaleItem, error) {
+	qb := localeItemQuery("SELECT "+localeItemColumns+" FROM localeitems", key, bundle, lang, content, false)
+	if tag != "" {
+		qb.where("EXISTS (SELECT 1 FROM localekeys WHERE localekeys.bundle = localeitems.bundle AND localekeys.key = localeitems.key AND localekeys.tags @> ?)",
+			pq.Array([]string{tag}))
+	}
+	selectStmt, params := qb.page(limit, offset).build()
 	log.Println(selectStmt)
-	sqlResult, err := lps.DBDelegate.Query(selectStmt, params...)
+	sqlResult, err := lps.DBDelegate.QueryContext(ctx, selectStmt, params...)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	defer sqlResult.Close()
 
 	items, err := parseResult(sqlResult)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	return items, nil
 }
 
 //GetLocaleItem return one localeitem by key
-func (lps LocalePersistenceService) GetLocaleItem(id string) (*LocaleItem, error) {
-	selectStmt := "SELECT id, bundle, lang, key, content FROM localeitems WHERE id = $1"
-
-	sqlResult, err := lps.DBDelegate.Query(selectStmt, id)
+func (lps LocalePersistenceService) GetLocaleItem(ctx context.Context, id string) (*LocaleItem, error) {
+	sqlResult, err := lps.statements.selectLocaleItem.QueryContext(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 	defer sqlResult.Close()
 
 	items, err := parseResult(sqlResult)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
 
 	if len(items) == 0 {
-		return nil, nil
+		return nil, newError(ErrNotFound, "No item found for id "+id, nil)
 	}
 
 	return &items[0], nil
 }
 
-//DeleteLocaleItem return one localeitem for key, bundle, lang
-func (lps LocalePersistenceService) DeleteLocaleItems(key, bundle, lang string) (int64, error) {
-	deleteStmt := "DELETE FROM localeitems WHERE"
-
-	whereClause, params := evaluateLocaleItemParams(key, bundle, lang, "", 0, 0)
-	deleteStmt += whereClause
-	sqlResult, err := lps.DBDelegate.Exec(deleteStmt, params...)
+//GetLocaleItemsByKeys return localeitems of bundle and lang with one of keys
+func (lps LocalePersistenceService) GetLocaleItemsByKeys(ctx context.Context, bundle, lang string, keys []string) ([]LocaleItem, error) {
+	selectStmt, params := localeItemQuery("SELECT "+localeItemColumns+" FROM localeitems", "", bundle, lang, "", false).
+		where("localeitems.key = ANY(?)", pq.Array(keys)).
+		build()
+	sqlResult, err := lps.DBDelegate.QueryContext(ctx, selectStmt, params...)
 	if err != nil {
-		return 0, err
+		return nil, translateError(err)
 	}
+	defer sqlResult.Close()
 
-	numItemAffected, err := sqlResult.RowsAffected()
+	return parseResult(sqlResult)
+}
+
+//CountLocaleItems return the number of localeitems that DeleteLocaleItems would move to trash
+func (lps LocalePersistenceService) CountLocaleItems(ctx context.Context, key, bundle, lang string) (int64, error) {
+	countStmt, params := deleteQuery("SELECT COUNT(*) FROM localeitems", key, bundle, lang).build()
+
+	var count int64
+	err := lps.DBDelegate.QueryRowContext(ctx, countStmt, params...).Scan(&count)
 	if err != nil {
-		return 0, err
+		return 0, translateError(err)
 	}
 
-	return numItemAffected, nil
+	return count, nil
 }
 
-func evaluateLocaleItemParams(key, bundle, lang, content string, limit, offset int) (string, []interface{}) {
-	placeHolderCounter := 0
-	statement := ""
-	params := []interface{}{}
-	if key != "" {
-		placeHolderCounter++
-		statement += " localeitems.key LIKE $" + strconv.Itoa(placeHolderCounter) + " AND"
-		params = append(params, "%"+key+"%")
-	}
-	if bundle != "" {
-		placeHolderCounter++
-		statement += " localeitems.bundle = $" + strconv.Itoa(placeHolderCounter) + " AND"
-		params = append(params, bundle)
-	}
-	if lang != "" {
-		placeHolderCounter++
-		statement += " localeitems.lang = $" + strconv.Itoa(placeHolderCounter) + " AND"
-		params = append(params, lang)
-	}
-	if content != "" {
-		placeHolderCounter++
-		statement += " localeitems.content LIKE $" + strconv.Itoa(placeHolderCounter) + " AND"
-		params = append(params, "%"+content+"%")
+//DeleteLocaleItem moves to trash localeitems for bundle, lang and exactly key
+func (lps LocalePersistenceService) DeleteLocaleItems(ctx context.Context, key, bundle, lang string) (int64, error) {
+	deleteStmt, params := deleteQuery("UPDATE localeitems SET deleted_at = now()", key, bundle, lang).build()
+	sqlResult, err := lps.DBDelegate.ExecContext(ctx, deleteStmt, params...)
+	if err != nil {
+		return 0, translateError(err)
 	}
 
-	statement = strings.TrimSuffix(statement, " AND")
-
-	if limit != 0 {
-		placeHolderCounter++
-		statement += " LIMIT $" + strconv.Itoa(placeHolderCounter)
-		params = append(params, limit)
-	}
-	if offset != 0 {
-		placeHolderCounter++
-		statement += " OFFSET $" + strconv.Itoa(placeHolderCounter)
-		params = append(params, offset)
+	numItemAffected, err := sqlResult.RowsAffected()
+	if err != nil {
+		return 0, translateError(err)
 	}
 
-	return statement, params
+	return numItemAffected, nil
 }
 
 func parseResult(res *sql.Rows) ([]LocaleItem, error) {
@@ -218,10 +217,13 @@ func parseResult(res *sql.Rows) ([]LocaleItem, error) {
 			&li.Lang,
 			&li.Key,
 			&li.Content,
+			&li.Plurals,
+			&li.MachineTranslated,
+			&li.NeedsReview,
 		)
 
 		if err != nil {
-			return nil, err
+			return nil, translateError(err)
 		}
 
 		result = append(result, li)
@@ -231,22 +233,20 @@ func parseResult(res *sql.Rows) ([]LocaleItem, error) {
 }
 
 //GetLangs return lang for bundle or all in case of bundleId as empty string
-func (lps LocalePersistenceService) GetLangs(bundleId string) ([]string, error) {
+func (lps LocalePersistenceService) GetLangs(ctx context.Context, bundleId string) ([]string, error) {
 	result := []string{}
-	stmtSource := "SELECT DISTINCT(lang) FROM localeitems"
-	if bundleId != "" {
-		stmtSource += " WHERE bundle = '" + bundleId + "'"
-	}
-	rows, err := lps.DBDelegate.Query(stmtSource)
+	stmtSource, params := localeItemQuery("SELECT DISTINCT(lang) FROM localeitems", "", bundleId, "", "", false).build()
+	rows, err := lps.DBDelegate.QueryContext(ctx, stmtSource, params...)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var lang string
 		err = rows.Scan(&lang)
 		if err != nil {
-			return nil, err
+			return nil, translateError(err)
 		}
 
 		result = append(result, lang)
@@ -255,23 +255,1170 @@ func (lps LocalePersistenceService) GetLangs(bundleId string) ([]string, error)
 	return result, nil
 }
 
-//GetBundles return all bundles
-func (lps LocalePersistenceService) GetBundles() ([]string, error) {
-	result := []string{}
-	rows, err := lps.DBDelegate.Query("SELECT DISTINCT(bundle) FROM localeitems")
+//SearchLocaleItems implements SearchPersistencer interface with postgresql implementation, best rank first
+func (lps LocalePersistenceService) SearchLocaleItems(ctx context.Context, params SearchQueryParams) ([]SearchHit, error) {
+	selectStmt, args := searchQuery(`SELECT `+localeItemColumns+`, deleted_at, ts_rank_cd(search_vector, query) AS rank,
+		ts_headline(localeitems_ts_config(lang), coalesce(content, ''), query, 'StartSel=<mark>, StopSel=</mark>, MaxWords=24, MinWords=8, MaxFragments=2')
+		FROM localeitems, LATERAL websearch_to_tsquery(localeitems_ts_config(lang), $1) AS query`, params).
+		build()
+
+	rows, err := lps.DBDelegate.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []SearchHit{}
+	for rows.Next() {
+		var hit SearchHit
+		err = rows.Scan(
+			&hit.ID,
+			&hit.Bundle,
+			&hit.Lang,
+			&hit.Key,
+			&hit.Content,
+			&hit.Plurals,
+			&hit.MachineTranslated,
+			&hit.NeedsReview,
+			&hit.DeletedAt,
+			&hit.Rank,
+			&hit.Snippet,
+		)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, hit)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+//similarityTx return a read only transaction where content % text matches similarity of at least threshold,
+//so the trigram index on content is used
+func (lps LocalePersistenceService) similarityTx(ctx context.Context, threshold float64) (*sql.Tx, error) {
+	tx, err := lps.DBDelegate.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	if _, err = tx.ExecContext(ctx, "SELECT set_config('pg_trgm.similarity_threshold', $1, true)", strconv.FormatFloat(threshold, 'f', -1, 64)); err != nil {
+		tx.Rollback()
+		return nil, translateError(err)
+	}
+	return tx, nil
+}
+
+//GetSimilarLocaleItems implements SimilarityPersistencer interface with postgresql implementation, most similar first
+func (lps LocalePersistenceService) GetSimilarLocaleItems(ctx context.Context, params SimilarQueryParams) ([]SimilarItem, error) {
+	tx, err := lps.similarityTx(ctx, params.Threshold)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
+
+	selectStmt, args := newQuery("SELECT "+localeItemColumns+", similarity(content, $1) AS similarity FROM localeitems", params.Text).
+		where("localeitems.deleted_at IS NULL").
+		where("localeitems.content % $1").
+		equal("localeitems.lang", params.Lang).
+		equal("localeitems.bundle", params.Bundle).
+		order("similarity DESC, localeitems.id").
+		page(params.Limit, 0).
+		build()
+
+	rows, err := tx.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
 
+	result := []SimilarItem{}
 	for rows.Next() {
-		var lang string
-		err = rows.Scan(&lang)
+		var si SimilarItem
+		err = rows.Scan(
+			&si.ID,
+			&si.Bundle,
+			&si.Lang,
+			&si.Key,
+			&si.Content,
+			&si.Plurals,
+			&si.MachineTranslated,
+			&si.NeedsReview,
+			&si.Similarity,
+		)
 		if err != nil {
-			return nil, err
+			return nil, translateError(err)
 		}
 
-		result = append(result, lang)
+		result = append(result, si)
 	}
 
-	return result, nil
+	return result, translateError(rows.Err())
+}
+
+//GetSimilarPairs implements SimilarityPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) GetSimilarPairs(ctx context.Context, bundle, lang string, threshold float64) ([]SimilarPair, error) {
+	tx, err := lps.similarityTx(ctx, threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	selectStmt, args := newQuery(`SELECT a.id, a.key, a.content, b.id, b.key, b.content, similarity(a.content, b.content)
+		FROM localeitems a JOIN localeitems b ON b.bundle = a.bundle AND b.lang = a.lang AND b.id > a.id AND b.content % a.content`).
+		where("a.deleted_at IS NULL").
+		where("b.deleted_at IS NULL").
+		where("a.content <> ''").
+		equal("a.bundle", bundle).
+		equal("a.lang", lang).
+		build()
+
+	rows, err := tx.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []SimilarPair{}
+	for rows.Next() {
+		pair := SimilarPair{A: LocaleItem{Bundle: bundle, Lang: lang}, B: LocaleItem{Bundle: bundle, Lang: lang}}
+		err = rows.Scan(&pair.A.ID, &pair.A.Key, &pair.A.Content, &pair.B.ID, &pair.B.Key, &pair.B.Content, &pair.Similarity)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, pair)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+//GetSuggestions implements MemoryPersistencer interface with postgresql implementation, best match first;
+//units are the stored items of other langs of a key whose source lang item matches and the imported ones
+func (lps LocalePersistenceService) GetSuggestions(ctx context.Context, params SuggestionQueryParams) ([]MemorySuggestion, error) {
+	tx, err := lps.similarityTx(ctx, params.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qb := newQuery(`WITH memory AS (
+			SELECT s.lang AS source_lang, s.content AS source_text, t.lang AS target_lang, t.content AS target_text, s.bundle, s.key, '`+memoryOriginItems+`' AS origin
+			FROM localeitems s JOIN localeitems t ON t.bundle = s.bundle AND t.key = s.key AND t.lang <> s.lang
+			WHERE s.deleted_at IS NULL AND t.deleted_at IS NULL AND NOT s.needs_review AND NOT t.needs_review AND t.content <> '' AND s.lang = $2 AND s.content % $1
+			UNION ALL
+			SELECT source_lang, source_text, target_lang, target_text, bundle, key, origin
+			FROM translation_memory WHERE source_lang = $2 AND source_text % $1
+		)
+		SELECT source_lang, source_text, target_lang, target_text, coalesce(bundle, ''), coalesce(key, ''), origin,
+			round(similarity(source_text, $1) * 100)::integer AS match
+		FROM memory`, params.Text, params.SourceLang).
+		equal("target_lang", params.TargetLang)
+	if params.excludeKey != "" {
+		qb.where("(bundle IS DISTINCT FROM ? OR key IS DISTINCT FROM ?)", params.excludeBundle, params.excludeKey)
+	}
+	selectStmt, args := qb.order("match DESC, target_lang, origin, bundle, key").
+		page(params.Limit, 0).
+		build()
+
+	rows, err := tx.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []MemorySuggestion{}
+	for rows.Next() {
+		var ms MemorySuggestion
+		err = rows.Scan(&ms.SourceLang, &ms.SourceText, &ms.TargetLang, &ms.TargetText, &ms.Bundle, &ms.Key, &ms.Origin, &ms.Match)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, ms)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+//GetMemoryUnits implements MemoryPersistencer interface with postgresql implementation, by bundle and key
+func (lps LocalePersistenceService) GetMemoryUnits(ctx context.Context, params MemoryExportParams) ([]MemoryUnit, error) {
+	selectStmt, args := newQuery(`SELECT source_lang, source_text, target_lang, target_text, coalesce(bundle, ''), coalesce(key, ''), origin
+		FROM (
+			SELECT s.lang AS source_lang, s.content AS source_text, t.lang AS target_lang, t.content AS target_text, s.bundle, s.key, '`+memoryOriginItems+`' AS origin
+			FROM localeitems s JOIN localeitems t ON t.bundle = s.bundle AND t.key = s.key AND t.lang <> s.lang
+			WHERE s.deleted_at IS NULL AND t.deleted_at IS NULL AND NOT s.needs_review AND NOT t.needs_review AND s.content <> '' AND t.content <> '' AND s.lang = $1
+			UNION ALL
+			SELECT source_lang, source_text, target_lang, target_text, bundle, key, origin
+			FROM translation_memory WHERE source_lang = $1
+		) memory`, params.SourceLang).
+		equal("target_lang", params.TargetLang).
+		equal("bundle", params.Bundle).
+		order("bundle NULLS LAST, key NULLS LAST, source_text, target_lang, origin").
+		build()
+
+	rows, err := lps.DBDelegate.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []MemoryUnit{}
+	for rows.Next() {
+		var mu MemoryUnit
+		err = rows.Scan(&mu.SourceLang, &mu.SourceText, &mu.TargetLang, &mu.TargetText, &mu.Bundle, &mu.Key, &mu.Origin)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, mu)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+//PostMemoryUnits implements MemoryPersistencer interface with postgresql implementation, return the num of new units
+func (lps LocalePersistenceService) PostMemoryUnits(ctx context.Context, units []MemoryUnit) (int64, error) {
+	tx, err := lps.DBDelegate.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	defer tx.Rollback()
+
+	insertStmt := tx.StmtContext(ctx, lps.statements.insertMemoryUnit)
+	defer insertStmt.Close()
+
+	var numInserted int64 = 0
+	for _, unit := range units {
+		result, err := insertStmt.ExecContext(ctx, unit.SourceLang, unit.SourceText, unit.TargetLang, unit.TargetText, unit.Bundle, unit.Key, unit.Origin)
+		if err != nil {
+			return 0, translateError(err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, translateError(err)
+		}
+		numInserted += affected
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, translateError(err)
+	}
+
+	return numInserted, nil
+}
+
+//GetGlossaryEntries implements GlossaryPersistencer interface with postgresql implementation, by source term
+func (lps LocalePersistenceService) GetGlossaryEntries(ctx context.Context, params GlossaryQueryParams) ([]GlossaryEntry, error) {
+	selectStmt, args := glossaryQuery("SELECT "+glossaryColumns+" FROM glossary", params).build()
+
+	rows, err := lps.DBDelegate.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []GlossaryEntry{}
+	for rows.Next() {
+		entry, err := scanGlossaryEntry(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, *entry)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+//GetGlossaryEntry implements GlossaryPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) GetGlossaryEntry(ctx context.Context, id string) (*GlossaryEntry, error) {
+	entry, err := scanGlossaryEntry(lps.statements.selectGlossary.QueryRowContext(ctx, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, newError(ErrNotFound, "No glossary entry found for id "+id, err)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return entry, nil
+}
+
+//PostGlossaryEntry implements GlossaryPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PostGlossaryEntry(ctx context.Context, entry GlossaryEntry) (*GlossaryEntry, error) {
+	var id string
+	err := lps.statements.insertGlossary.QueryRowContext(ctx, entry.SourceLang, entry.SourceTerm, entry.TargetLang,
+		entry.TargetTerm, entry.DoNotTranslate, entry.Note).Scan(&id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return nil, newError(ErrConflict, fmt.Sprintf("Term %s from %s to %s already exists", entry.SourceTerm, entry.SourceLang, entry.TargetLang), err)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return lps.GetGlossaryEntry(ctx, id)
+}
+
+//PostGlossaryEntries implements GlossaryPersistencer interface with postgresql implementation, return the num of upserted entries
+func (lps LocalePersistenceService) PostGlossaryEntries(ctx context.Context, entries []GlossaryEntry) (int64, error) {
+	tx, err := lps.DBDelegate.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	defer tx.Rollback()
+
+	upsertStmt := tx.StmtContext(ctx, lps.statements.upsertGlossary)
+	defer upsertStmt.Close()
+
+	var numUpserted int64 = 0
+	for _, entry := range entries {
+		result, err := upsertStmt.ExecContext(ctx, entry.SourceLang, entry.SourceTerm, entry.TargetLang, entry.TargetTerm, entry.DoNotTranslate, entry.Note)
+		if err != nil {
+			return 0, translateError(err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, translateError(err)
+		}
+		numUpserted += affected
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, translateError(err)
+	}
+
+	return numUpserted, nil
+}
+
+//PatchGlossaryEntry implements GlossaryPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PatchGlossaryEntry(ctx context.Context, id string, patch GlossaryEntryPatch) (*GlossaryEntry, error) {
+	sqlResult, err := lps.statements.updateGlossary.ExecContext(ctx, id, patch.TargetTerm, patch.DoNotTranslate, patch.Note)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	numUpdated, err := sqlResult.RowsAffected()
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if numUpdated == 0 {
+		return nil, newError(ErrNotFound, "No glossary entry found for id "+id, nil)
+	}
+
+	return lps.GetGlossaryEntry(ctx, id)
+}
+
+//DeleteGlossaryEntry implements GlossaryPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) DeleteGlossaryEntry(ctx context.Context, id string) error {
+	sqlResult, err := lps.statements.deleteGlossary.ExecContext(ctx, id)
+	if err != nil {
+		return translateError(err)
+	}
+
+	numDeleted, err := sqlResult.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+	if numDeleted == 0 {
+		return newError(ErrNotFound, "No glossary entry found for id "+id, nil)
+	}
+
+	return nil
+}
+
+func scanGlossaryEntry(row rowScanner) (*GlossaryEntry, error) {
+	var entry GlossaryEntry
+	err := row.Scan(
+		&entry.ID,
+		&entry.SourceLang,
+		&entry.SourceTerm,
+		&entry.TargetLang,
+		&entry.TargetTerm,
+		&entry.DoNotTranslate,
+		&entry.Note,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+//GetKeys implements KeyPersistencer interface with postgresql implementation, every key of bundle when keys is nil
+func (lps LocalePersistenceService) GetKeys(ctx context.Context, bundle string, keys []string) ([]KeyMetadata, error) {
+	qb := newQuery("SELECT "+keyColumns+" FROM localekeys").equal("bundle", bundle)
+	if keys != nil {
+		qb.where("key = ANY(?)", pq.Array(keys))
+	}
+	selectStmt, args := qb.order("key").build()
+
+	rows, err := lps.DBDelegate.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []KeyMetadata{}
+	for rows.Next() {
+		metadata, err := scanKeyMetadata(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, *metadata)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+//PutKey implements KeyPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PutKey(ctx context.Context, metadata KeyMetadata) error {
+	_, err := lps.statements.upsertLocaleKey.ExecContext(ctx, metadata.Bundle, metadata.Key, metadata.MaxLength,
+		metadata.LengthUnit, metadata.Charset, metadata.Multiline, metadata.Description, metadata.Note, pq.Array(metadata.Tags))
+	return translateError(err)
+}
+
+//DeleteKey implements KeyPersistencer interface with postgresql implementation,
+//screenshots of the key are removed with it by the foreign key cascade, their blobs are left to the caller
+func (lps LocalePersistenceService) DeleteKey(ctx context.Context, bundle, key string) error {
+	sqlResult, err := lps.statements.deleteLocaleKey.ExecContext(ctx, bundle, key)
+	if err != nil {
+		return translateError(err)
+	}
+
+	numDeleted, err := sqlResult.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+	if numDeleted == 0 {
+		return newError(ErrNotFound, fmt.Sprintf("No metadata found for key %s of bundle %s", key, bundle), nil)
+	}
+
+	return nil
+}
+
+//RenameKey implements KeyPersistencer interface with postgresql implementation, items keep their id so their comments
+//stay with them; it return the num of items renamed, trashed ones included
+func (lps LocalePersistenceService) RenameKey(ctx context.Context, bundle, key, newKey string) (int64, error) {
+	tx, err := lps.DBDelegate.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	defer tx.Rollback()
+
+	var used bool
+	if err = tx.StmtContext(ctx, lps.statements.selectKeyUsed).QueryRowContext(ctx, bundle, newKey).Scan(&used); err != nil {
+		return 0, translateError(err)
+	}
+	//a concurrent write of new key is a unique violation
+	conflict := newError(ErrConflict, fmt.Sprintf("Key %s of bundle %s already exists", newKey, bundle), nil)
+	if used {
+		return 0, conflict
+	}
+
+	var pqErr *pq.Error
+	itemsResult, err := tx.StmtContext(ctx, lps.statements.renameLocaleItems).ExecContext(ctx, bundle, key, newKey)
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return 0, conflict
+	}
+	if err != nil {
+		return 0, translateError(err)
+	}
+	numRenamed, err := itemsResult.RowsAffected()
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	keyResult, err := tx.StmtContext(ctx, lps.statements.renameLocaleKey).ExecContext(ctx, bundle, key, newKey)
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return 0, conflict
+	}
+	if err != nil {
+		return 0, translateError(err)
+	}
+	numKeys, err := keyResult.RowsAffected()
+	if err != nil {
+		return 0, translateError(err)
+	}
+	if numRenamed == 0 && numKeys == 0 {
+		return 0, newError(ErrNotFound, fmt.Sprintf("No items nor metadata found for key %s of bundle %s", key, bundle), nil)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, translateError(err)
+	}
+
+	return numRenamed, nil
+}
+
+func scanKeyMetadata(row rowScanner) (*KeyMetadata, error) {
+	var metadata KeyMetadata
+	err := row.Scan(
+		&metadata.Bundle,
+		&metadata.Key,
+		&metadata.MaxLength,
+		&metadata.LengthUnit,
+		&metadata.Charset,
+		&metadata.Multiline,
+		&metadata.Description,
+		&metadata.Note,
+		pq.Array(&metadata.Tags),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Tags == nil {
+		metadata.Tags = []string{}
+	}
+
+	return &metadata, nil
+}
+
+//GetScreenshots implements ScreenshotPersistencer interface with postgresql implementation, oldest first
+func (lps LocalePersistenceService) GetScreenshots(ctx context.Context, bundle string, keys []string) ([]Screenshot, error) {
+	selectStmt, args := newQuery("SELECT "+screenshotColumns+" FROM screenshots").
+		equal("bundle", bundle).
+		where("key = ANY(?)", pq.Array(keys)).
+		order("key, id").
+		build()
+
+	rows, err := lps.DBDelegate.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []Screenshot{}
+	for rows.Next() {
+		screenshot, err := scanScreenshot(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, *screenshot)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+//GetScreenshot implements ScreenshotPersistencer interface with postgresql implementation, data is nil when it is in blob directory
+func (lps LocalePersistenceService) GetScreenshot(ctx context.Context, bundle, key, id string) (*Screenshot, []byte, error) {
+	var screenshot Screenshot
+	var data []byte
+	err := lps.statements.selectScreenshot.QueryRowContext(ctx, id, bundle, key).Scan(
+		&screenshot.ID,
+		&screenshot.Bundle,
+		&screenshot.Key,
+		&screenshot.ContentType,
+		&screenshot.Size,
+		&screenshot.BlobPath,
+		&screenshot.CreatedAt,
+		&data,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, newError(ErrNotFound, fmt.Sprintf("No screenshot %s found for key %s of bundle %s", id, key, bundle), err)
+	}
+	if err != nil {
+		return nil, nil, translateError(err)
+	}
+	screenshot.URL = screenshotURL(screenshot.Bundle, screenshot.Key, screenshot.ID)
+
+	return &screenshot, data, nil
+}
+
+//PostScreenshot implements ScreenshotPersistencer interface with postgresql implementation,
+//a key without metadata gets the default one
+func (lps LocalePersistenceService) PostScreenshot(ctx context.Context, screenshot Screenshot, data []byte) (*Screenshot, error) {
+	err := lps.statements.insertScreenshot.QueryRowContext(ctx, screenshot.Bundle, screenshot.Key, screenshot.ContentType,
+		screenshot.Size, data, screenshot.BlobPath).Scan(&screenshot.ID, &screenshot.CreatedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	screenshot.URL = screenshotURL(screenshot.Bundle, screenshot.Key, screenshot.ID)
+
+	return &screenshot, nil
+}
+
+//DeleteScreenshot implements ScreenshotPersistencer interface with postgresql implementation, return the removed screenshot
+func (lps LocalePersistenceService) DeleteScreenshot(ctx context.Context, bundle, key, id string) (*Screenshot, error) {
+	screenshot := Screenshot{ID: id, Bundle: bundle, Key: key}
+	err := lps.statements.deleteScreenshot.QueryRowContext(ctx, id, bundle, key).Scan(&screenshot.BlobPath)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, newError(ErrNotFound, fmt.Sprintf("No screenshot %s found for key %s of bundle %s", id, key, bundle), err)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return &screenshot, nil
+}
+
+func scanScreenshot(row rowScanner) (*Screenshot, error) {
+	var screenshot Screenshot
+	err := row.Scan(
+		&screenshot.ID,
+		&screenshot.Bundle,
+		&screenshot.Key,
+		&screenshot.ContentType,
+		&screenshot.Size,
+		&screenshot.BlobPath,
+		&screenshot.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	screenshot.URL = screenshotURL(screenshot.Bundle, screenshot.Key, screenshot.ID)
+
+	return &screenshot, nil
+}
+
+//GetComments implements CommentPersistencer interface with postgresql implementation, oldest first
+func (lps LocalePersistenceService) GetComments(ctx context.Context, localeItemID string) ([]Comment, error) {
+	selectStmt, args := newQuery("SELECT "+commentColumns+" FROM comments").
+		where("comments.localeitem_id = ?", localeItemID).
+		order("comments.id").
+		build()
+
+	rows, err := lps.DBDelegate.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, *comment)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+//GetComment implements CommentPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) GetComment(ctx context.Context, id string) (*Comment, error) {
+	comment, err := scanComment(lps.statements.selectComment.QueryRowContext(ctx, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, newError(ErrNotFound, "No comment found for id "+id, err)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return comment, nil
+}
+
+//PostComment implements CommentPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PostComment(ctx context.Context, comment Comment) (*Comment, error) {
+	var parentID sql.NullString
+	if comment.ParentID != "" {
+		parentID = sql.NullString{String: comment.ParentID, Valid: true}
+	}
+
+	var id string
+	err := lps.statements.insertComment.QueryRowContext(ctx, comment.LocaleItemID, parentID, comment.Author, comment.Body,
+		pq.Array(comment.Mentions)).Scan(&id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return lps.GetComment(ctx, id)
+}
+
+//ResolveComment implements CommentPersistencer interface with postgresql implementation, id must be the first comment of a thread
+func (lps LocalePersistenceService) ResolveComment(ctx context.Context, localeItemID, id string, resolved bool, user string) (*Comment, error) {
+	sqlResult, err := lps.statements.updateCommentResolved.ExecContext(ctx, id, localeItemID, resolved, user)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	numUpdated, err := sqlResult.RowsAffected()
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if numUpdated == 0 {
+		return nil, newError(ErrNotFound, fmt.Sprintf("No thread found for comment %s of locale item %s", id, localeItemID), nil)
+	}
+
+	return lps.GetComment(ctx, id)
+}
+
+//GetOpenQuestions implements CommentPersistencer interface with postgresql implementation, oldest first
+func (lps LocalePersistenceService) GetOpenQuestions(ctx context.Context, params QuestionQueryParams) ([]Question, error) {
+	qb := newQuery("SELECT "+commentColumns+`, localeitems.bundle, localeitems.key, localeitems.lang,
+		(SELECT COUNT(*) FROM comments replies WHERE replies.parent_id = comments.id)
+		FROM comments JOIN localeitems ON localeitems.id = comments.localeitem_id`).
+		where("comments.parent_id IS NULL AND NOT comments.resolved AND localeitems.deleted_at IS NULL").
+		equal("localeitems.bundle", params.Bundle).
+		equal("localeitems.lang", params.Lang).
+		order("comments.created_at, comments.id").
+		page(params.Limit, params.Offset)
+	if params.Mention != "" {
+		mention := pq.Array([]string{params.Mention})
+		qb.where("(comments.mentions @> ? OR EXISTS (SELECT 1 FROM comments replies WHERE replies.parent_id = comments.id AND replies.mentions @> ?))",
+			mention, mention)
+	}
+
+	selectStmt, args := qb.build()
+	rows, err := lps.DBDelegate.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []Question{}
+	for rows.Next() {
+		var question Question
+		var parentID sql.NullString
+		err = rows.Scan(
+			&question.ID,
+			&question.LocaleItemID,
+			&parentID,
+			&question.Author,
+			&question.Body,
+			pq.Array(&question.Mentions),
+			&question.Resolved,
+			&question.ResolvedBy,
+			&question.ResolvedAt,
+			&question.CreatedAt,
+			&question.Bundle,
+			&question.Key,
+			&question.Lang,
+			&question.NumReplies,
+		)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		if question.Mentions == nil {
+			question.Mentions = []string{}
+		}
+
+		result = append(result, question)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+func scanComment(row rowScanner) (*Comment, error) {
+	var comment Comment
+	var parentID sql.NullString
+	err := row.Scan(
+		&comment.ID,
+		&comment.LocaleItemID,
+		&parentID,
+		&comment.Author,
+		&comment.Body,
+		pq.Array(&comment.Mentions),
+		&comment.Resolved,
+		&comment.ResolvedBy,
+		&comment.ResolvedAt,
+		&comment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	comment.ParentID = parentID.String
+	if comment.Mentions == nil {
+		comment.Mentions = []string{}
+	}
+
+	return &comment, nil
+}
+
+//GetTrashedLocaleItems implements TrashPersistencer interface with postgresql implementation, last deleted first
+func (lps LocalePersistenceService) GetTrashedLocaleItems(ctx context.Context, key, bundle, lang string, limit, offset int) ([]TrashItem, error) {
+	selectStmt, params := localeItemQuery("SELECT "+localeItemColumns+", deleted_at FROM localeitems", key, bundle, lang, "", true).
+		order("deleted_at DESC, id").
+		page(limit, offset).
+		build()
+
+	rows, err := lps.DBDelegate.QueryContext(ctx, selectStmt, params...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []TrashItem{}
+	for rows.Next() {
+		var ti TrashItem
+		err = rows.Scan(
+			&ti.ID,
+			&ti.Bundle,
+			&ti.Lang,
+			&ti.Key,
+			&ti.Content,
+			&ti.Plurals,
+			&ti.MachineTranslated,
+			&ti.NeedsReview,
+			&ti.DeletedAt,
+		)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, ti)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+//RestoreLocaleItems implements TrashPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) RestoreLocaleItems(ctx context.Context, params RestoreParams) (int64, error) {
+	qb := localeItemQuery("UPDATE localeitems SET deleted_at = NULL", params.Key, params.Bundle, params.Lang, "", true)
+	if len(params.IDs) > 0 {
+		qb.where("localeitems.id = ANY(?::integer[])", pq.Array(params.IDs))
+	}
+	restoreStmt, args := qb.build()
+
+	sqlResult, err := lps.DBDelegate.ExecContext(ctx, restoreStmt, args...)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	return sqlResult.RowsAffected()
+}
+
+//PurgeTrash implements TrashPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	sqlResult, err := lps.statements.purgeTrash.ExecContext(ctx, deletedBefore)
+	if err != nil {
+		return 0, translateError(err)
+	}
+
+	return sqlResult.RowsAffected()
+}
+
+//PostAuditEntry implements AuditPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PostAuditEntry(ctx context.Context, entry AuditEntry) error {
+	_, err := lps.statements.insertAudit.ExecContext(ctx, entry.User, entry.Method, entry.Route, entry.Bundle, entry.Lang, entry.Key,
+		entry.Status, entry.NumSuccessfull, entry.NumFailed, entry.SourceIP)
+	return translateError(err)
+}
+
+//GetAuditEntries implements AuditPersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) GetAuditEntries(ctx context.Context, params AuditQueryParams) ([]AuditEntry, error) {
+	qb := newQuery(`SELECT id, user_name, method, route, bundle, lang, key, status, num_successful, num_failed, source_ip, created_at
+		FROM audit_log`).
+		equal("user_name", params.User).
+		equal("bundle", params.Bundle).
+		equal("lang", params.Lang).
+		equal("key", params.Key).
+		order("created_at, id").
+		page(params.Limit, params.Offset)
+	if !params.From.IsZero() {
+		qb.where("created_at >= ?", params.From)
+	}
+	if !params.To.IsZero() {
+		qb.where("created_at < ?", params.To)
+	}
+
+	selectStmt, args := qb.build()
+	rows, err := lps.DBDelegate.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		err = rows.Scan(
+			&entry.ID,
+			&entry.User,
+			&entry.Method,
+			&entry.Route,
+			&entry.Bundle,
+			&entry.Lang,
+			&entry.Key,
+			&entry.Status,
+			&entry.NumSuccessfull,
+			&entry.NumFailed,
+			&entry.SourceIP,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, entry)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+//GetLanguages implements LanguagePersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) GetLanguages(ctx context.Context, enabled *bool) ([]Language, error) {
+	qb := newQuery("SELECT tag, display_name, direction, plural_categories, COALESCE(parent_tag, ''), enabled FROM languages").
+		order("tag")
+	if enabled != nil {
+		qb.where("enabled = ?", *enabled)
+	}
+	selectStmt, args := qb.build()
+
+	rows, err := lps.DBDelegate.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []Language{}
+	for rows.Next() {
+		lang, err := scanLanguage(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		result = append(result, *lang)
+	}
+
+	return result, translateError(rows.Err())
+}
+
+//GetLanguage implements LanguagePersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) GetLanguage(ctx context.Context, tag string) (*Language, error) {
+	lang, err := scanLanguage(lps.statements.selectLanguage.QueryRowContext(ctx, tag))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, newError(ErrNotFound, "No language found for tag "+tag, err)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return lang, nil
+}
+
+//PostLanguage implements LanguagePersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PostLanguage(ctx context.Context, lang Language) (*Language, error) {
+	_, err := lps.statements.insertLanguage.ExecContext(ctx, lang.Tag, lang.DisplayName, lang.Direction,
+		pq.Array(lang.PluralCategories), lang.ParentTag, lang.Enabled)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return nil, newError(ErrConflict, "Language "+lang.Tag+" already exists", err)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	return lps.GetLanguage(ctx, lang.Tag)
+}
+
+//PatchLanguage implements LanguagePersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PatchLanguage(ctx context.Context, tag string, patch LanguagePatch) (*Language, error) {
+	var pluralCategories interface{}
+	if patch.PluralCategories != nil {
+		pluralCategories = pq.Array(*patch.PluralCategories)
+	}
+
+	sqlResult, err := lps.statements.updateLanguage.ExecContext(ctx, tag, patch.DisplayName, patch.Direction,
+		pluralCategories, patch.ParentTag, patch.Enabled)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	numUpdated, err := sqlResult.RowsAffected()
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if numUpdated == 0 {
+		return nil, newError(ErrNotFound, "No language found for tag "+tag, nil)
+	}
+
+	return lps.GetLanguage(ctx, tag)
+}
+
+//DeleteLanguage implements LanguagePersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) DeleteLanguage(ctx context.Context, tag string) error {
+	sqlResult, err := lps.statements.deleteLanguage.ExecContext(ctx, tag)
+	if err != nil {
+		return translateError(err)
+	}
+
+	numDeleted, err := sqlResult.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+	if numDeleted > 0 {
+		return nil
+	}
+
+	//nothing deleted: the language is missing or still in use
+	_, err = lps.GetLanguage(ctx, tag)
+	if err != nil {
+		return err
+	}
+	return newError(ErrConflict, "Language "+tag+" is used by locale items, disable it instead", nil)
+}
+
+//rowScanner is implemented by both sql.Row and sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLanguage(row rowScanner) (*Language, error) {
+	var lang Language
+	err := row.Scan(
+		&lang.Tag,
+		&lang.DisplayName,
+		&lang.Direction,
+		pq.Array(&lang.PluralCategories),
+		&lang.ParentTag,
+		&lang.Enabled,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lang, nil
+}
+
+//GetBundles implements BundlePersistencer interface with postgresql implementation,
+//bundles that exist only as items are returned with default settings
+func (lps LocalePersistenceService) GetBundles(ctx context.Context, ids []string, withCounts bool) ([]Bundle, error) {
+	qb := newQuery(`SELECT ids.id, b.id IS NOT NULL, COALESCE(b.description, ''), COALESCE(b.owner, ''), COALESCE(b.source_lang, ''),
+			COALESCE(b.target_langs, '{}'), COALESCE(b.key_pattern, ''), COALESCE(b.fallback_chain, '{}'), COALESCE(b.message_format, 'none'),
+			COALESCE(b.placeholder_check, 'warn')
+		FROM (SELECT id FROM bundles UNION SELECT DISTINCT bundle FROM localeitems WHERE deleted_at IS NULL) ids
+		LEFT JOIN bundles b ON b.id = ids.id`).
+		order("ids.id")
+	if ids != nil {
+		qb.where("ids.id = ANY(?)", pq.Array(ids))
+	}
+	selectStmt, args := qb.build()
+
+	rows, err := lps.DBDelegate.QueryContext(ctx, selectStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	result := []Bundle{}
+	positions := map[string]int{}
+	for rows.Next() {
+		var bundle Bundle
+		err = rows.Scan(
+			&bundle.ID,
+			&bundle.Managed,
+			&bundle.Description,
+			&bundle.Owner,
+			&bundle.SourceLang,
+			pq.Array(&bundle.TargetLangs),
+			&bundle.KeyPattern,
+			pq.Array(&bundle.FallbackChain),
+			&bundle.MessageFormat,
+			&bundle.PlaceholderCheck,
+		)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		bundle.NumItemsByLang = map[string]int64{}
+		positions[bundle.ID] = len(result)
+		result = append(result, bundle)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, translateError(err)
+	}
+
+	if !withCounts {
+		return result, nil
+	}
+
+	qb = newQuery("SELECT bundle, lang, COUNT(*) FROM localeitems").
+		where("deleted_at IS NULL").
+		group("bundle, lang")
+	if ids != nil {
+		qb.where("bundle = ANY(?)", pq.Array(ids))
+	}
+	countStmt, args := qb.build()
+
+	countRows, err := lps.DBDelegate.QueryContext(ctx, countStmt, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer countRows.Close()
+
+	for countRows.Next() {
+		var bundle, lang string
+		var count int64
+		err = countRows.Scan(&bundle, &lang, &count)
+		if err != nil {
+			return nil, translateError(err)
+		}
+
+		if i, ok := positions[bundle]; ok {
+			result[i].NumItemsByLang[lang] = count
+			result[i].NumItems += count
+		}
+	}
+
+	return result, translateError(countRows.Err())
+}
+
+//PostBundle implements BundlePersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PostBundle(ctx context.Context, bundle Bundle) error {
+	_, err := lps.statements.insertBundle.ExecContext(ctx, bundle.ID, bundle.Description, bundle.Owner, bundle.SourceLang,
+		pq.Array(bundle.TargetLangs), bundle.KeyPattern, pq.Array(bundle.FallbackChain), bundle.MessageFormat, bundle.PlaceholderCheck)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return newError(ErrConflict, "Bundle "+bundle.ID+" already has settings", err)
+	}
+
+	return translateError(err)
+}
+
+//PatchBundle implements BundlePersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) PatchBundle(ctx context.Context, id string, patch BundlePatch) error {
+	var targetLangs, fallbackChain interface{}
+	if patch.TargetLangs != nil {
+		targetLangs = pq.Array(*patch.TargetLangs)
+	}
+	if patch.FallbackChain != nil {
+		fallbackChain = pq.Array(*patch.FallbackChain)
+	}
+
+	sqlResult, err := lps.statements.updateBundle.ExecContext(ctx, id, patch.Description, patch.Owner, patch.SourceLang,
+		targetLangs, patch.KeyPattern, fallbackChain, patch.MessageFormat, patch.PlaceholderCheck)
+	if err != nil {
+		return translateError(err)
+	}
+
+	numUpdated, err := sqlResult.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+	if numUpdated == 0 {
+		return newError(ErrNotFound, "No settings found for bundle "+id+", create them with POST", nil)
+	}
+
+	return nil
+}
+
+//DeleteBundle implements BundlePersistencer interface with postgresql implementation
+func (lps LocalePersistenceService) DeleteBundle(ctx context.Context, id string) error {
+	sqlResult, err := lps.statements.deleteBundle.ExecContext(ctx, id)
+	if err != nil {
+		return translateError(err)
+	}
+
+	numDeleted, err := sqlResult.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+	if numDeleted > 0 {
+		return nil
+	}
+
+	//nothing deleted: the bundle has no settings or still has items
+	bundles, err := lps.GetBundles(ctx, []string{id}, false)
+	if err != nil {
+		return err
+	}
+	if len(bundles) == 0 || !bundles[0].Managed {
+		return newError(ErrNotFound, "No settings found for bundle "+id, nil)
+	}
+	return newError(ErrConflict, "Bundle "+id+" still has locale items, delete them first", nil)
 }
@@ -0,0 +1,83 @@
+package storaging
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/formatting"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+)
+
+//pseudo-localization expansion, as percentage of the length of source text
+const (
+	defaultPseudoExpansion = 30
+	maxPseudoExpansion     = 300
+)
+
+//loadPseudoExpansion reads PSEUDO_EXPANSION, the percentage of length added to pseudo-localized content
+func loadPseudoExpansion() (int, error) {
+	candidate := os.Getenv("PSEUDO_EXPANSION")
+	if candidate == "" {
+		return defaultPseudoExpansion, nil
+	}
+
+	value, err := strconv.Atoi(candidate)
+	if err != nil || value < 0 || value > maxPseudoExpansion {
+		return 0, fmt.Errorf("invalid PSEUDO_EXPANSION: %q, it must be a percentage from 0 to %d", candidate, maxPseudoExpansion)
+	}
+
+	return value, nil
+}
+
+//pseudoItems return items of source lang as items of the pseudo lang, content and plural variants pseudo-localized;
+//they have no id since they are not stored
+func pseudoItems(lang string, items []LocaleItem, expansion int) []LocaleItem {
+	options := formatting.PseudoOptions{Expansion: expansion, Mirrored: lang == localizing.PseudoMirrored}
+	result := make([]LocaleItem, len(items))
+	for i, item := range items {
+		result[i] = LocaleItem{Key: item.Key, Bundle: item.Bundle, Lang: lang, Content: formatting.Pseudolocalize(item.Content, options)}
+		if item.Plurals != nil {
+			result[i].Plurals = PluralForms{}
+			for category, variant := range item.Plurals {
+				result[i].Plurals[category] = formatting.Pseudolocalize(variant, options)
+			}
+		}
+	}
+	return result
+}
+
+//readItems return the items read reads in lang; in a pseudo-localization lang the ones read reads in the source lang
+//of bundle, or in sourceLang when bundle is not set, pseudo-localized. Every endpoint returning items reads them with it
+func (lph LocalePersistenceHandler) readItems(ctx context.Context, bundleID, sourceLang, lang string, read func(lang string) ([]LocaleItem, error)) ([]LocaleItem, error) {
+	pseudoLang, isPseudo := localizing.Pseudo(lang)
+	if !isPseudo {
+		return read(lang)
+	}
+
+	if bundleID != "" {
+		bundle, err := lph.getBundle(ctx, bundleID)
+		if err != nil {
+			return nil, err
+		}
+		if bundle.SourceLang == "" {
+			return nil, newError(ErrConflict, fmt.Sprintf("Bundle %s has no source_lang to pseudo-localize", bundle.ID), nil)
+		}
+		sourceLang = bundle.SourceLang
+	}
+
+	items, err := read(sourceLang)
+	if err != nil {
+		return nil, err
+	}
+	return pseudoItems(pseudoLang, items, lph.pseudoExpansion), nil
+}
+
+//checkNotPseudo return an error when lang is a pseudo-localization one, its items are never stored
+func checkNotPseudo(field, lang string) []FieldError {
+	if pseudoLang, ok := localizing.Pseudo(lang); ok {
+		return []FieldError{{field, fieldInvalidLang, fmt.Sprintf("%s %s is pseudo-localized from the source lang, it cannot be stored", field, pseudoLang)}}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package storaging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPseudoItems(t *testing.T) {
+	items := pseudoItems(localizing.PseudoAccented, []LocaleItem{
+		{ID: "1", Key: "SAVE", Bundle: "label", Lang: "en", Content: "Save {name}", NeedsReview: true},
+		{ID: "2", Key: "FILES", Bundle: "label", Lang: "en", Content: "{n} files", Plurals: PluralForms{"one": "{n} file", "other": "{n} files"}},
+	}, 0)
+
+	assert.Equal(t, []LocaleItem{
+		{Key: "SAVE", Bundle: "label", Lang: localizing.PseudoAccented, Content: "[Šáṽé {name}]"},
+		{Key: "FILES", Bundle: "label", Lang: localizing.PseudoAccented, Content: "[{n} ƒîļéš]", Plurals: PluralForms{"one": "[{n} ƒîļé]", "other": "[{n} ƒîļéš]"}},
+	}, items)
+}
+
+func TestReadItems(t *testing.T) {
+	lph := LocalePersistenceHandler{}
+	stored := []LocaleItem{{ID: "1", Key: "SAVE", Bundle: "label", Lang: "en", Content: "Save"}}
+	var langs []string
+	read := func(lang string) ([]LocaleItem, error) {
+		langs = append(langs, lang)
+		return stored, nil
+	}
+
+	items, err := lph.readItems(context.Background(), "", "en", "it-IT", read)
+	assert.NoError(t, err)
+	assert.Equal(t, stored, items)
+
+	items, err = lph.readItems(context.Background(), "", "en", localizing.PseudoMirrored, read)
+	assert.NoError(t, err)
+	assert.Equal(t, localizing.PseudoMirrored, items[0].Lang)
+	assert.Empty(t, items[0].ID)
+	assert.Equal(t, []string{"it-IT", "en"}, langs)
+}
+
+func TestPseudoLangIsNotStored(t *testing.T) {
+	v := &itemValidator{}
+	assert.Equal(t, []FieldError{{"lang", fieldInvalidLang, "lang qps-ploc is pseudo-localized from the source lang, it cannot be stored"}},
+		v.validate(LocaleItem{Key: "SAVE", Bundle: "label", Lang: "qps-ploc", Content: "[Šáṽé]"}, writeRules{}))
+
+	_, errs := newLanguage(Language{Tag: "qps-plocm"})
+	assert.Equal(t, []FieldError{{"tag", fieldInvalidLang, "tag qps-plocm is pseudo-localized from the source lang, it cannot be stored"}}, errs)
+}
+
+func TestLoadPseudoExpansion(t *testing.T) {
+	t.Setenv("PSEUDO_EXPANSION", "")
+	expansion, err := loadPseudoExpansion()
+	assert.NoError(t, err)
+	assert.Equal(t, defaultPseudoExpansion, expansion)
+
+	t.Setenv("PSEUDO_EXPANSION", "50")
+	expansion, err = loadPseudoExpansion()
+	assert.NoError(t, err)
+	assert.Equal(t, 50, expansion)
+
+	for _, invalid := range []string{"-1", "301", "lots"} {
+		t.Setenv("PSEUDO_EXPANSION", invalid)
+		_, err = loadPseudoExpansion()
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,178 @@
+package storaging
+
+import (
+	"strconv"
+	"strings"
+)
+
+//likeEscaper escapes LIKE wildcards in user supplied terms, backslash is the escape char
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+//queryBuilder builds dynamic statements where every value is bound to a numbered placeholder,
+//so values never become part of the sql text
+type queryBuilder struct {
+	statement  string
+	conditions []string
+	groupBy    string
+	orderBy    string
+	limit      int
+	offset     int
+	args       []interface{}
+}
+
+//newQuery return a builder for statement, that is the part before WHERE;
+//args are the values of $1..$n used in statement, conditions placeholders follow them
+func newQuery(statement string, args ...interface{}) *queryBuilder {
+	return &queryBuilder{statement: statement, args: args}
+}
+
+//where adds a condition in AND, every ? in condition is replaced by the placeholder of the matching arg
+func (qb *queryBuilder) where(condition string, args ...interface{}) *queryBuilder {
+	var sb strings.Builder
+	argIndex := 0
+	for _, r := range condition {
+		if r == '?' && argIndex < len(args) {
+			qb.args = append(qb.args, args[argIndex])
+			argIndex++
+			sb.WriteString("$" + strconv.Itoa(len(qb.args)))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+
+	qb.conditions = append(qb.conditions, sb.String())
+	return qb
+}
+
+//equal adds column = value, nothing when value is empty
+func (qb *queryBuilder) equal(column, value string) *queryBuilder {
+	if value == "" {
+		return qb
+	}
+	return qb.where(column+" = ?", value)
+}
+
+//contains adds column LIKE %value% with wildcards of value escaped, nothing when value is empty
+func (qb *queryBuilder) contains(column, value string) *queryBuilder {
+	if value == "" {
+		return qb
+	}
+	return qb.where(column+` LIKE ? ESCAPE '\'`, "%"+escapeLike(value)+"%")
+}
+
+//group sets the GROUP BY clause, it must never contain user supplied values
+func (qb *queryBuilder) group(groupBy string) *queryBuilder {
+	qb.groupBy = groupBy
+	return qb
+}
+
+//order sets the ORDER BY clause, it must never contain user supplied values
+func (qb *queryBuilder) order(orderBy string) *queryBuilder {
+	qb.orderBy = orderBy
+	return qb
+}
+
+//page sets LIMIT and OFFSET, zero values are skipped
+func (qb *queryBuilder) page(limit, offset int) *queryBuilder {
+	qb.limit = limit
+	qb.offset = offset
+	return qb
+}
+
+//build return the statement and its args, in placeholders order
+func (qb *queryBuilder) build() (string, []interface{}) {
+	var sb strings.Builder
+	args := append([]interface{}{}, qb.args...)
+
+	sb.WriteString(qb.statement)
+	if len(qb.conditions) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(qb.conditions, " AND "))
+	}
+	if qb.groupBy != "" {
+		sb.WriteString(" GROUP BY ")
+		sb.WriteString(qb.groupBy)
+	}
+	if qb.orderBy != "" {
+		sb.WriteString(" ORDER BY ")
+		sb.WriteString(qb.orderBy)
+	}
+	if qb.limit != 0 {
+		args = append(args, qb.limit)
+		sb.WriteString(" LIMIT $" + strconv.Itoa(len(args)))
+	}
+	if qb.offset != 0 {
+		args = append(args, qb.offset)
+		sb.WriteString(" OFFSET $" + strconv.Itoa(len(args)))
+	}
+
+	return sb.String(), args
+}
+
+func escapeLike(term string) string {
+	return likeEscaper.Replace(term)
+}
+
+//localeItemQuery adds the filters shared by every query on localeitems
+func localeItemQuery(statement, key, bundle, lang, content string, trashed bool) *queryBuilder {
+	qb := newQuery(statement)
+	if trashed {
+		qb.where("localeitems.deleted_at IS NOT NULL")
+	} else {
+		qb.where("localeitems.deleted_at IS NULL")
+	}
+
+	return qb.contains("localeitems.key", key).
+		equal("localeitems.bundle", bundle).
+		equal("localeitems.lang", lang).
+		contains("localeitems.content", content)
+}
+
+//deleteQuery adds the filters of a delete of localeitems not in trash, key matches exactly
+//so a part of a key never selects other keys
+func deleteQuery(statement, key, bundle, lang string) *queryBuilder {
+	return localeItemQuery(statement, "", bundle, lang, "", false).
+		equal("localeitems.key", key)
+}
+
+//searchQuery return the full-text search of params.Q, $1, on localeitems by rank;
+//with a lang filter the query of its config is constant, otherwise the one of every config is,
+//so the GIN index on search_vector is used in both cases
+func searchQuery(statement string, params SearchQueryParams) *queryBuilder {
+	qb := newQuery(statement, params.Q)
+	switch params.Status {
+	case searchStatusTrashed:
+		qb.where("localeitems.deleted_at IS NOT NULL")
+	case searchStatusAll:
+	default:
+		qb.where("localeitems.deleted_at IS NULL")
+	}
+
+	if params.Lang != "" {
+		qb.where("localeitems.lang = ?", params.Lang).
+			where("localeitems.search_vector @@ websearch_to_tsquery(localeitems_ts_config(?), $1)", params.Lang)
+	} else {
+		qb.where("localeitems.search_vector @@ localeitems_any_tsquery($1)")
+	}
+
+	return qb.equal("localeitems.bundle", params.Bundle).
+		where("localeitems.search_vector @@ query").
+		order("rank DESC, localeitems.id").
+		page(params.Limit, params.Offset)
+}
+
+//glossaryQuery return entries of params, the ones of a target lang include the ones for every lang;
+//q matches part of source or target term in any case
+func glossaryQuery(statement string, params GlossaryQueryParams) *queryBuilder {
+	qb := newQuery(statement).equal("source_lang", params.SourceLang)
+	if params.TargetLang != "" {
+		qb.where("target_lang IN (?, ?)", params.TargetLang, glossaryAnyLang)
+	}
+	if params.Q != "" {
+		pattern := "%" + escapeLike(params.Q) + "%"
+		qb.where(`(source_term ILIKE ? ESCAPE '\' OR target_term ILIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+
+	return qb.order("source_lang, lower(source_term), target_lang").
+		page(params.Limit, params.Offset)
+}
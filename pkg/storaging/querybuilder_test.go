@@ -0,0 +1,129 @@
+package storaging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var hostileValues = []string{
+	"' OR '1'='1",
+	"label'; DROP TABLE localeitems; --",
+	"$1",
+	"?",
+	"%",
+	"_",
+	`\`,
+	"\x00",
+	"label\" OR \"\"=\"",
+	"/* comment */",
+}
+
+func TestQueryBuilderPlaceholders(t *testing.T) {
+	stmt, args := localeItemQuery("SELECT id FROM localeitems", "KEY", "label", "it-IT", "text", false).
+		page(10, 20).
+		build()
+
+	assert.Equal(t, `SELECT id FROM localeitems WHERE localeitems.deleted_at IS NULL AND localeitems.key LIKE $1 ESCAPE '\' AND localeitems.bundle = $2 AND localeitems.lang = $3 AND localeitems.content LIKE $4 ESCAPE '\' LIMIT $5 OFFSET $6`, stmt)
+	assert.Equal(t, []interface{}{"%KEY%", "label", "it-IT", "%text%", 10, 20}, args)
+}
+
+func TestQueryBuilderSkipsEmptyFilters(t *testing.T) {
+	stmt, args := localeItemQuery("SELECT id FROM localeitems", "", "label", "", "", true).build()
+
+	assert.Equal(t, "SELECT id FROM localeitems WHERE localeitems.deleted_at IS NOT NULL AND localeitems.bundle = $1", stmt)
+	assert.Equal(t, []interface{}{"label"}, args)
+}
+
+func TestDeleteQueryMatchesWholeKey(t *testing.T) {
+	//a non admin sending part of a key must not move to trash every key containing it
+	stmt, args := deleteQuery("UPDATE localeitems SET deleted_at = now()", "e", "label", "").build()
+
+	assert.Equal(t, "UPDATE localeitems SET deleted_at = now() WHERE localeitems.deleted_at IS NULL AND localeitems.bundle = $1 AND localeitems.key = $2", stmt)
+	assert.Equal(t, []interface{}{"label", "e"}, args)
+}
+
+func TestQueryBuilderGroup(t *testing.T) {
+	stmt, args := newQuery("SELECT bundle, COUNT(*) FROM localeitems").
+		equal("lang", "it-IT").
+		group("bundle").
+		order("bundle").
+		build()
+
+	assert.Equal(t, "SELECT bundle, COUNT(*) FROM localeitems WHERE lang = $1 GROUP BY bundle ORDER BY bundle", stmt)
+	assert.Equal(t, []interface{}{"it-IT"}, args)
+}
+
+func TestSearchQuery(t *testing.T) {
+	stmt, args := searchQuery("SELECT id FROM localeitems", SearchQueryParams{Q: "caffè", Bundle: "label", Lang: "it-IT", Status: searchStatusActive, Limit: 20}).build()
+
+	assert.Equal(t, "SELECT id FROM localeitems WHERE localeitems.deleted_at IS NULL AND localeitems.lang = $2 AND localeitems.search_vector @@ websearch_to_tsquery(localeitems_ts_config($3), $1) AND localeitems.bundle = $4 AND localeitems.search_vector @@ query ORDER BY rank DESC, localeitems.id LIMIT $5", stmt)
+	assert.Equal(t, []interface{}{"caffè", "it-IT", "it-IT", "label", 20}, args)
+
+	stmt, args = searchQuery("SELECT id FROM localeitems", SearchQueryParams{Q: "caffè", Status: searchStatusAll}).build()
+
+	assert.Equal(t, "SELECT id FROM localeitems WHERE localeitems.search_vector @@ localeitems_any_tsquery($1) AND localeitems.search_vector @@ query ORDER BY rank DESC, localeitems.id", stmt)
+	assert.Equal(t, []interface{}{"caffè"}, args)
+}
+
+func TestGlossaryQuery(t *testing.T) {
+	stmt, args := glossaryQuery("SELECT id FROM glossary", GlossaryQueryParams{SourceLang: "en", TargetLang: "it-IT", Q: "work_", Limit: 10}).build()
+
+	assert.Equal(t, `SELECT id FROM glossary WHERE source_lang = $1 AND target_lang IN ($2, $3) AND (source_term ILIKE $4 ESCAPE '\' OR target_term ILIKE $5 ESCAPE '\') ORDER BY source_lang, lower(source_term), target_lang LIMIT $6`, stmt)
+	assert.Equal(t, []interface{}{"en", "it-IT", "*", `%work\_%`, `%work\_%`, 10}, args)
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\%`, escapeLike("100%"))
+	assert.Equal(t, `HELLO\_TEST`, escapeLike("HELLO_TEST"))
+	assert.Equal(t, `C:\\temp`, escapeLike(`C:\temp`))
+}
+
+//FuzzLocaleItemQuery checks that values never change the statement, they only end up in args
+func FuzzLocaleItemQuery(f *testing.F) {
+	for _, value := range hostileValues {
+		f.Add(value, value, value)
+	}
+
+	expectedStmt, _ := localeItemQuery("SELECT id FROM localeitems", "k", "b", "l", "c", false).page(1, 1).build()
+
+	f.Fuzz(func(t *testing.T, bundle, key, content string) {
+		if bundle == "" || key == "" || content == "" {
+			t.Skip()
+		}
+
+		stmt, args := localeItemQuery("SELECT id FROM localeitems", key, bundle, "l", content, false).page(1, 1).build()
+
+		if stmt != expectedStmt {
+			t.Fatalf("statement changed by values %q %q %q: %s", bundle, key, content, stmt)
+		}
+		if len(args) != 6 {
+			t.Fatalf("expected 6 args, got %d", len(args))
+		}
+		if args[0] != "%"+escapeLike(key)+"%" || args[1] != bundle || args[3] != "%"+escapeLike(content)+"%" {
+			t.Fatalf("args do not match values: %v", args)
+		}
+	})
+}
+
+//FuzzEscapeLike checks that an escaped term has no unescaped wildcard left
+func FuzzEscapeLike(f *testing.F) {
+	for _, value := range hostileValues {
+		f.Add(value)
+	}
+
+	f.Fuzz(func(t *testing.T, term string) {
+		escaped := []rune(escapeLike(term))
+		for i := 0; i < len(escaped); i++ {
+			switch escaped[i] {
+			case '\\':
+				if i+1 >= len(escaped) {
+					t.Fatalf("dangling escape in %q", string(escaped))
+				}
+				i++
+			case '%', '_':
+				t.Fatalf("unescaped wildcard in %q", string(escaped))
+			}
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package storaging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/gin-gonic/gin"
+)
+
+//maxScreenshotSize is the max size in bytes of an uploaded screenshot
+const maxScreenshotSize = 8 << 20
+
+//screenshotExtensions are the accepted image types, by content type sniffed from data, with the extension of their blob
+var screenshotExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+//Screenshot rappresents an image of the UI where a key is shown, URL return its data
+type Screenshot struct {
+	ID          string    `json:"id"`
+	Bundle      string    `json:"bundle"`
+	Key         string    `json:"key"`
+	ContentType string    `json:"content_type"`
+	Size        int       `json:"size"`
+	URL         string    `json:"url"`
+	CreatedAt   time.Time `json:"created_at"`
+	//BlobPath is the file of data in the blob directory, empty when data is stored in database
+	BlobPath string `json:"-"`
+}
+
+//ScreenshotPersistencer interface for screenshots persistence
+type ScreenshotPersistencer interface {
+	GetScreenshots(ctx context.Context, bundle string, keys []string) ([]Screenshot, error)
+	GetScreenshot(ctx context.Context, bundle, key, id string) (*Screenshot, []byte, error)
+	PostScreenshot(ctx context.Context, screenshot Screenshot, data []byte) (*Screenshot, error)
+	DeleteScreenshot(ctx context.Context, bundle, key, id string) (*Screenshot, error)
+}
+
+//loadScreenshotDir reads SCREENSHOT_DIR, the blob directory of screenshots; when empty they are stored in database
+func loadScreenshotDir() (string, error) {
+	dir := os.Getenv("SCREENSHOT_DIR")
+	if dir == "" {
+		return "", nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("invalid SCREENSHOT_DIR: %v", err)
+	}
+	return dir, nil
+}
+
+//screenshotURL return the path of the data of a screenshot
+func screenshotURL(bundle, key, id string) string {
+	return fmt.Sprintf("/api/v1/bundles/%s/keys/%s/screenshots/%s", url.PathEscape(bundle), url.PathEscape(key), id)
+}
+
+//newBlobPath return a random file name with extension ext
+func newBlobPath(ext string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random) + ext, nil
+}
+
+//removeBlob removes the file of a screenshot from the blob directory, an error is only logged
+//since the screenshot is already removed from database
+func (lph LocalePersistenceHandler) removeBlob(blobPath string) {
+	if blobPath == "" || lph.screenshotDir == "" {
+		return
+	}
+	if err := os.Remove(filepath.Join(lph.screenshotDir, blobPath)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error on removing screenshot %s: %v\n", blobPath, err)
+	}
+}
+
+//PostScreenshot adds a screenshot to a key, the body is the image as png, jpeg, gif or webp
+func (lph LocalePersistenceHandler) PostScreenshot(c *gin.Context) {
+	screenshot := Screenshot{Bundle: c.Param("id"), Key: c.Param("key")}
+	setAuditFilters(c, screenshot.Bundle, "", screenshot.Key)
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxScreenshotSize+1))
+	if err != nil {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeBadRequest, "Error on reading request: "+err.Error())
+		return
+	}
+	if len(data) > maxScreenshotSize {
+		problem.Abort(c, http.StatusRequestEntityTooLarge, problem.CodeBadRequest, fmt.Sprintf("Screenshot is larger than %d bytes", maxScreenshotSize))
+		return
+	}
+	screenshot.ContentType = http.DetectContentType(data)
+	ext, ok := screenshotExtensions[screenshot.ContentType]
+	if !ok {
+		problem.Abort(c, http.StatusUnsupportedMediaType, problem.CodeBadRequest, "Screenshot must be a png, jpeg, gif or webp image, not "+screenshot.ContentType)
+		return
+	}
+	screenshot.Size = len(data)
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opPostScreenshot)
+	defer cancel()
+	if _, err = lph.getBundle(ctx, screenshot.Bundle); err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	if lph.screenshotDir != "" {
+		if screenshot.BlobPath, err = newBlobPath(ext); err != nil {
+			problem.AbortInternal(c, err)
+			return
+		}
+		if err = os.WriteFile(filepath.Join(lph.screenshotDir, screenshot.BlobPath), data, 0o644); err != nil {
+			problem.AbortInternal(c, err)
+			return
+		}
+		data = nil
+	}
+
+	created, err := lph.ScreenshotDelegate.PostScreenshot(ctx, screenshot, data)
+	if err != nil {
+		lph.removeBlob(screenshot.BlobPath)
+		respondError(c, ctx, err)
+		return
+	}
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.JSON(http.StatusCreated, created)
+}
+
+//GetScreenshot return the image of a screenshot
+func (lph LocalePersistenceHandler) GetScreenshot(c *gin.Context) {
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetScreenshot)
+	defer cancel()
+	screenshot, data, err := lph.ScreenshotDelegate.GetScreenshot(ctx, c.Param("id"), c.Param("key"), c.Param("screenshotId"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	if screenshot.BlobPath != "" {
+		if lph.screenshotDir == "" {
+			problem.AbortInternal(c, fmt.Errorf("screenshot %s is in blob directory but SCREENSHOT_DIR is not set", screenshot.ID))
+			return
+		}
+		c.File(filepath.Join(lph.screenshotDir, screenshot.BlobPath))
+		return
+	}
+	c.Data(http.StatusOK, screenshot.ContentType, data)
+}
+
+//DeleteScreenshot removes a screenshot of a key
+func (lph LocalePersistenceHandler) DeleteScreenshot(c *gin.Context) {
+	bundle, key := c.Param("id"), c.Param("key")
+	setAuditFilters(c, bundle, "", key)
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opDeleteScreenshot)
+	defer cancel()
+	screenshot, err := lph.ScreenshotDelegate.DeleteScreenshot(ctx, bundle, key, c.Param("screenshotId"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	lph.removeBlob(screenshot.BlobPath)
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: 1})
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package storaging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	//maxSearchLength is the max length in chars of a search query
+	maxSearchLength = 256
+	//defaultSearchLimit is used when limit is not set
+	defaultSearchLimit = 20
+	//maxSearchLimit is the max number of results of a search page
+	maxSearchLimit = 100
+)
+
+//statuses of searched items
+const (
+	searchStatusActive  = "active"
+	searchStatusTrashed = "trashed"
+	searchStatusAll     = "all"
+)
+
+//SearchQueryParams rappresents a full-text search on content, Q uses web search syntax:
+//"quoted phrase", or, -excluded
+type SearchQueryParams struct {
+	Q      string `form:"q"`
+	Bundle string `form:"bundle"`
+	Lang   string `form:"lang"`
+	Status string `form:"status"`
+	Offset int    `form:"offset"`
+	Limit  int    `form:"limit"`
+}
+
+//SearchHit rappresents an item matching a search, Snippet is content with matching words in <mark></mark>
+type SearchHit struct {
+	LocaleItem
+	Rank      float64    `json:"rank"`
+	Snippet   string     `json:"snippet"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+//SearchPersistencer interface for full-text search persistence
+type SearchPersistencer interface {
+	SearchLocaleItems(ctx context.Context, params SearchQueryParams) ([]SearchHit, error)
+}
+
+//checkSearch return invalid fields of params and sets defaults of status and limit
+func checkSearch(params *SearchQueryParams) []FieldError {
+	errs := checkText("q", params.Q, maxSearchLength, false)
+
+	switch params.Status {
+	case "":
+		params.Status = searchStatusActive
+	case searchStatusActive, searchStatusTrashed, searchStatusAll:
+	default:
+		errs = append(errs, FieldError{"status", fieldInvalidName,
+			fmt.Sprintf("status must be %s, %s or %s", searchStatusActive, searchStatusTrashed, searchStatusAll)})
+	}
+
+	if params.Limit <= 0 {
+		params.Limit = defaultSearchLimit
+	} else if params.Limit > maxSearchLimit {
+		errs = append(errs, FieldError{"limit", fieldTooLong, fmt.Sprintf("limit is %d, max is %d", params.Limit, maxSearchLimit)})
+	}
+
+	return errs
+}
+
+//SearchLocaleItems return items whose content matches q by rank, with highlighted snippets
+func (lph LocalePersistenceHandler) SearchLocaleItems(c *gin.Context) {
+	var searchQueryParams SearchQueryParams
+	err := c.ShouldBindQuery(&searchQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	searchQueryParams.Lang = localizing.CanonicalizeOrKeep(searchQueryParams.Lang)
+	if errs := checkSearch(&searchQueryParams); len(errs) > 0 {
+		abortValidation(c, "Search has invalid params", errs)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opSearchLocaleItems)
+	defer cancel()
+	hits, err := lph.SearchDelegate.SearchLocaleItems(ctx, searchQueryParams)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, hits)
+}
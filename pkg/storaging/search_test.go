@@ -0,0 +1,41 @@
+package storaging
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSearch(t *testing.T) {
+	tests := []struct {
+		name   string
+		params SearchQueryParams
+		codes  []string
+	}{
+		{"valid", SearchQueryParams{Q: "perché", Status: searchStatusTrashed, Limit: 100}, nil},
+		{"defaults", SearchQueryParams{Q: "hello"}, nil},
+		{"missing q", SearchQueryParams{}, []string{fieldRequired}},
+		{"q too long", SearchQueryParams{Q: strings.Repeat("q", maxSearchLength+1)}, []string{fieldTooLong}},
+		{"unknown status", SearchQueryParams{Q: "hello", Status: "deleted"}, []string{fieldInvalidName}},
+		{"limit too high", SearchQueryParams{Q: "hello", Limit: maxSearchLimit + 1}, []string{fieldTooLong}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes := []string{}
+			for _, fe := range checkSearch(&tt.params) {
+				codes = append(codes, fe.Code)
+			}
+			if tt.codes == nil {
+				tt.codes = []string{}
+			}
+			assert.Equal(t, tt.codes, codes)
+		})
+	}
+
+	params := SearchQueryParams{Q: "hello"}
+	checkSearch(&params)
+	assert.Equal(t, searchStatusActive, params.Status)
+	assert.Equal(t, defaultSearchLimit, params.Limit)
+}
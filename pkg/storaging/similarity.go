@@ -0,0 +1,216 @@
+package storaging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	//defaultSimilarThreshold is the min similarity of similar items when threshold is not set
+	defaultSimilarThreshold = 0.5
+	//defaultDuplicateThreshold is the min similarity of near-duplicates when threshold is not set
+	defaultDuplicateThreshold = 0.7
+	//defaultSimilarLimit is used when limit is not set
+	defaultSimilarLimit = 10
+)
+
+//SimilarQueryParams rappresents a search of items with content similar to Text in Lang
+type SimilarQueryParams struct {
+	Text      string  `form:"text"`
+	Lang      string  `form:"lang"`
+	Bundle    string  `form:"bundle"`
+	Threshold float64 `form:"threshold"`
+	Limit     int     `form:"limit"`
+}
+
+//DuplicatesQueryParams rappresents filters of near-duplicates report, Lang is source_lang of bundle when not set
+type DuplicatesQueryParams struct {
+	Lang      string  `form:"lang"`
+	Threshold float64 `form:"threshold"`
+}
+
+//SimilarItem rappresents an item with the trigram similarity of its content, from 0 to 1
+type SimilarItem struct {
+	LocaleItem
+	Similarity float64 `json:"similarity"`
+}
+
+//SimilarPair rappresents two items of different keys with similar content
+type SimilarPair struct {
+	A          LocaleItem
+	B          LocaleItem
+	Similarity float64
+}
+
+//DuplicateCluster rappresents items linked by pairs of similar content, Similarity is the highest of its pairs
+type DuplicateCluster struct {
+	Similarity float64      `json:"similarity"`
+	Items      []LocaleItem `json:"items"`
+}
+
+//DuplicatesReport rappresents near-duplicate strings of a lang of a bundle, biggest clusters first
+type DuplicatesReport struct {
+	Bundle    string             `json:"bundle"`
+	Lang      string             `json:"lang"`
+	Threshold float64            `json:"threshold"`
+	Clusters  []DuplicateCluster `json:"clusters"`
+}
+
+//SimilarityPersistencer interface for similarity search persistence
+type SimilarityPersistencer interface {
+	GetSimilarLocaleItems(ctx context.Context, params SimilarQueryParams) ([]SimilarItem, error)
+	GetSimilarPairs(ctx context.Context, bundle, lang string, threshold float64) ([]SimilarPair, error)
+}
+
+//checkThreshold return an error when threshold is not in (0, 1], zero is replaced by defaultThreshold
+func checkThreshold(threshold *float64, defaultThreshold float64) []FieldError {
+	if *threshold == 0 {
+		*threshold = defaultThreshold
+	}
+	if *threshold < 0 || *threshold > 1 {
+		return []FieldError{{"threshold", fieldInvalidName, fmt.Sprintf("threshold is %g, it must be greater than 0 and at most 1", *threshold)}}
+	}
+	return nil
+}
+
+//checkSimilar return invalid fields of params and sets defaults of threshold and limit
+func checkSimilar(params *SimilarQueryParams) []FieldError {
+	errs := checkText("text", params.Text, maxContentLength, true)
+	if params.Text == "" {
+		errs = append(errs, FieldError{"text", fieldRequired, "text is required"})
+	}
+	errs = append(errs, checkText("lang", params.Lang, maxLangLength, false)...)
+	errs = append(errs, checkThreshold(&params.Threshold, defaultSimilarThreshold)...)
+
+	if params.Limit <= 0 {
+		params.Limit = defaultSimilarLimit
+	} else if params.Limit > maxSearchLimit {
+		errs = append(errs, FieldError{"limit", fieldTooLong, fmt.Sprintf("limit is %d, max is %d", params.Limit, maxSearchLimit)})
+	}
+
+	return errs
+}
+
+//clusterPairs groups items linked by similar pairs, so A~B and B~C are one cluster even if A and C are not similar;
+//biggest clusters first, items of a cluster by key
+func clusterPairs(pairs []SimilarPair) []DuplicateCluster {
+	parent := map[string]string{}
+	items := map[string]LocaleItem{}
+	var find func(id string) string
+	find = func(id string) string {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+
+	for _, pair := range pairs {
+		for _, item := range []LocaleItem{pair.A, pair.B} {
+			if _, ok := parent[item.ID]; !ok {
+				parent[item.ID] = item.ID
+				items[item.ID] = item
+			}
+		}
+		parent[find(pair.A.ID)] = find(pair.B.ID)
+	}
+
+	byRoot := map[string]*DuplicateCluster{}
+	for id, item := range items {
+		root := find(id)
+		if byRoot[root] == nil {
+			byRoot[root] = &DuplicateCluster{}
+		}
+		byRoot[root].Items = append(byRoot[root].Items, item)
+	}
+	for _, pair := range pairs {
+		cluster := byRoot[find(pair.A.ID)]
+		if pair.Similarity > cluster.Similarity {
+			cluster.Similarity = pair.Similarity
+		}
+	}
+
+	clusters := make([]DuplicateCluster, 0, len(byRoot))
+	for _, cluster := range byRoot {
+		sort.Slice(cluster.Items, func(i, j int) bool { return cluster.Items[i].Key < cluster.Items[j].Key })
+		clusters = append(clusters, *cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if len(clusters[i].Items) != len(clusters[j].Items) {
+			return len(clusters[i].Items) > len(clusters[j].Items)
+		}
+		return clusters[i].Items[0].Key < clusters[j].Items[0].Key
+	})
+	return clusters
+}
+
+//GetSimilarLocaleItems return items of lang with content similar to text, most similar first
+func (lph LocalePersistenceHandler) GetSimilarLocaleItems(c *gin.Context) {
+	var similarQueryParams SimilarQueryParams
+	err := c.ShouldBindQuery(&similarQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	similarQueryParams.Lang = localizing.CanonicalizeOrKeep(similarQueryParams.Lang)
+	if errs := checkSimilar(&similarQueryParams); len(errs) > 0 {
+		abortValidation(c, "Similarity search has invalid params", errs)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetSimilar)
+	defer cancel()
+	items, err := lph.SimilarityDelegate.GetSimilarLocaleItems(ctx, similarQueryParams)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+//GetDuplicates return clusters of near-duplicate strings across keys of the bundle, in its source lang by default
+func (lph LocalePersistenceHandler) GetDuplicates(c *gin.Context) {
+	var duplicatesQueryParams DuplicatesQueryParams
+	err := c.ShouldBindQuery(&duplicatesQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	if errs := checkThreshold(&duplicatesQueryParams.Threshold, defaultDuplicateThreshold); len(errs) > 0 {
+		abortValidation(c, "Duplicates report has invalid params", errs)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetDuplicates)
+	defer cancel()
+	bundle, err := lph.getBundle(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	lang := localizing.CanonicalizeOrKeep(duplicatesQueryParams.Lang)
+	if lang == "" {
+		lang = bundle.SourceLang
+	}
+	if lang == "" {
+		problem.Abort(c, http.StatusConflict, problem.CodeConflict, fmt.Sprintf("Bundle %s has no source_lang, set lang to look for duplicates", bundle.ID))
+		return
+	}
+
+	pairs, err := lph.SimilarityDelegate.GetSimilarPairs(ctx, bundle.ID, lang, duplicatesQueryParams.Threshold)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, DuplicatesReport{bundle.ID, lang, duplicatesQueryParams.Threshold, clusterPairs(pairs)})
+}
@@ -0,0 +1,57 @@
+package storaging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterPairs(t *testing.T) {
+	item := func(id, key string) LocaleItem { return LocaleItem{ID: id, Key: key, Bundle: "label", Lang: "en"} }
+
+	clusters := clusterPairs([]SimilarPair{
+		{item("1", "SAVE"), item("2", "SAVE_BUTTON"), 0.9},
+		{item("2", "SAVE_BUTTON"), item("3", "SAVE_CHANGES"), 0.75},
+		{item("4", "CANCEL"), item("5", "ABORT"), 1},
+	})
+
+	assert.Len(t, clusters, 2)
+	assert.Equal(t, 0.9, clusters[0].Similarity)
+	assert.Equal(t, []string{"SAVE", "SAVE_BUTTON", "SAVE_CHANGES"}, []string{clusters[0].Items[0].Key, clusters[0].Items[1].Key, clusters[0].Items[2].Key})
+	assert.Equal(t, 1.0, clusters[1].Similarity)
+	assert.Equal(t, []string{"ABORT", "CANCEL"}, []string{clusters[1].Items[0].Key, clusters[1].Items[1].Key})
+
+	assert.Empty(t, clusterPairs(nil))
+}
+
+func TestCheckSimilar(t *testing.T) {
+	tests := []struct {
+		name   string
+		params SimilarQueryParams
+		codes  []string
+	}{
+		{"valid", SimilarQueryParams{Text: "Save changes", Lang: "en", Threshold: 1}, nil},
+		{"missing text and lang", SimilarQueryParams{}, []string{fieldRequired, fieldRequired}},
+		{"threshold out of range", SimilarQueryParams{Text: "Save", Lang: "en", Threshold: 1.5}, []string{fieldInvalidName}},
+		{"negative threshold", SimilarQueryParams{Text: "Save", Lang: "en", Threshold: -0.1}, []string{fieldInvalidName}},
+		{"limit too high", SimilarQueryParams{Text: "Save", Lang: "en", Limit: maxSearchLimit + 1}, []string{fieldTooLong}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes := []string{}
+			for _, fe := range checkSimilar(&tt.params) {
+				codes = append(codes, fe.Code)
+			}
+			if tt.codes == nil {
+				tt.codes = []string{}
+			}
+			assert.Equal(t, tt.codes, codes)
+		})
+	}
+
+	params := SimilarQueryParams{Text: "Save", Lang: "en"}
+	checkSimilar(&params)
+	assert.Equal(t, defaultSimilarThreshold, params.Threshold)
+	assert.Equal(t, defaultSimilarLimit, params.Limit)
+}
@@ -0,0 +1,4 @@
+-- a bundle with items, trashed ones included, keeps its settings
+DELETE FROM bundles
+WHERE id = $1
+    AND NOT EXISTS (SELECT 1 FROM localeitems WHERE bundle = $1);
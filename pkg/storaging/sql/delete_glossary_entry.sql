@@ -0,0 +1 @@
+DELETE FROM glossary WHERE id = $1;
@@ -0,0 +1,4 @@
+-- a language still used by items, trashed ones included, is not deleted
+DELETE FROM languages
+WHERE tag = $1
+    AND NOT EXISTS (SELECT 1 FROM localeitems WHERE lang = $1);
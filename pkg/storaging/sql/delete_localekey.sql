@@ -0,0 +1 @@
+DELETE FROM localekeys WHERE bundle = $1 AND key = $2;
@@ -0,0 +1,3 @@
+DELETE FROM screenshots
+WHERE id = $1 AND bundle = $2 AND key = $3
+RETURNING blob_path;
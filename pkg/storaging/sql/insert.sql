@@ -1 +0,0 @@
-INSERT INTO localeitems ( key, bundle, lang, content ) VALUES( $1,$2,$3,$4) RETURNING id;
\ No newline at end of file
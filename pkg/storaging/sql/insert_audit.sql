@@ -0,0 +1,2 @@
+INSERT INTO audit_log ( user_name, method, route, bundle, lang, key, status, num_successful, num_failed, source_ip )
+VALUES( $1,$2,$3,$4,$5,$6,$7,$8,$9,$10);
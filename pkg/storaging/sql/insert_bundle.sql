@@ -0,0 +1,2 @@
+INSERT INTO bundles(id, description, owner, source_lang, target_langs, key_pattern, fallback_chain, message_format, placeholder_check)
+VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9);
@@ -0,0 +1,3 @@
+INSERT INTO comments(localeitem_id, parent_id, author, body, mentions)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
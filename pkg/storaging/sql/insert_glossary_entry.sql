@@ -0,0 +1,3 @@
+INSERT INTO glossary(source_lang, source_term, target_lang, target_term, do_not_translate, note)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id;
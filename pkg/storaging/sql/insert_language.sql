@@ -0,0 +1,2 @@
+INSERT INTO languages(tag, display_name, direction, plural_categories, parent_tag, enabled)
+VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6);
@@ -0,0 +1,3 @@
+INSERT INTO translation_memory(source_lang, source_text, target_lang, target_text, bundle, key, origin)
+VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7)
+ON CONFLICT (source_lang, target_lang, md5(source_text), md5(target_text)) DO NOTHING;
@@ -0,0 +1,8 @@
+-- a key gets default metadata with its first screenshot
+WITH localekey AS (
+    INSERT INTO localekeys(bundle, key) VALUES ($1, $2)
+    ON CONFLICT (bundle, key) DO NOTHING
+)
+INSERT INTO screenshots(bundle, key, content_type, size, data, blob_path)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, created_at;
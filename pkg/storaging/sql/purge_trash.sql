@@ -0,0 +1 @@
+DELETE FROM localeitems WHERE deleted_at IS NOT NULL AND deleted_at < $1;
@@ -0,0 +1,2 @@
+-- trashed items are renamed too, so a restore does not bring the old key back
+UPDATE localeitems SET key = $3 WHERE bundle = $1 AND key = $2;
@@ -0,0 +1,2 @@
+-- screenshots follow by the foreign key cascade
+UPDATE localekeys SET key = $3 WHERE bundle = $1 AND key = $2;
@@ -0,0 +1,3 @@
+SELECT id, localeitem_id, parent_id, author, body, mentions, resolved, resolved_by, resolved_at, created_at
+FROM comments
+WHERE id = $1;
@@ -0,0 +1 @@
+SELECT id, source_lang, source_term, target_lang, target_term, do_not_translate, note FROM glossary WHERE id = $1;
@@ -0,0 +1,3 @@
+-- a key is used by an item, trashed ones included, or by its metadata
+SELECT EXISTS (SELECT 1 FROM localeitems WHERE bundle = $1 AND key = $2)
+    OR EXISTS (SELECT 1 FROM localekeys WHERE bundle = $1 AND key = $2);
@@ -0,0 +1 @@
+SELECT tag, display_name, direction, plural_categories, COALESCE(parent_tag, ''), enabled FROM languages WHERE tag = $1;
@@ -0,0 +1 @@
+SELECT id, bundle, lang, key, content, plurals, machine_translated, needs_review FROM localeitems WHERE id = $1 AND deleted_at IS NULL;
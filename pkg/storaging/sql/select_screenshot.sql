@@ -0,0 +1,3 @@
+SELECT id, bundle, key, content_type, size, blob_path, created_at, data
+FROM screenshots
+WHERE id = $1 AND bundle = $2 AND key = $3;
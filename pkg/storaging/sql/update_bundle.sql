@@ -0,0 +1,12 @@
+-- NULL keeps the current value, an empty source_lang clears it
+UPDATE bundles SET
+    description = COALESCE($2, description),
+    owner = COALESCE($3, owner),
+    source_lang = CASE WHEN $4::VARCHAR IS NULL THEN source_lang ELSE NULLIF($4::VARCHAR, '') END,
+    target_langs = COALESCE($5, target_langs),
+    key_pattern = COALESCE($6, key_pattern),
+    fallback_chain = COALESCE($7, fallback_chain),
+    message_format = COALESCE($8, message_format),
+    placeholder_check = COALESCE($9, placeholder_check),
+    updated_at = now()
+WHERE id = $1;
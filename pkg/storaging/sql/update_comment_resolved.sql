@@ -0,0 +1,6 @@
+-- only the first comment of a thread has the resolved state
+UPDATE comments SET
+    resolved = $3,
+    resolved_by = CASE WHEN $3 THEN $4 ELSE '' END,
+    resolved_at = CASE WHEN $3 THEN now() ELSE NULL END
+WHERE id = $1 AND localeitem_id = $2 AND parent_id IS NULL;
@@ -0,0 +1,6 @@
+-- NULL keeps the current value, a do not translate term is translated as itself
+UPDATE glossary SET
+    target_term = CASE WHEN COALESCE($3, do_not_translate) THEN source_term ELSE COALESCE($2, target_term) END,
+    do_not_translate = COALESCE($3, do_not_translate),
+    note = COALESCE($4, note)
+WHERE id = $1;
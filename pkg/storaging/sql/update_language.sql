@@ -0,0 +1,8 @@
+-- NULL keeps the current value, an empty parent_tag clears it
+UPDATE languages SET
+    display_name = COALESCE($2, display_name),
+    direction = COALESCE($3, direction),
+    plural_categories = COALESCE($4, plural_categories),
+    parent_tag = CASE WHEN $5::VARCHAR IS NULL THEN parent_tag ELSE NULLIF($5::VARCHAR, '') END,
+    enabled = COALESCE($6, enabled)
+WHERE tag = $1;
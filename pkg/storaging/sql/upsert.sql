@@ -1,6 +1,6 @@
-INSERT INTO localeitems ( key, bundle, lang, content ) 
-VALUES( $1,$2,$3,$4)
+INSERT INTO localeitems ( key, bundle, lang, content, plurals, machine_translated, needs_review ) 
+VALUES( $1,$2,$3,$4,$5,$6,$7)
 ON CONFLICT ON CONSTRAINT ukey_localeitems
-DO UPDATE SET content = $4 
+DO UPDATE SET content = $4, plurals = $5, machine_translated = $6, needs_review = $7, deleted_at = NULL 
 WHERE localeitems.key = $1 AND localeitems.bundle = $2 AND localeitems.lang = $3
-RETURNING id;
\ No newline at end of file
+RETURNING id;
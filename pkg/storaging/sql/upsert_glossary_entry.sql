@@ -0,0 +1,8 @@
+-- an imported term replaces the one with the same source term, in any case, of the language pair
+INSERT INTO glossary(source_lang, source_term, target_lang, target_term, do_not_translate, note)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (source_lang, target_lang, lower(source_term)) DO UPDATE SET
+    source_term = EXCLUDED.source_term,
+    target_term = EXCLUDED.target_term,
+    do_not_translate = EXCLUDED.do_not_translate,
+    note = EXCLUDED.note;
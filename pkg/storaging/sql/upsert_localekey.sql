@@ -0,0 +1,10 @@
+INSERT INTO localekeys(bundle, key, max_length, length_unit, charset, multiline, description, note, tags)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (bundle, key) DO UPDATE SET
+    max_length = EXCLUDED.max_length,
+    length_unit = EXCLUDED.length_unit,
+    charset = EXCLUDED.charset,
+    multiline = EXCLUDED.multiline,
+    description = EXCLUDED.description,
+    note = EXCLUDED.note,
+    tags = EXCLUDED.tags;
@@ -0,0 +1,110 @@
+package storaging
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+)
+
+//go:embed sql/*.sql
+var sqlFiles embed.FS
+
+//preparedStatements holds every static statement, prepared once when the service is built
+type preparedStatements struct {
+	upsertLocaleItem      *sql.Stmt
+	selectLocaleItem      *sql.Stmt
+	purgeTrash            *sql.Stmt
+	insertAudit           *sql.Stmt
+	selectLanguage        *sql.Stmt
+	insertLanguage        *sql.Stmt
+	updateLanguage        *sql.Stmt
+	deleteLanguage        *sql.Stmt
+	insertBundle          *sql.Stmt
+	updateBundle          *sql.Stmt
+	deleteBundle          *sql.Stmt
+	insertMemoryUnit      *sql.Stmt
+	selectGlossary        *sql.Stmt
+	insertGlossary        *sql.Stmt
+	upsertGlossary        *sql.Stmt
+	updateGlossary        *sql.Stmt
+	deleteGlossary        *sql.Stmt
+	upsertLocaleKey       *sql.Stmt
+	deleteLocaleKey       *sql.Stmt
+	selectKeyUsed         *sql.Stmt
+	renameLocaleItems     *sql.Stmt
+	renameLocaleKey       *sql.Stmt
+	selectScreenshot      *sql.Stmt
+	insertScreenshot      *sql.Stmt
+	deleteScreenshot      *sql.Stmt
+	selectComment         *sql.Stmt
+	insertComment         *sql.Stmt
+	updateCommentResolved *sql.Stmt
+}
+
+//statementTarget is a sql file and the field of its prepared statement
+type statementTarget struct {
+	fileName string
+	stmt     **sql.Stmt
+}
+
+//targets return every statement of ps with its sql file, the only list both prepare and close use
+func (ps *preparedStatements) targets() []statementTarget {
+	return []statementTarget{
+		{"sql/upsert.sql", &ps.upsertLocaleItem},
+		{"sql/select_localeitem.sql", &ps.selectLocaleItem},
+		{"sql/purge_trash.sql", &ps.purgeTrash},
+		{"sql/insert_audit.sql", &ps.insertAudit},
+		{"sql/select_language.sql", &ps.selectLanguage},
+		{"sql/insert_language.sql", &ps.insertLanguage},
+		{"sql/update_language.sql", &ps.updateLanguage},
+		{"sql/delete_language.sql", &ps.deleteLanguage},
+		{"sql/insert_bundle.sql", &ps.insertBundle},
+		{"sql/update_bundle.sql", &ps.updateBundle},
+		{"sql/delete_bundle.sql", &ps.deleteBundle},
+		{"sql/insert_memory_unit.sql", &ps.insertMemoryUnit},
+		{"sql/select_glossary_entry.sql", &ps.selectGlossary},
+		{"sql/insert_glossary_entry.sql", &ps.insertGlossary},
+		{"sql/upsert_glossary_entry.sql", &ps.upsertGlossary},
+		{"sql/update_glossary_entry.sql", &ps.updateGlossary},
+		{"sql/delete_glossary_entry.sql", &ps.deleteGlossary},
+		{"sql/upsert_localekey.sql", &ps.upsertLocaleKey},
+		{"sql/delete_localekey.sql", &ps.deleteLocaleKey},
+		{"sql/select_key_used.sql", &ps.selectKeyUsed},
+		{"sql/rename_localeitems.sql", &ps.renameLocaleItems},
+		{"sql/rename_localekey.sql", &ps.renameLocaleKey},
+		{"sql/select_screenshot.sql", &ps.selectScreenshot},
+		{"sql/insert_screenshot.sql", &ps.insertScreenshot},
+		{"sql/delete_screenshot.sql", &ps.deleteScreenshot},
+		{"sql/select_comment.sql", &ps.selectComment},
+		{"sql/insert_comment.sql", &ps.insertComment},
+		{"sql/update_comment_resolved.sql", &ps.updateCommentResolved},
+	}
+}
+
+//prepareStatements prepares the embedded sql files, it fails on the first statement that does not prepare
+func prepareStatements(db *sql.DB) (*preparedStatements, error) {
+	ps := &preparedStatements{}
+	for _, target := range ps.targets() {
+		source, err := sqlFiles.ReadFile(target.fileName)
+		if err != nil {
+			ps.close()
+			return nil, err
+		}
+
+		*target.stmt, err = db.Prepare(string(source))
+		if err != nil {
+			ps.close()
+			return nil, fmt.Errorf("prepare %s: %v", target.fileName, err)
+		}
+	}
+
+	return ps, nil
+}
+
+func (ps *preparedStatements) close() {
+	for _, target := range ps.targets() {
+		if *target.stmt != nil {
+			(*target.stmt).Close()
+		}
+	}
+}
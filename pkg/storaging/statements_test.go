@@ -0,0 +1,24 @@
+package storaging
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetsCoverEveryStatement(t *testing.T) {
+	ps := &preparedStatements{}
+	fields := map[uintptr]bool{}
+	value := reflect.ValueOf(ps).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		fields[value.Field(i).UnsafeAddr()] = true
+	}
+
+	for _, target := range ps.targets() {
+		_, err := sqlFiles.ReadFile(target.fileName)
+		assert.NoError(t, err)
+		delete(fields, reflect.ValueOf(target.stmt).Pointer())
+	}
+	assert.Empty(t, fields, "every prepared statement must be in targets")
+}
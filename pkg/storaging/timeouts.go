@@ -0,0 +1,119 @@
+package storaging
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+//defaultQueryTimeout is used when neither QUERY_TIMEOUT nor QUERY_TIMEOUT_<OPERATION> are set
+const defaultQueryTimeout = 10 * time.Second
+
+//operations with a configurable timeout, QUERY_TIMEOUT_GET_LOCALE_ITEMS=2s sets the one for get_locale_items
+const (
+	opPostLocaleItem    = "post_locale_item"
+	opPostLocaleItems   = "post_locale_items"
+	opGetLocaleItem     = "get_locale_item"
+	opGetLocaleItems    = "get_locale_items"
+	opCountLocaleItems  = "count_locale_items"
+	opDeleteLocaleItems = "delete_locale_items"
+	opGetLangs          = "get_langs"
+	opGetBundles        = "get_bundles"
+	opGetTrash          = "get_trash"
+	opRestoreTrash      = "restore_trash"
+	opPurgeTrash        = "purge_trash"
+	opPostAudit         = "post_audit"
+	opGetAudit          = "get_audit"
+	opGetLanguages      = "get_languages"
+	opGetLanguage       = "get_language"
+	opPostLanguage      = "post_language"
+	opPatchLanguage     = "patch_language"
+	opDeleteLanguage    = "delete_language"
+	opPostBundle        = "post_bundle"
+	opPatchBundle       = "patch_bundle"
+	opDeleteBundle      = "delete_bundle"
+	opLintBundle        = "lint_bundle"
+	opSearchLocaleItems = "search_locale_items"
+	opGetSimilar        = "get_similar"
+	opGetDuplicates     = "get_duplicates"
+	opGetSuggestions    = "get_suggestions"
+	opImportMemory      = "import_memory"
+	opExportMemory      = "export_memory"
+	opTranslateBundle   = "translate_bundle"
+	opGetGlossary       = "get_glossary"
+	opPostGlossary      = "post_glossary"
+	opPatchGlossary     = "patch_glossary"
+	opDeleteGlossary    = "delete_glossary"
+	opImportGlossary    = "import_glossary"
+	opGetKeys           = "get_keys"
+	opPutKey            = "put_key"
+	opDeleteKey         = "delete_key"
+	opRenameKey         = "rename_key"
+	opGetViolations     = "get_violations"
+	opGetScreenshot     = "get_screenshot"
+	opPostScreenshot    = "post_screenshot"
+	opDeleteScreenshot  = "delete_screenshot"
+	opExportBundle      = "export_bundle"
+	opGetComments       = "get_comments"
+	opPostComment       = "post_comment"
+	opPatchComment      = "patch_comment"
+	opGetQuestions      = "get_questions"
+	opGetHistory        = "get_history"
+)
+
+var operations = []string{
+	opPostLocaleItem, opPostLocaleItems, opGetLocaleItem, opGetLocaleItems, opCountLocaleItems, opDeleteLocaleItems,
+	opGetLangs, opGetBundles, opGetTrash, opRestoreTrash, opPurgeTrash, opPostAudit, opGetAudit,
+	opGetLanguages, opGetLanguage, opPostLanguage, opPatchLanguage, opDeleteLanguage,
+	opPostBundle, opPatchBundle, opDeleteBundle, opLintBundle,
+	opSearchLocaleItems, opGetSimilar, opGetDuplicates, opGetSuggestions, opImportMemory, opExportMemory,
+	opTranslateBundle, opGetGlossary, opPostGlossary, opPatchGlossary, opDeleteGlossary, opImportGlossary,
+	opGetKeys, opPutKey, opDeleteKey, opRenameKey, opGetViolations, opGetScreenshot, opPostScreenshot, opDeleteScreenshot, opExportBundle,
+	opGetComments, opPostComment, opPatchComment, opGetQuestions, opGetHistory,
+}
+
+//queryTimeouts holds the timeout of every persistence operation
+type queryTimeouts map[string]time.Duration
+
+//loadQueryTimeouts reads QUERY_TIMEOUT as default and QUERY_TIMEOUT_<OPERATION> overrides
+func loadQueryTimeouts() (queryTimeouts, error) {
+	defaultTimeout, err := durationFromEnv("QUERY_TIMEOUT", defaultQueryTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	qt := queryTimeouts{}
+	for _, operation := range operations {
+		qt[operation], err = durationFromEnv("QUERY_TIMEOUT_"+strings.ToUpper(operation), defaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return qt, nil
+}
+
+//context derives from parent a context that expires after the operation timeout
+func (qt queryTimeouts) context(parent context.Context, operation string) (context.Context, context.CancelFunc) {
+	timeout, ok := qt[operation]
+	if !ok {
+		timeout = defaultQueryTimeout
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+func durationFromEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	candidate := os.Getenv(name)
+	if candidate == "" {
+		return defaultValue, nil
+	}
+
+	value, err := time.ParseDuration(candidate)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", name, err)
+	}
+
+	return value, nil
+}
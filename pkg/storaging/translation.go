@@ -0,0 +1,217 @@
+package storaging
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/translating"
+	"github.com/gin-gonic/gin"
+)
+
+//fieldMachineTranslation is the code of items the provider could not translate keeping placeholders
+const fieldMachineTranslation = "machine_translation_failed"
+
+//TranslateParams rappresents a machine translation run of a bundle, every target lang of the bundle when TargetLangs is empty;
+//a dry run only estimates usage, the provider is not called
+type TranslateParams struct {
+	TargetLangs []string `json:"target_langs"`
+	DryRun      bool     `json:"dry_run"`
+}
+
+//TranslationRun rappresents the result of a machine translation run, Usage is what the provider bills
+type TranslationRun struct {
+	Bundle         string             `json:"bundle"`
+	SourceLang     string             `json:"source_lang"`
+	TargetLangs    []string           `json:"target_langs"`
+	DryRun         bool               `json:"dry_run"`
+	Usage          translating.Report `json:"usage"`
+	NumSuccessfull int64              `json:"num_successful"`
+	NumFailed      int64              `json:"num_failed"`
+	Errors         []LintIssue        `json:"errors,omitempty"`
+}
+
+//translationSlot is the text of a translated item, by index, a job fills: its content or a plural variant, by field
+type translationSlot struct {
+	item  int
+	field string
+}
+
+//missingTranslations return a job for every text of a key with a source lang item and without an item in a target lang,
+//by key and target lang; an item of a source with plural variants has the plural categories of its lang in languages,
+//or the ones of source when its lang is not there, each translated from the same text variantPairs pairs it with.
+//ID of a job is its index, the slot with the same index is the text it fills
+func missingTranslations(bundle Bundle, targetLangs []string, items []LocaleItem, languages map[string]Language) ([]translating.Job, []LocaleItem, []translationSlot) {
+	type langKey struct{ lang, key string }
+	sources := map[string]LocaleItem{}
+	existing := map[langKey]bool{}
+	for _, item := range items {
+		existing[langKey{item.Lang, item.Key}] = true
+		if item.Lang == bundle.SourceLang && item.Content != "" {
+			sources[item.Key] = item
+		}
+	}
+
+	keys := make([]string, 0, len(sources))
+	for key := range sources {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	jobs := []translating.Job{}
+	translated := []LocaleItem{}
+	slots := []translationSlot{}
+	for _, key := range keys {
+		source := sources[key]
+		for _, lang := range targetLangs {
+			if lang == bundle.SourceLang || existing[langKey{lang, key}] {
+				continue
+			}
+			item := LocaleItem{Key: key, Bundle: bundle.ID, Lang: lang, MachineTranslated: true, NeedsReview: true}
+			if len(source.Plurals) > 0 {
+				categories := source.Plurals.categories()
+				if language, ok := languages[lang]; ok && len(language.PluralCategories) > 0 {
+					categories = language.PluralCategories
+				}
+				item.Plurals = PluralForms{}
+				for _, category := range categories {
+					item.Plurals[category] = ""
+				}
+			}
+
+			for _, pair := range variantPairs(item, source) {
+				jobs = append(jobs, translating.Job{ID: fmt.Sprint(len(jobs)), SourceLang: bundle.SourceLang, TargetLang: lang, Text: pair.source})
+				slots = append(slots, translationSlot{len(translated), pair.field})
+			}
+			translated = append(translated, item)
+		}
+	}
+	return jobs, translated, slots
+}
+
+//TranslateBundle pre-fills with machine translation the items missing in target langs of the bundle,
+//they are marked as machine translated and needing review; it needs admin role
+func (lph LocalePersistenceHandler) TranslateBundle(c *gin.Context) {
+	if !requireAdmin(c, "Machine translation") {
+		return
+	}
+
+	var translateParams TranslateParams
+	err := c.ShouldBindJSON(&translateParams)
+	if err != nil && err != io.EOF {
+		respondBindError(c, err)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opTranslateBundle)
+	defer cancel()
+	bundle, err := lph.getBundle(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	setAuditFilters(c, bundle.ID, "", "")
+	if bundle.SourceLang == "" {
+		problem.Abort(c, http.StatusConflict, problem.CodeConflict, fmt.Sprintf("Bundle %s has no source_lang to translate from", bundle.ID))
+		return
+	}
+
+	targetLangs := bundle.TargetLangs
+	if len(translateParams.TargetLangs) > 0 {
+		targetLangs = make([]string, len(translateParams.TargetLangs))
+		errs := []FieldError{}
+		for i, lang := range translateParams.TargetLangs {
+			targetLangs[i] = localizing.CanonicalizeOrKeep(lang)
+			if !bundle.acceptsLang(targetLangs[i]) {
+				errs = append(errs, FieldError{"target_langs", fieldNotEnabled, fmt.Sprintf("lang %s is not a target language of bundle %s", targetLangs[i], bundle.ID)})
+			}
+		}
+		if len(errs) > 0 {
+			abortValidation(c, "Machine translation has invalid params", errs)
+			return
+		}
+	}
+	if len(targetLangs) == 0 {
+		problem.Abort(c, http.StatusConflict, problem.CodeConflict, fmt.Sprintf("Bundle %s has no target_langs to translate to", bundle.ID))
+		return
+	}
+
+	items, err := lph.PersistenceDelegate.GetLocaleItems(ctx, "", bundle.ID, "", "", "", 0, 0)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	languages, err := lph.enabledLanguages(ctx)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	jobs, translated, slots := missingTranslations(*bundle, targetLangs, items, languages)
+	run := TranslationRun{Bundle: bundle.ID, SourceLang: bundle.SourceLang, TargetLangs: targetLangs, DryRun: translateParams.DryRun}
+	if translateParams.DryRun {
+		setAuditDryRun(c)
+		run.Usage = lph.translator.Estimate(jobs)
+		c.JSON(http.StatusOK, run)
+		return
+	}
+
+	results, usage, err := lph.translator.Run(ctx, jobs)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	run.Usage = usage
+
+	failed := map[int]bool{}
+	for i, result := range results {
+		slot := slots[i]
+		if result.Err != nil {
+			if usage.NumBatches == 0 {
+				problem.Abort(c, http.StatusBadGateway, problem.CodeUpstreamFailed, "Machine translation failed: "+result.Err.Error())
+				return
+			}
+			run.Errors = append(run.Errors, LintIssue{Key: translated[slot.item].Key, Lang: translated[slot.item].Lang,
+				FieldError: FieldError{slot.field, fieldMachineTranslation, result.Err.Error()}})
+			failed[slot.item] = true
+			continue
+		}
+		if slot.field == "content" {
+			translated[slot.item].Content = result.Text
+		} else {
+			translated[slot.item].Plurals[strings.TrimPrefix(slot.field, "plurals.")] = result.Text
+		}
+	}
+
+	toWrite := []LocaleItem{}
+	for i, item := range translated {
+		if !failed[i] {
+			toWrite = append(toWrite, item)
+		}
+	}
+
+	rules, err := lph.writeRules(ctx, toWrite)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	valid, itemErrs, _ := lph.validator.validateAll(toWrite, rules)
+	for _, ie := range itemErrs {
+		run.Errors = append(run.Errors, LintIssue{Key: toWrite[ie.Index].Key, Lang: toWrite[ie.Index].Lang, FieldError: ie.FieldError})
+	}
+
+	run.NumSuccessfull, err = lph.PersistenceDelegate.PostLocaleItems(ctx, valid)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+	run.NumFailed = int64(len(translated)) - run.NumSuccessfull
+
+	setAuditResult(c, MassiveResult{NumSuccessfull: run.NumSuccessfull, NumFailed: run.NumFailed})
+	c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,51 @@
+package storaging
+
+import (
+	"testing"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/translating"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingTranslations(t *testing.T) {
+	bundle := Bundle{ID: "label", SourceLang: "en", TargetLangs: []string{"it-IT", "de-DE"}}
+	item := func(lang, key, content string) LocaleItem {
+		return LocaleItem{Key: key, Bundle: "label", Lang: lang, Content: content}
+	}
+
+	jobs, translated, slots := missingTranslations(bundle, []string{"en", "it-IT", "de-DE"}, []LocaleItem{
+		item("en", "SAVE", "Save"),
+		item("en", "CANCEL", "Cancel {name}"),
+		item("en", "EMPTY", ""),
+		item("it-IT", "SAVE", "Salva"),
+		item("de-DE", "ONLY_DE", "Nur"),
+	}, nil)
+
+	assert.Equal(t, []translating.Job{
+		{ID: "0", SourceLang: "en", TargetLang: "it-IT", Text: "Cancel {name}"},
+		{ID: "1", SourceLang: "en", TargetLang: "de-DE", Text: "Cancel {name}"},
+		{ID: "2", SourceLang: "en", TargetLang: "de-DE", Text: "Save"},
+	}, jobs)
+	assert.Len(t, translated, 3)
+	assert.Equal(t, LocaleItem{Key: "SAVE", Bundle: "label", Lang: "de-DE", MachineTranslated: true, NeedsReview: true}, translated[2])
+	assert.Equal(t, []translationSlot{{0, "content"}, {1, "content"}, {2, "content"}}, slots)
+}
+
+func TestMissingTranslationsOfPlurals(t *testing.T) {
+	bundle := Bundle{ID: "label", SourceLang: "en"}
+	source := LocaleItem{Key: "FILES", Bundle: "label", Lang: "en", Content: "{n} files",
+		Plurals: PluralForms{"one": "{n} file", "other": "{n} files"}}
+	languages := map[string]Language{"ru": {Tag: "ru", PluralCategories: []string{"one", "few", "many", "other"}}}
+
+	jobs, translated, slots := missingTranslations(bundle, []string{"ru", "it-IT"}, []LocaleItem{source}, languages)
+
+	texts := []string{}
+	for _, job := range jobs {
+		texts = append(texts, job.Text)
+	}
+	assert.Equal(t, []string{"{n} files", "{n} file", "{n} files", "{n} files", "{n} files", "{n} files", "{n} file", "{n} files"}, texts)
+	assert.Equal(t, []translationSlot{{0, "content"}, {0, "plurals.one"}, {0, "plurals.few"}, {0, "plurals.many"}, {0, "plurals.other"},
+		{1, "content"}, {1, "plurals.one"}, {1, "plurals.other"}}, slots)
+	assert.Equal(t, PluralForms{"one": "", "few": "", "many": "", "other": ""}, translated[0].Plurals)
+	assert.Equal(t, PluralForms{"one": "", "other": ""}, translated[1].Plurals)
+}
@@ -0,0 +1,135 @@
+package storaging
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/problem"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	//defaultTrashRetention is used when TRASH_RETENTION is not set
+	defaultTrashRetention = 30 * 24 * time.Hour
+	//defaultTrashPurgeInterval is used when TRASH_PURGE_INTERVAL is not set
+	defaultTrashPurgeInterval = time.Hour
+)
+
+//TrashItem rappresents a deleted locale item that can be restored until purge
+type TrashItem struct {
+	LocaleItem
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+//TrashQueryParams rappresents filters to browse trash
+type TrashQueryParams struct {
+	Bundle string `form:"bundle"`
+	Lang   string `form:"lang"`
+	Key    string `form:"key"`
+	Offset int    `form:"offset"`
+	Limit  int    `form:"limit"`
+}
+
+//RestoreParams rappresents items to bring back from trash, by ids and/or filters
+type RestoreParams struct {
+	IDs    []string `json:"ids"`
+	Bundle string   `json:"bundle"`
+	Lang   string   `json:"lang"`
+	Key    string   `json:"key"`
+}
+
+//TrashPersistencer interface for trash persistence
+type TrashPersistencer interface {
+	GetTrashedLocaleItems(ctx context.Context, key, bundle, lang string, limit, offset int) ([]TrashItem, error)
+	RestoreLocaleItems(ctx context.Context, params RestoreParams) (int64, error)
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+//GetTrash return deleted items filtered by bundle, lang and key
+func (lph LocalePersistenceHandler) GetTrash(c *gin.Context) {
+	var trashQueryParams TrashQueryParams
+	err := c.ShouldBindQuery(&trashQueryParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opGetTrash)
+	defer cancel()
+	items, err := lph.TrashDelegate.GetTrashedLocaleItems(ctx, trashQueryParams.Key, trashQueryParams.Bundle, localizing.CanonicalizeOrKeep(trashQueryParams.Lang), trashQueryParams.Limit, trashQueryParams.Offset)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+//RestoreTrash bring back deleted items
+func (lph LocalePersistenceHandler) RestoreTrash(c *gin.Context) {
+	var restoreParams RestoreParams
+	err := c.ShouldBindJSON(&restoreParams)
+	if err != nil {
+		respondBindError(c, err)
+		return
+	}
+
+	restoreParams.Lang = localizing.CanonicalizeOrKeep(restoreParams.Lang)
+	setAuditFilters(c, restoreParams.Bundle, restoreParams.Lang, restoreParams.Key)
+
+	if len(restoreParams.IDs) == 0 && restoreParams.Bundle == "" {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeValidationFailed, "Restore needs ids or at least a bundle")
+		return
+	}
+
+	ctx, cancel := lph.timeouts.context(c.Request.Context(), opRestoreTrash)
+	defer cancel()
+	numRestored, err := lph.TrashDelegate.RestoreLocaleItems(ctx, restoreParams)
+	if err != nil {
+		respondError(c, ctx, err)
+		return
+	}
+
+	result := MassiveResult{}
+	result.NumSuccessfull = numRestored
+	result.NumFailed = int64(len(restoreParams.IDs)) - numRestored
+	if result.NumFailed < 0 {
+		result.NumFailed = 0
+	}
+
+	setAuditResult(c, result)
+	c.JSON(http.StatusOK, result)
+}
+
+//purgeTrash drops permanently items in trash older than TRASH_RETENTION, every TRASH_PURGE_INTERVAL
+func purgeTrash(tp TrashPersistencer, timeouts queryTimeouts) {
+	retention, err := durationFromEnv("TRASH_RETENTION", defaultTrashRetention)
+	if err != nil {
+		log.Printf("Trash purge disabled: %v\n", err)
+		return
+	}
+	interval, err := durationFromEnv("TRASH_PURGE_INTERVAL", defaultTrashPurgeInterval)
+	if err != nil {
+		log.Printf("Trash purge disabled: %v\n", err)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := timeouts.context(context.Background(), opPurgeTrash)
+		numPurged, err := tp.PurgeTrash(ctx, time.Now().Add(-retention))
+		cancel()
+		if err != nil {
+			log.Printf("Error on purge trash: %v\n", err)
+			continue
+		}
+		if numPurged > 0 {
+			log.Printf("Purged %d items from trash\n", numPurged)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package storaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/formatting"
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/localizing"
+	"golang.org/x/text/language"
+)
+
+//max length, in characters, of locale item fields as defined on localeitems table
+const (
+	maxKeyLength     = 512
+	maxBundleLength  = 128
+	maxLangLength    = 35
+	maxContentLength = 4096
+)
+
+//codes of field errors
+const (
+	fieldRequired     = "required"
+	fieldTooLong      = "too_long"
+	fieldInvalidUTF8  = "invalid_utf8"
+	fieldControlChar  = "control_char"
+	fieldInvalidLang  = "invalid_lang"
+	fieldInvalidName  = "invalid_name"
+	fieldPatternMatch = "pattern_mismatch"
+	fieldNotEnabled   = "not_enabled"
+	fieldSyntax       = "syntax_error"
+	fieldCategory     = "invalid_plural_category"
+)
+
+//bundle names are used in urls, so only safe chars
+var bundleNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+//FieldError rappresents a single invalid field of a locale item
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+//ItemError rappresents an invalid field of the item at index of a bulk payload
+type ItemError struct {
+	Index int `json:"index"`
+	FieldError
+}
+
+//writeRules holds the registry data items are checked against, a nil registry is not checked
+type writeRules struct {
+	languages   map[string]Language
+	bundles     map[string]Bundle
+	keyPatterns map[string]*regexp.Regexp
+	sources     map[itemKey]LocaleItem
+	glossary    map[glossaryKey][]GlossaryEntry
+	keys        map[itemKey]KeyMetadata
+}
+
+//itemKey identifies the items of a key in every lang
+type itemKey struct {
+	bundle string
+	key    string
+}
+
+//writeRules return the registry data for items: enabled languages, settings of their bundles and keys and glossary of their langs
+func (lph LocalePersistenceHandler) writeRules(ctx context.Context, items []LocaleItem) (writeRules, error) {
+	rules := writeRules{bundles: map[string]Bundle{}, keyPatterns: map[string]*regexp.Regexp{}, sources: map[itemKey]LocaleItem{},
+		glossary: map[glossaryKey][]GlossaryEntry{}, keys: map[itemKey]KeyMetadata{}}
+
+	languages, err := lph.enabledLanguages(ctx)
+	if err != nil {
+		return rules, err
+	}
+	rules.languages = languages
+
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		if !seen[item.Bundle] {
+			seen[item.Bundle] = true
+			ids = append(ids, item.Bundle)
+		}
+	}
+
+	bundles, err := lph.BundleDelegate.GetBundles(ctx, ids, false)
+	if err != nil {
+		return rules, err
+	}
+
+	for _, bundle := range bundles {
+		rules.bundles[bundle.ID] = bundle
+		if bundle.KeyPattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(bundle.KeyPattern)
+		if err != nil {
+			return rules, fmt.Errorf("key_pattern of bundle %s: %v", bundle.ID, err)
+		}
+		rules.keyPatterns[bundle.ID] = re
+	}
+
+	if err = lph.loadKeys(ctx, items, &rules); err != nil {
+		return rules, err
+	}
+	if err = lph.loadGlossary(ctx, items, &rules); err != nil {
+		return rules, err
+	}
+	err = lph.loadSources(ctx, items, &rules)
+	return rules, err
+}
+
+//loadSources adds to rules the source lang items of translations in items that need a placeholder or glossary check,
+//source items in the same payload win over stored ones
+func (lph LocalePersistenceHandler) loadSources(ctx context.Context, items []LocaleItem, rules *writeRules) error {
+	keysByBundle := map[string][]string{}
+	for _, item := range items {
+		bundle, ok := rules.bundles[item.Bundle]
+		if !ok || bundle.SourceLang == "" || item.Lang == bundle.SourceLang {
+			continue
+		}
+		if bundle.PlaceholderCheck == placeholderCheckOff && len(rules.glossary[glossaryKey{bundle.SourceLang, item.Lang}]) == 0 {
+			continue
+		}
+		keysByBundle[item.Bundle] = append(keysByBundle[item.Bundle], item.Key)
+	}
+
+	for bundleID, keys := range keysByBundle {
+		sources, err := lph.PersistenceDelegate.GetLocaleItemsByKeys(ctx, bundleID, rules.bundles[bundleID].SourceLang, keys)
+		if err != nil {
+			return err
+		}
+		for _, source := range sources {
+			rules.sources[itemKey{source.Bundle, source.Key}] = source
+		}
+	}
+
+	for _, item := range items {
+		if bundle, ok := rules.bundles[item.Bundle]; ok && item.Lang == bundle.SourceLang {
+			rules.sources[itemKey{item.Bundle, item.Key}] = item
+		}
+	}
+
+	return nil
+}
+
+//itemValidator checks locale items before they reach the db
+type itemValidator struct {
+	keyPatterns map[string]*regexp.Regexp
+}
+
+//newItemValidator return a validator with key patterns per bundle from KEY_PATTERNS,
+//a json object as {"label": "^@[A-Z_]+@$"} where "*" is the pattern for every other bundle
+func newItemValidator() (*itemValidator, error) {
+	v := &itemValidator{keyPatterns: map[string]*regexp.Regexp{}}
+
+	candidate := os.Getenv("KEY_PATTERNS")
+	if candidate == "" {
+		return v, nil
+	}
+
+	patterns := map[string]string{}
+	err := json.Unmarshal([]byte(candidate), &patterns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid KEY_PATTERNS: %v", err)
+	}
+
+	for bundle, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KEY_PATTERNS for bundle %s: %v", bundle, err)
+		}
+		v.keyPatterns[bundle] = re
+	}
+
+	return v, nil
+}
+
+//keyPattern return the pattern keys of bundle must match, nil if any key is allowed;
+//the one in bundle settings wins over KEY_PATTERNS
+func (v *itemValidator) keyPattern(bundle string, rules writeRules) *regexp.Regexp {
+	if re, ok := rules.keyPatterns[bundle]; ok {
+		return re
+	}
+	if re, ok := v.keyPatterns[bundle]; ok {
+		return re
+	}
+	return v.keyPatterns["*"]
+}
+
+//checkKey return the errors of key as a key of bundle: its text and the key pattern of bundle, or the one of KEY_PATTERNS
+func (v *itemValidator) checkKey(bundle Bundle, key string) ([]FieldError, error) {
+	errs := checkText("key", key, maxKeyLength, false)
+
+	rules := writeRules{keyPatterns: map[string]*regexp.Regexp{}}
+	if bundle.KeyPattern != "" {
+		re, err := regexp.Compile(bundle.KeyPattern)
+		if err != nil {
+			return nil, fmt.Errorf("key_pattern of bundle %s: %v", bundle.ID, err)
+		}
+		rules.keyPatterns[bundle.ID] = re
+	}
+	if re := v.keyPattern(bundle.ID, rules); key != "" && re != nil && !re.MatchString(key) {
+		errs = append(errs, FieldError{"key", fieldPatternMatch, fmt.Sprintf("key must match %s in bundle %s", re.String(), bundle.ID)})
+	}
+
+	return errs, nil
+}
+
+//validate return every invalid field of item, empty if item is valid; lang is expected canonicalized
+func (v *itemValidator) validate(item LocaleItem, rules writeRules) []FieldError {
+	errs := []FieldError{}
+
+	errs = append(errs, checkText("key", item.Key, maxKeyLength, false)...)
+	errs = append(errs, checkText("bundle", item.Bundle, maxBundleLength, false)...)
+	errs = append(errs, checkText("lang", item.Lang, maxLangLength, false)...)
+	errs = append(errs, checkText("content", item.Content, maxContentLength, true)...)
+	errs = append(errs, checkPlurals(item, rules)...)
+
+	if item.Bundle != "" && !bundleNamePattern.MatchString(item.Bundle) {
+		errs = append(errs, FieldError{"bundle", fieldInvalidName, "bundle must start with a letter or digit and contain only letters, digits, '_', '.' and '-'"})
+	}
+
+	if item.Lang != "" {
+		if pseudoErrs := checkNotPseudo("lang", item.Lang); len(pseudoErrs) > 0 {
+			errs = append(errs, pseudoErrs...)
+		} else if _, err := language.Parse(item.Lang); err != nil {
+			errs = append(errs, FieldError{"lang", fieldInvalidLang, fmt.Sprintf("lang %q is not a valid BCP 47 tag", item.Lang)})
+		} else if _, ok := rules.languages[item.Lang]; rules.languages != nil && !ok {
+			errs = append(errs, FieldError{"lang", fieldNotEnabled, fmt.Sprintf("lang %s is not enabled in languages registry", item.Lang)})
+		} else if bundle, ok := rules.bundles[item.Bundle]; ok && !bundle.acceptsLang(item.Lang) {
+			errs = append(errs, FieldError{"lang", fieldNotEnabled, fmt.Sprintf("lang %s is not a target language of bundle %s", item.Lang, item.Bundle)})
+		}
+	}
+
+	if bundle, ok := rules.bundles[item.Bundle]; ok && bundle.MessageFormat == messageFormatICU {
+		errs = append(errs, checkICU("content", item.Content, item.Lang, rules)...)
+		for _, category := range item.Plurals.categories() {
+			errs = append(errs, checkICU("plurals."+category, item.Plurals[category], item.Lang, rules)...)
+		}
+	}
+
+	if re := v.keyPattern(item.Bundle, rules); item.Key != "" && re != nil && !re.MatchString(item.Key) {
+		errs = append(errs, FieldError{"key", fieldPatternMatch, fmt.Sprintf("key must match %s in bundle %s", re.String(), item.Bundle)})
+	}
+
+	if metadata, ok := rules.keys[itemKey{item.Bundle, item.Key}]; ok {
+		errs = append(errs, checkConstraints(item, metadata)...)
+	}
+
+	return errs
+}
+
+//check return errors and warnings of item: invalid fields first, then placeholders compared to
+//the source lang item that are errors or warnings as set by the bundle; glossary terms are always warnings
+func (v *itemValidator) check(item LocaleItem, rules writeRules) ([]FieldError, []FieldError) {
+	errs := v.validate(item, rules)
+	if len(errs) > 0 {
+		return errs, nil
+	}
+
+	bundle, ok := rules.bundles[item.Bundle]
+	if !ok || item.Lang == bundle.SourceLang {
+		return errs, nil
+	}
+	source, ok := rules.sources[itemKey{item.Bundle, item.Key}]
+	if !ok {
+		return errs, nil
+	}
+
+	warnings := glossaryIssues(item, source, rules.glossary[glossaryKey{bundle.SourceLang, item.Lang}])
+	if bundle.PlaceholderCheck == placeholderCheckOff {
+		return errs, warnings
+	}
+	issues := placeholderIssues(item, source)
+	if bundle.PlaceholderCheck == placeholderCheckError {
+		return issues, warnings
+	}
+	return errs, append(issues, warnings...)
+}
+
+//validateAll return valid items, errors of the invalid ones and warnings of the valid ones, indexed as in items
+func (v *itemValidator) validateAll(items []LocaleItem, rules writeRules) ([]LocaleItem, []ItemError, []ItemError) {
+	valid := make([]LocaleItem, 0, len(items))
+	errs := []ItemError{}
+	warnings := []ItemError{}
+
+	for i, item := range items {
+		fieldErrs, fieldWarnings := v.check(item, rules)
+		for _, fw := range fieldWarnings {
+			warnings = append(warnings, ItemError{i, fw})
+		}
+		if len(fieldErrs) == 0 {
+			valid = append(valid, item)
+			continue
+		}
+		for _, fe := range fieldErrs {
+			errs = append(errs, ItemError{i, fe})
+		}
+	}
+
+	return valid, errs, warnings
+}
+
+//checkText checks presence, encoding, length and control chars of a field value,
+//multiline allows new line, carriage return and tab
+func checkText(field, value string, maxLength int, multiline bool) []FieldError {
+	if value == "" {
+		if multiline {
+			return nil
+		}
+		return []FieldError{{field, fieldRequired, field + " is required"}}
+	}
+
+	if !utf8.ValidString(value) {
+		return []FieldError{{field, fieldInvalidUTF8, field + " is not valid UTF-8"}}
+	}
+
+	errs := []FieldError{}
+	if length := utf8.RuneCountInString(value); length > maxLength {
+		errs = append(errs, FieldError{field, fieldTooLong, fmt.Sprintf("%s is %d characters, max is %d", field, length, maxLength)})
+	}
+
+	for i, r := range value {
+		if multiline && (r == '\n' || r == '\r' || r == '\t') {
+			continue
+		}
+		if unicode.IsControl(r) {
+			errs = append(errs, FieldError{field, fieldControlChar, fmt.Sprintf("%s has control character %U at byte %d", field, r, i)})
+			break
+		}
+	}
+
+	return errs
+}
+
+//checkICU parses the value of field as ICU MessageFormat and checks plural categories against the language, when it's enabled
+func checkICU(field, value, langTag string, rules writeRules) []FieldError {
+	msg, err := formatting.Parse(value)
+	if err != nil {
+		return []FieldError{{field, fieldSyntax, field + " is not valid ICU MessageFormat: " + err.Error()}}
+	}
+
+	lang, ok := rules.languages[langTag]
+	if !ok {
+		return nil
+	}
+	tag, err := language.Parse(langTag)
+	if err != nil {
+		return nil
+	}
+
+	errs := []FieldError{}
+	for _, categoryErr := range formatting.CheckCategories(value, msg, lang.PluralCategories, localizing.OrdinalCategories(tag)) {
+		errs = append(errs, FieldError{field, fieldCategory, categoryErr.Error()})
+	}
+	return errs
+}
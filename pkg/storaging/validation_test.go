@@ -0,0 +1,126 @@
+package storaging
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	v := &itemValidator{keyPatterns: map[string]*regexp.Regexp{
+		"label": regexp.MustCompile(`^@[A-Z_]+@$`),
+	}}
+
+	rules := writeRules{
+		languages: map[string]Language{
+			"it-IT": {PluralCategories: []string{"one", "other"}},
+			"en-US": {PluralCategories: []string{"one", "other"}},
+			"en":    {PluralCategories: []string{"one", "other"}},
+		},
+		bundles: map[string]Bundle{
+			"app": {ID: "app", SourceLang: "en", TargetLangs: []string{"it-IT"}},
+			"web": {ID: "web", MessageFormat: messageFormatICU},
+		},
+		keyPatterns: map[string]*regexp.Regexp{"errors": regexp.MustCompile(`^[A-Z]+$`)},
+	}
+
+	tests := []struct {
+		name  string
+		item  LocaleItem
+		codes []string
+	}{
+		{"valid", LocaleItem{Key: "@HELLO@", Bundle: "label", Lang: "it-IT", Content: "Ciao\nmondo"}, nil},
+		{"valid without key pattern", LocaleItem{Key: "HELLO", Bundle: "message", Lang: "en-US", Content: "Hello"}, nil},
+		{"empty content", LocaleItem{Key: "HELLO", Bundle: "message", Lang: "en"}, nil},
+		{"missing fields", LocaleItem{Content: "Hello"}, []string{fieldRequired, fieldRequired, fieldRequired}},
+		{"key too long", LocaleItem{Key: strings.Repeat("k", maxKeyLength+1), Bundle: "message", Lang: "en"}, []string{fieldTooLong}},
+		{"content too long in chars", LocaleItem{Key: "HELLO", Bundle: "message", Lang: "en", Content: strings.Repeat("è", maxContentLength+1)}, []string{fieldTooLong}},
+		{"content at max in chars", LocaleItem{Key: "HELLO", Bundle: "message", Lang: "en", Content: strings.Repeat("è", maxContentLength)}, nil},
+		{"invalid lang", LocaleItem{Key: "HELLO", Bundle: "message", Lang: "english!"}, []string{fieldInvalidLang}},
+		{"invalid bundle", LocaleItem{Key: "HELLO", Bundle: "my bundle", Lang: "en"}, []string{fieldInvalidName}},
+		{"key pattern", LocaleItem{Key: "hello", Bundle: "label", Lang: "en"}, []string{fieldPatternMatch}},
+		{"control char in content", LocaleItem{Key: "HELLO", Bundle: "message", Lang: "en", Content: "Hello\x00"}, []string{fieldControlChar}},
+		{"new line in key", LocaleItem{Key: "HEL\nLO", Bundle: "message", Lang: "en"}, []string{fieldControlChar}},
+		{"bundle key pattern", LocaleItem{Key: "hello", Bundle: "errors", Lang: "en"}, []string{fieldPatternMatch}},
+		{"source lang of bundle", LocaleItem{Key: "hello", Bundle: "app", Lang: "en"}, nil},
+		{"target lang of bundle", LocaleItem{Key: "hello", Bundle: "app", Lang: "it-IT"}, nil},
+		{"lang not target of bundle", LocaleItem{Key: "hello", Bundle: "app", Lang: "en-US"}, []string{fieldNotEnabled}},
+		{"icu", LocaleItem{Key: "FILES", Bundle: "web", Lang: "it-IT", Content: "{n, plural, one {# file} other {# file}}"}, nil},
+		{"icu syntax", LocaleItem{Key: "FILES", Bundle: "web", Lang: "it-IT", Content: "{n, plural, one {# file}"}, []string{fieldSyntax}},
+		{"icu plural category", LocaleItem{Key: "FILES", Bundle: "web", Lang: "it-IT", Content: "{n, plural, few {# file} other {# file}}"}, []string{fieldCategory}},
+		{"icu ordinal category", LocaleItem{Key: "PLACE", Bundle: "web", Lang: "it-IT", Content: "{n, selectordinal, many {#º} other {#º}}"}, nil},
+		{"no icu outside bundle", LocaleItem{Key: "FILES", Bundle: "message", Lang: "it-IT", Content: "{n, plural"}, nil},
+		{"lang not enabled", LocaleItem{Key: "HELLO", Bundle: "message", Lang: "fr"}, []string{fieldNotEnabled}},
+		{"invalid utf8", LocaleItem{Key: "HELLO", Bundle: "message", Lang: "en", Content: "\xff\xfe"}, []string{fieldInvalidUTF8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes := []string{}
+			for _, fe := range v.validate(tt.item, rules) {
+				codes = append(codes, fe.Code)
+			}
+			if tt.codes == nil {
+				tt.codes = []string{}
+			}
+			assert.Equal(t, tt.codes, codes)
+		})
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	v := &itemValidator{keyPatterns: map[string]*regexp.Regexp{}}
+
+	valid, errs, _ := v.validateAll([]LocaleItem{
+		{Bundle: "label", Lang: "it-IT"},
+		{Key: "HELLO", Bundle: "label", Lang: "it-IT"},
+		{Key: "HELLO", Bundle: "label"},
+	}, writeRules{})
+
+	assert.Len(t, valid, 1)
+	assert.Equal(t, []ItemError{
+		{0, FieldError{"key", fieldRequired, "key is required"}},
+		{2, FieldError{"lang", fieldRequired, "lang is required"}},
+	}, errs)
+}
+
+func TestCheckPlaceholders(t *testing.T) {
+	v := &itemValidator{keyPatterns: map[string]*regexp.Regexp{}}
+	rules := writeRules{
+		bundles: map[string]Bundle{
+			"warn":  {ID: "warn", SourceLang: "en", PlaceholderCheck: placeholderCheckWarn},
+			"error": {ID: "error", SourceLang: "en", PlaceholderCheck: placeholderCheckError},
+			"off":   {ID: "off", SourceLang: "en", PlaceholderCheck: placeholderCheckOff},
+		},
+		sources: map[itemKey]LocaleItem{
+			{"warn", "HELLO"}:  {Key: "HELLO", Bundle: "warn", Lang: "en", Content: "Hello {username}"},
+			{"error", "HELLO"}: {Key: "HELLO", Bundle: "error", Lang: "en", Content: "Hello {username}"},
+			{"off", "HELLO"}:   {Key: "HELLO", Bundle: "off", Lang: "en", Content: "Hello {username}"},
+			{"error", "FILES"}: {Key: "FILES", Bundle: "error", Lang: "en", Content: "{n} files",
+				Plurals: PluralForms{"one": "One file", "other": "{n} files"}},
+		},
+	}
+
+	errs, warnings := v.check(LocaleItem{Key: "HELLO", Bundle: "warn", Lang: "it-IT", Content: "Ciao"}, rules)
+	assert.Empty(t, errs)
+	assert.Equal(t, []FieldError{{"content", fieldPlaceholderMissing, "placeholder {username} of source en is missing"}}, warnings)
+
+	errs, warnings = v.check(LocaleItem{Key: "HELLO", Bundle: "error", Lang: "it-IT", Content: "Ciao"}, rules)
+	assert.Equal(t, []FieldError{{"content", fieldPlaceholderMissing, "placeholder {username} of source en is missing"}}, errs)
+	assert.Empty(t, warnings)
+
+	errs, warnings = v.check(LocaleItem{Key: "HELLO", Bundle: "off", Lang: "it-IT", Content: "Ciao"}, rules)
+	assert.Empty(t, errs)
+	assert.Empty(t, warnings)
+
+	errs, warnings = v.check(LocaleItem{Key: "BYE", Bundle: "error", Lang: "it-IT", Content: "Ciao"}, rules)
+	assert.Empty(t, errs)
+	assert.Empty(t, warnings)
+
+	//a variant is compared with the same one of source, or with other when source does not have it
+	errs, _ = v.check(LocaleItem{Key: "FILES", Bundle: "error", Lang: "pl", Content: "{n} plików",
+		Plurals: PluralForms{"one": "Jeden plik", "few": "pliki", "many": "{n} plików", "other": "{n} pliku"}}, rules)
+	assert.Equal(t, []FieldError{{"plurals.few", fieldPlaceholderMissing, "placeholder {n} of source en is missing"}}, errs)
+}
@@ -0,0 +1,93 @@
+package translating
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+//wordPattern matches the words a dictionary translates, tokens and punctuation are kept as they are
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}'’]+`)
+
+//Dictionary is an offline deterministic translator: a text found as a whole is replaced by its translation,
+//otherwise every word found is, keeping the capital of its first letter; the rest is kept as it is
+type Dictionary struct {
+	//entries are the translations by target lang and lower case source text
+	entries map[string]map[string]string
+}
+
+//NewDictionary return a dictionary for entries by target lang and source text
+func NewDictionary(entries map[string]map[string]string) *Dictionary {
+	d := &Dictionary{entries: map[string]map[string]string{}}
+	for lang, translations := range entries {
+		d.entries[lang] = map[string]string{}
+		for source, target := range translations {
+			d.entries[lang][strings.ToLower(source)] = target
+		}
+	}
+	return d
+}
+
+//LoadDictionary reads entries from a JSON file as {"it-IT": {"hello": "ciao"}}, no file is an empty dictionary
+func LoadDictionary(fileName string) (*Dictionary, error) {
+	entries := map[string]map[string]string{}
+	if fileName != "" {
+		source, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(source, &entries); err != nil {
+			return nil, err
+		}
+	}
+	return NewDictionary(entries), nil
+}
+
+//Name implements Translator
+func (d *Dictionary) Name() string {
+	return ProviderDictionary
+}
+
+//Translate implements Translator, entries of the target lang are looked up first, then the ones of its base language
+func (d *Dictionary) Translate(ctx context.Context, batch Batch) ([]string, error) {
+	translations := d.entries[batch.TargetLang]
+	if tag, err := language.Parse(batch.TargetLang); err == nil && translations == nil {
+		base, _ := tag.Base()
+		translations = d.entries[base.String()]
+	}
+
+	result := make([]string, len(batch.Texts))
+	for i, text := range batch.Texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result[i] = translate(text, translations)
+	}
+	return result, nil
+}
+
+func translate(text string, translations map[string]string) string {
+	trimmed := strings.TrimSpace(text)
+	if target, ok := translations[strings.ToLower(trimmed)]; ok && trimmed != "" {
+		return strings.Replace(text, trimmed, target, 1)
+	}
+
+	return wordPattern.ReplaceAllStringFunc(text, func(word string) string {
+		target, ok := translations[strings.ToLower(word)]
+		if !ok {
+			return word
+		}
+		first, _ := utf8.DecodeRuneInString(word)
+		if unicode.IsUpper(first) {
+			targetFirst, size := utf8.DecodeRuneInString(target)
+			return string(unicode.ToUpper(targetFirst)) + target[size:]
+		}
+		return target
+	})
+}
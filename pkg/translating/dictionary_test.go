@@ -0,0 +1,45 @@
+package translating
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDictionary(t *testing.T) {
+	dictionary := NewDictionary(map[string]map[string]string{
+		"it":    {"hello": "ciao", "files": "file", "Save your changes": "Salva le modifiche"},
+		"de-DE": {"hello": "hallo"},
+	})
+
+	result, err := dictionary.Translate(context.Background(), Batch{"en", "it-IT", []string{"Hello ⟦0⟧, 3 files", " save your changes ", "unknown"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Ciao ⟦0⟧, 3 file", " Salva le modifiche ", "unknown"}, result)
+
+	result, err = dictionary.Translate(context.Background(), Batch{"en", "de-DE", []string{"hello"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hallo"}, result)
+
+	result, err = dictionary.Translate(context.Background(), Batch{"en", "fr", []string{"hello"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, result)
+}
+
+func TestLoadDictionary(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "dictionary.json")
+	assert.NoError(t, os.WriteFile(fileName, []byte(`{"it-IT": {"Hello": "Ciao"}}`), 0600))
+
+	dictionary, err := LoadDictionary(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ciao", translate("hello", dictionary.entries["it-IT"]))
+
+	_, err = LoadDictionary(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+
+	dictionary, err = LoadDictionary("")
+	assert.NoError(t, err)
+	assert.Empty(t, dictionary.entries)
+}
@@ -0,0 +1,79 @@
+package translating
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+//maxErrorBody is the max num of bytes of an error response reported back
+const maxErrorBody = 512
+
+//HTTPTranslator is the adapter of a generic MT endpoint: it POSTs {"source_lang", "target_lang", "texts"}
+//and expects {"texts"} with the translations in the same order
+type HTTPTranslator struct {
+	URL    string
+	APIKey string
+	Client *http.Client
+}
+
+type httpRequest struct {
+	SourceLang string   `json:"source_lang"`
+	TargetLang string   `json:"target_lang"`
+	Texts      []string `json:"texts"`
+}
+
+type httpResponse struct {
+	Texts []string `json:"texts"`
+}
+
+//NewHTTPTranslator return the adapter for the endpoint at url, apiKey is sent as bearer token when it's set
+func NewHTTPTranslator(url, apiKey string) *HTTPTranslator {
+	return &HTTPTranslator{URL: url, APIKey: apiKey, Client: http.DefaultClient}
+}
+
+//Name implements Translator
+func (ht *HTTPTranslator) Name() string {
+	return ProviderHTTP
+}
+
+//Translate implements Translator
+func (ht *HTTPTranslator) Translate(ctx context.Context, batch Batch) ([]string, error) {
+	body, err := json.Marshal(httpRequest{batch.SourceLang, batch.TargetLang, batch.Texts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ht.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if ht.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+ht.APIKey)
+	}
+
+	resp, err := ht.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return nil, fmt.Errorf("MT endpoint responded %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+
+	var result httpResponse
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid MT response: %v", err)
+	}
+	if len(result.Texts) != len(batch.Texts) {
+		return nil, fmt.Errorf("MT endpoint returned %d texts for %d", len(result.Texts), len(batch.Texts))
+	}
+
+	return result.Texts, nil
+}
@@ -0,0 +1,68 @@
+package translating
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPTranslator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "bad key", http.StatusUnauthorized)
+			return
+		}
+		var req httpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := httpResponse{}
+		for _, text := range req.Texts {
+			resp.Texts = append(resp.Texts, "["+req.TargetLang+"] "+strings.ToUpper(text))
+		}
+		if req.SourceLang == "short" {
+			resp.Texts = resp.Texts[1:]
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	translator := NewHTTPTranslator(server.URL, "secret")
+	result, err := translator.Translate(context.Background(), Batch{"en", "it", []string{"hello ⟦0⟧", "bye"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"[it] HELLO ⟦0⟧", "[it] BYE"}, result)
+
+	_, err = translator.Translate(context.Background(), Batch{"short", "it", []string{"hello", "bye"}})
+	assert.EqualError(t, err, "MT endpoint returned 1 texts for 2")
+
+	_, err = NewHTTPTranslator(server.URL, "wrong").Translate(context.Background(), Batch{"en", "it", []string{"hello"}})
+	assert.EqualError(t, err, "MT endpoint responded 401: bad key")
+}
+
+func TestNewRunnerFromEnv(t *testing.T) {
+	t.Setenv("MT_PROVIDER", ProviderHTTP)
+	t.Setenv("MT_URL", "http://localhost:9999/translate")
+	t.Setenv("MT_BATCH_SIZE", "10")
+	t.Setenv("MT_COST_PER_MILLION_CHARS", "20")
+
+	runner, err := NewRunnerFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderHTTP, runner.Translator.Name())
+	assert.Equal(t, 10, runner.BatchSize)
+	assert.Equal(t, defaultBatchMaxChars, runner.BatchMaxChars)
+	assert.Equal(t, 20.0, runner.CostPerMillionChars)
+
+	t.Setenv("MT_BATCH_SIZE", "0")
+	_, err = NewRunnerFromEnv()
+	assert.EqualError(t, err, `invalid MT_BATCH_SIZE: "0"`)
+
+	t.Setenv("MT_PROVIDER", "deepl")
+	_, err = NewRunnerFromEnv()
+	assert.EqualError(t, err, `invalid MT_PROVIDER: "deepl"`)
+}
@@ -0,0 +1,218 @@
+package translating
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ekr-paolo-carraro/locale-mgmt/pkg/formatting"
+)
+
+//tokenPattern matches the tokens replacing placeholders, providers may add spaces in the brackets
+var tokenPattern = regexp.MustCompile(`⟦\s*(\d+)\s*⟧`)
+
+//Job rappresents a text to translate, ID identifies it in results
+type Job struct {
+	ID         string
+	SourceLang string
+	TargetLang string
+	Text       string
+}
+
+//Result rappresents the translation of a job, Err is set when it failed
+type Result struct {
+	ID   string
+	Text string
+	Err  error
+}
+
+//Report rappresents the usage of a run, Cost is NumChars sent by the price per million chars
+type Report struct {
+	Provider   string  `json:"provider"`
+	NumBatches int     `json:"num_batches"`
+	NumTexts   int     `json:"num_texts"`
+	NumChars   int     `json:"num_chars"`
+	Cost       float64 `json:"cost"`
+}
+
+//Runner translates jobs in batches of BatchSize texts and BatchMaxChars chars at most,
+//a text longer than BatchMaxChars is a batch by itself
+type Runner struct {
+	Translator          Translator
+	BatchSize           int
+	BatchMaxChars       int
+	CostPerMillionChars float64
+}
+
+//maskedText is a text with placeholders replaced by tokens, sent only when it has translatable text
+type maskedText struct {
+	text         string
+	placeholders []string
+	translatable bool
+}
+
+//mask replaces every placeholder and ICU syntax of content with a token ⟦n⟧, n is its index in placeholders
+func mask(content string) maskedText {
+	var sb strings.Builder
+	m := maskedText{}
+	for _, segment := range formatting.Segments(content) {
+		if segment.Translatable {
+			sb.WriteString(segment.Text)
+			m.translatable = m.translatable || strings.TrimSpace(segment.Text) != ""
+			continue
+		}
+		sb.WriteString("⟦" + strconv.Itoa(len(m.placeholders)) + "⟧")
+		m.placeholders = append(m.placeholders, segment.Text)
+	}
+	m.text = sb.String()
+	return m
+}
+
+//unmask restores the placeholders of m in the translation of m, every token must be there once;
+//the result must have the placeholders of source and be valid ICU when source is
+func unmask(source string, m maskedText, translated string) (string, error) {
+	seen := make([]bool, len(m.placeholders))
+	var tokenErr error
+	result := tokenPattern.ReplaceAllStringFunc(translated, func(token string) string {
+		index, _ := strconv.Atoi(tokenPattern.FindStringSubmatch(token)[1])
+		if index >= len(m.placeholders) || seen[index] {
+			tokenErr = fmt.Errorf("translation has unknown or repeated placeholder token %s", token)
+			return token
+		}
+		seen[index] = true
+		return m.placeholders[index]
+	})
+	if tokenErr != nil {
+		return "", tokenErr
+	}
+	for i, ok := range seen {
+		if !ok {
+			return "", fmt.Errorf("translation lost placeholder %s", m.placeholders[i])
+		}
+	}
+
+	if _, err := formatting.Parse(source); err == nil {
+		if _, err = formatting.Parse(result); err != nil {
+			return "", fmt.Errorf("translation is not valid ICU MessageFormat: %v", err)
+		}
+	}
+	issues := formatting.ComparePlaceholders(formatting.ExtractPlaceholders(source), formatting.ExtractPlaceholders(result))
+	if len(issues) > 0 {
+		return "", fmt.Errorf("translation has placeholder %s %s", issues[0].Placeholder.Text, issues[0].Code)
+	}
+
+	return result, nil
+}
+
+//Run translates jobs grouped by source and target lang, results are in the order of jobs;
+//a failed batch fails its jobs only, the run stops when ctx is done
+func (r *Runner) Run(ctx context.Context, jobs []Job) ([]Result, Report, error) {
+	report := Report{Provider: r.Translator.Name()}
+	results := make([]Result, len(jobs))
+	masked, pairs, pending := group(jobs)
+	for i, job := range jobs {
+		results[i].ID = job.ID
+		if !masked[i].translatable {
+			results[i].Text = job.Text
+		}
+	}
+
+	for _, pair := range pairs {
+		for _, batch := range r.batches(pending[pair], masked) {
+			texts := make([]string, len(batch))
+			chars := 0
+			for j, i := range batch {
+				texts[j] = masked[i].text
+				chars += utf8.RuneCountInString(masked[i].text)
+			}
+
+			translated, err := r.Translator.Translate(ctx, Batch{pair.source, pair.target, texts})
+			if err == nil && len(translated) != len(texts) {
+				err = fmt.Errorf("%s returned %d texts for %d", r.Translator.Name(), len(translated), len(texts))
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, r.priced(report), ctx.Err()
+				}
+				for _, i := range batch {
+					results[i].Err = err
+				}
+				continue
+			}
+
+			report.NumBatches++
+			report.NumTexts += len(texts)
+			report.NumChars += chars
+			for j, i := range batch {
+				results[i].Text, results[i].Err = unmask(jobs[i].Text, masked[i], translated[j])
+			}
+		}
+	}
+
+	return results, r.priced(report), nil
+}
+
+//Estimate return the report a run of jobs would have, without calling the translator
+func (r *Runner) Estimate(jobs []Job) Report {
+	report := Report{Provider: r.Translator.Name()}
+	masked, pairs, pending := group(jobs)
+	for _, pair := range pairs {
+		report.NumBatches += len(r.batches(pending[pair], masked))
+		for _, i := range pending[pair] {
+			report.NumTexts++
+			report.NumChars += utf8.RuneCountInString(masked[i].text)
+		}
+	}
+
+	return r.priced(report)
+}
+
+//langPair is the source and target lang of a batch
+type langPair struct{ source, target string }
+
+//group return masked texts of jobs and the indexes of the ones to translate by lang pair, pairs in order of jobs
+func group(jobs []Job) ([]maskedText, []langPair, map[langPair][]int) {
+	masked := make([]maskedText, len(jobs))
+	pairs := []langPair{}
+	pending := map[langPair][]int{}
+	for i, job := range jobs {
+		masked[i] = mask(job.Text)
+		if !masked[i].translatable {
+			continue
+		}
+		pair := langPair{job.SourceLang, job.TargetLang}
+		if _, ok := pending[pair]; !ok {
+			pairs = append(pairs, pair)
+		}
+		pending[pair] = append(pending[pair], i)
+	}
+	return masked, pairs, pending
+}
+
+//batches splits indexes of masked texts by BatchSize and BatchMaxChars
+func (r *Runner) batches(indexes []int, masked []maskedText) [][]int {
+	batches := [][]int{}
+	current := []int{}
+	chars := 0
+	for _, i := range indexes {
+		length := utf8.RuneCountInString(masked[i].text)
+		if len(current) > 0 && ((r.BatchSize > 0 && len(current) >= r.BatchSize) || (r.BatchMaxChars > 0 && chars+length > r.BatchMaxChars)) {
+			batches = append(batches, current)
+			current, chars = []int{}, 0
+		}
+		current = append(current, i)
+		chars += length
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+func (r *Runner) priced(report Report) Report {
+	report.Cost = float64(report.NumChars) * r.CostPerMillionChars / 1e6
+	return report
+}
@@ -0,0 +1,116 @@
+package translating
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//recorder is a translator that records batches and translates with fn
+type recorder struct {
+	batches []Batch
+	fn      func(text string) string
+	err     error
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Translate(ctx context.Context, batch Batch) ([]string, error) {
+	r.batches = append(r.batches, batch)
+	if r.err != nil {
+		return nil, r.err
+	}
+	result := []string{}
+	for _, text := range batch.Texts {
+		result = append(result, r.fn(text))
+	}
+	return result, nil
+}
+
+func TestMask(t *testing.T) {
+	m := mask("Hello {name}, <b>%d</b> {n, plural, one {# file} other {# files}}")
+	assert.Equal(t, "Hello ⟦0⟧, ⟦1⟧ ⟦2⟧ file⟦3⟧ files⟦4⟧", m.text)
+	assert.True(t, m.translatable)
+
+	assert.False(t, mask("{name} <br/>").translatable)
+}
+
+func TestUnmask(t *testing.T) {
+	source := "Hello {name}, you have {n, plural, one {# file} other {# files}}"
+	m := mask(source)
+
+	result, err := unmask(source, m, "Ciao ⟦0⟧, hai ⟦1⟧ file⟦2⟧ file⟦ 3 ⟧")
+	assert.NoError(t, err)
+	assert.Equal(t, "Ciao {name}, hai {n, plural, one {# file} other {# file}}", result)
+
+	_, err = unmask(source, m, "Ciao, hai ⟦1⟧ file⟦2⟧ file⟦3⟧")
+	assert.EqualError(t, err, "translation lost placeholder {name}")
+
+	_, err = unmask(source, m, "Ciao ⟦0⟧ ⟦0⟧, hai ⟦1⟧ file⟦2⟧ file⟦3⟧")
+	assert.EqualError(t, err, "translation has unknown or repeated placeholder token ⟦0⟧")
+
+	_, err = unmask(source, m, "Ciao ⟦0⟧, hai ⟦2⟧ file⟦1⟧ file⟦3⟧")
+	assert.Error(t, err)
+
+	_, err = unmask("%s has %d files", mask("%s has %d files"), "⟦1⟧ file di ⟦0⟧")
+	assert.EqualError(t, err, "translation has placeholder %d reordered")
+}
+
+func TestRunBatchesAndCost(t *testing.T) {
+	translator := &recorder{fn: strings.ToUpper}
+	runner := &Runner{Translator: translator, BatchSize: 2, BatchMaxChars: 12, CostPerMillionChars: 20}
+
+	results, report, err := runner.Run(context.Background(), []Job{
+		{"1", "en", "it", "one"},
+		{"2", "en", "de", "two"},
+		{"3", "en", "it", "three {n}"},
+		{"4", "en", "it", "four"},
+		{"5", "en", "it", "{only}"},
+		{"6", "en", "it", "a long text over max"},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Result{
+		{"1", "ONE", nil}, {"2", "TWO", nil}, {"3", "THREE {n}", nil}, {"4", "FOUR", nil}, {"5", "{only}", nil}, {"6", "A LONG TEXT OVER MAX", nil},
+	}, results)
+
+	texts := [][]string{}
+	for _, batch := range translator.batches {
+		texts = append(texts, batch.Texts)
+	}
+	assert.Equal(t, [][]string{{"one", "three ⟦0⟧"}, {"four"}, {"a long text over max"}, {"two"}}, texts)
+
+	assert.Equal(t, "recorder", report.Provider)
+	assert.Equal(t, 4, report.NumBatches)
+	assert.Equal(t, 5, report.NumTexts)
+	assert.Equal(t, 39, report.NumChars)
+	assert.InDelta(t, 0.00078, report.Cost, 1e-9)
+
+	estimate := runner.Estimate([]Job{
+		{"1", "en", "it", "one"},
+		{"2", "en", "de", "two"},
+		{"3", "en", "it", "three {n}"},
+		{"4", "en", "it", "four"},
+		{"5", "en", "it", "{only}"},
+		{"6", "en", "it", "a long text over max"},
+	})
+	assert.Equal(t, report, estimate)
+	assert.Len(t, translator.batches, 4)
+}
+
+func TestRunFailures(t *testing.T) {
+	runner := &Runner{Translator: &recorder{err: errors.New("quota exceeded")}}
+	results, report, err := runner.Run(context.Background(), []Job{{"1", "en", "it", "one"}})
+	assert.NoError(t, err)
+	assert.EqualError(t, results[0].Err, "quota exceeded")
+	assert.Equal(t, 0, report.NumChars)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	runner = &Runner{Translator: &recorder{err: context.Canceled}}
+	_, _, err = runner.Run(ctx, []Job{{"1", "en", "it", "one"}})
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,89 @@
+package translating
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+//providers of MT_PROVIDER
+const (
+	ProviderDictionary = "dictionary"
+	ProviderHTTP       = "http"
+)
+
+const (
+	//defaultBatchSize is used when MT_BATCH_SIZE is not set
+	defaultBatchSize = 50
+	//defaultBatchMaxChars is used when MT_BATCH_MAX_CHARS is not set
+	defaultBatchMaxChars = 10000
+)
+
+//Batch rappresents texts to translate from a lang to another, placeholders are already replaced by tokens
+type Batch struct {
+	SourceLang string
+	TargetLang string
+	Texts      []string
+}
+
+//Translator is a machine translation provider
+type Translator interface {
+	//Name identifies the provider in run reports
+	Name() string
+	//Translate return the translations of the texts of batch in the same order, tokens as ⟦0⟧ must be kept
+	Translate(ctx context.Context, batch Batch) ([]string, error)
+}
+
+//NewRunnerFromEnv return a runner for the provider of MT_PROVIDER, dictionary by default:
+//MT_DICTIONARY_FILE for dictionary, MT_URL and MT_API_KEY for http;
+//MT_BATCH_SIZE, MT_BATCH_MAX_CHARS and MT_COST_PER_MILLION_CHARS are shared by every provider
+func NewRunnerFromEnv() (*Runner, error) {
+	var translator Translator
+	switch provider := os.Getenv("MT_PROVIDER"); provider {
+	case "", ProviderDictionary:
+		dictionary, err := LoadDictionary(os.Getenv("MT_DICTIONARY_FILE"))
+		if err != nil {
+			return nil, err
+		}
+		translator = dictionary
+	case ProviderHTTP:
+		url := os.Getenv("MT_URL")
+		if url == "" {
+			return nil, fmt.Errorf("MT_URL is required by %s provider", ProviderHTTP)
+		}
+		translator = NewHTTPTranslator(url, os.Getenv("MT_API_KEY"))
+	default:
+		return nil, fmt.Errorf("invalid MT_PROVIDER: %q", provider)
+	}
+
+	runner := &Runner{Translator: translator}
+	var err error
+	if runner.BatchSize, err = intFromEnv("MT_BATCH_SIZE", defaultBatchSize); err != nil {
+		return nil, err
+	}
+	if runner.BatchMaxChars, err = intFromEnv("MT_BATCH_MAX_CHARS", defaultBatchMaxChars); err != nil {
+		return nil, err
+	}
+	if candidate := os.Getenv("MT_COST_PER_MILLION_CHARS"); candidate != "" {
+		if runner.CostPerMillionChars, err = strconv.ParseFloat(candidate, 64); err != nil || runner.CostPerMillionChars < 0 {
+			return nil, fmt.Errorf("invalid MT_COST_PER_MILLION_CHARS: %q", candidate)
+		}
+	}
+
+	return runner, nil
+}
+
+func intFromEnv(name string, defaultValue int) (int, error) {
+	candidate := os.Getenv(name)
+	if candidate == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(candidate)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid %s: %q", name, candidate)
+	}
+
+	return value, nil
+}